@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/gob"
 	"flag"
@@ -8,12 +9,16 @@ import (
 	"os"
 	"strings"
 	"time"
+	_ "time/tzdata" // Facility time zones must load in the minimal runtime image
 
 	"github.com/DukeRupert/haven/internal/config"
 	"github.com/DukeRupert/haven/internal/handler"
+	"github.com/DukeRupert/haven/internal/mail"
 	"github.com/DukeRupert/haven/internal/middleware"
 	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/oidc"
 	"github.com/DukeRupert/haven/internal/repository"
+	"github.com/DukeRupert/haven/internal/secret"
 	"github.com/DukeRupert/haven/internal/store"
 	"github.com/DukeRupert/haven/internal/worker"
 	"github.com/jackc/pgx/v5"
@@ -121,6 +126,21 @@ func main() {
 
 	// Initialize Echo instance
 	e := echo.New()
+	// Rate limits, lockouts and session activity are keyed on the client
+	// IP, so only trust forwarding headers set by a known proxy
+	if len(config.TrustedProxies) > 0 {
+		options := []echo.TrustOption{
+			echo.TrustLoopback(false),
+			echo.TrustLinkLocal(false),
+			echo.TrustPrivateNet(false),
+		}
+		for _, ipRange := range config.TrustedProxies {
+			options = append(options, echo.TrustIPRange(ipRange))
+		}
+		e.IPExtractor = echo.ExtractIPFromXFFHeader(options...)
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
 	e.Static("/static", "web/assets")
 	// Add Prometheus middleware early in the chain
 	e.Use(echoprometheus.NewMiddleware("mirandashift"))
@@ -137,10 +157,16 @@ func main() {
 	repos := repository.NewRepositories(database)
 
 	// Initialize session store
-	sessionStore, err := store.NewPgxStore(repos.Session, []byte("your-secret-key"))
+	sessionStore, err := store.NewPgxStore(repos.Session, logger, config.SessionKeyPairs()...)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("Failed to create session store")
 	}
+	sessionStore.SetLifetime(config.SessionIdleTimeout, config.SessionMaxLifetime)
+	if linked, err := sessionStore.LinkSessions(context.Background()); err != nil {
+		logger.Error().Err(err).Msg("failed to link sessions to their users")
+	} else if linked > 0 {
+		logger.Info().Int("count", linked).Msg("linked existing sessions to their users")
+	}
 	// Add session middleware at application level
 	e.Use(session.Middleware(sessionStore))
 
@@ -149,16 +175,67 @@ func main() {
 		Logger: logger,
 	})
 
+	// Single sign-on is optional
+	var oidcConfig *oidc.Config
+	if config.OIDCIssuerURL != "" {
+		oidcConfig = &oidc.Config{
+			IssuerURL:    config.OIDCIssuerURL,
+			ClientID:     config.OIDCClientID,
+			ClientSecret: config.OIDCClientSecret,
+		}
+	}
+
+	// Facility client secrets and TOTP secrets are stored sealed
+	secrets, err := secret.NewBox(config.SecretKey)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create secret box")
+	}
+	sealed, err := repos.SSO.SealSecrets(context.Background(), secrets.Seal)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to seal facility sso secrets")
+	}
+	if sealed > 0 {
+		logger.Info().Int("count", sealed).Msg("sealed plain text facility sso secrets")
+	}
+	sealed, err = repos.TwoFactor.SealSecrets(context.Background(), secrets.Seal)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to seal two-factor secrets")
+	}
+	if sealed > 0 {
+		logger.Info().Int("count", sealed).Msg("sealed plain text two-factor secrets")
+	}
+
+	// Mail is sent through Postmark, an SMTP server or, in development,
+	// written to files
+	mailTransport, err := mail.NewTransport(mail.TransportConfig{
+		Name:                config.MailTransport,
+		PostmarkServerToken: config.PostmarkServerToken,
+		SMTP: mail.SMTPConfig{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			Security: config.SMTPSecurity,
+		},
+		FileDir: config.MailFileDir,
+	}, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize mail transport")
+	}
+	logger.Info().Str("transport", config.MailTransport).Msg("mail transport ready")
+
 	// Initialize main application handler
 	appHandler, err := handler.New(handler.Config{
 		Repos:   repos,
 		Logger:  logger,
 		BaseURL: config.BaseURL,
 		MailerConfig: handler.MailerConfig{
-			ServerToken: config.PostmarkServerToken,
-			FromEmail:   config.FromEmail,
-			FromName:    "MirandaShift Support",
+			Transport: mailTransport,
+			FromEmail: config.FromEmail,
+			FromName:  "MirandaShift Support",
 		},
+		OIDC:    oidcConfig,
+		Secrets: secrets,
 	})
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to initialize handler")
@@ -176,6 +253,55 @@ func main() {
 	tokenCleaner.Start()
 	defer tokenCleaner.Stop()
 
+	// Purge sessions past their idle timeout or max lifetime
+	sessionCleaner := worker.NewCleaner(
+		"session",
+		repos.Session,
+		logger,
+		15*time.Minute,
+	)
+	sessionCleaner.Start()
+	defer sessionCleaner.Stop()
+
+	// Expired rate limit windows are only needed until they reset
+	rateLimitCleaner := worker.NewCleaner(
+		"rate_limit",
+		repos.RateLimit,
+		logger,
+		15*time.Minute,
+	)
+	rateLimitCleaner.Start()
+	defer rateLimitCleaner.Stop()
+
+	// Move users whose scheduled transfer takes effect
+	transferApplier := worker.NewTransferApplier(
+		repos.Transfer,
+		logger,
+		15*time.Minute,
+	)
+	transferApplier.Start()
+	defer transferApplier.Stop()
+
+	// Warn admins about qualifications nearing their expiry date
+	expiryMailer, err := mail.NewMailer(
+		mailTransport,
+		config.FromEmail,
+		"MirandaShift Support",
+	)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize expiry mailer")
+	}
+	expiryNotifier := worker.NewExpiryNotifier(
+		repos.Qualification,
+		repos.User,
+		expiryMailer,
+		logger,
+		config.QualificationExpiryWindow,
+		time.Hour,
+	)
+	expiryNotifier.Start()
+	defer expiryNotifier.Stop()
+
 	// Start server
 	logger.Info().Msg("Starting server on :8080")
 	e.Logger.Fatal(e.Start(":8080"))
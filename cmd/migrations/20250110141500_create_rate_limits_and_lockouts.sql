@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    hits INTEGER NOT NULL DEFAULT 0,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_expires_at ON rate_limit_buckets(expires_at);
+
+ALTER TABLE users
+    ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE account_lockouts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    facility_id INTEGER NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    failed_attempts INTEGER NOT NULL,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    unlocked_at TIMESTAMP WITH TIME ZONE,
+    unlocked_by INTEGER,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (facility_id) REFERENCES facilities(id) ON DELETE CASCADE,
+    FOREIGN KEY (unlocked_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_account_lockouts_facility_id ON account_lockouts(facility_id, locked_at DESC);
+CREATE INDEX idx_account_lockouts_user_id ON account_lockouts(user_id);
+
+COMMENT ON TABLE rate_limit_buckets IS 'Fixed-window request counters shared by all application instances';
+COMMENT ON TABLE account_lockouts IS 'Temporary account locks caused by repeated failed logins';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_account_lockouts_user_id;
+DROP INDEX IF EXISTS idx_account_lockouts_facility_id;
+DROP TABLE IF EXISTS account_lockouts;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_login_attempts;
+DROP INDEX IF EXISTS idx_rate_limit_buckets_expires_at;
+DROP TABLE IF EXISTS rate_limit_buckets;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE http_sessions
+    ADD COLUMN IF NOT EXISTS ip_address TEXT,
+    ADD COLUMN IF NOT EXISTS user_agent TEXT,
+    ADD COLUMN IF NOT EXISTS last_seen_on TIMESTAMPTZ;
+
+COMMENT ON COLUMN http_sessions.ip_address IS 'Client IP of the most recent authenticated request';
+COMMENT ON COLUMN http_sessions.user_agent IS 'User agent of the most recent authenticated request';
+COMMENT ON COLUMN http_sessions.last_seen_on IS 'Time of the most recent authenticated request';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE http_sessions
+    DROP COLUMN IF EXISTS last_seen_on,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE impersonation_events (
+    id SERIAL PRIMARY KEY,
+    impersonator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('start', 'stop', 'write')),
+    method TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impersonation_events_impersonator_id ON impersonation_events(impersonator_id);
+CREATE INDEX idx_impersonation_events_user_id ON impersonation_events(user_id);
+
+COMMENT ON TABLE impersonation_events IS 'Audit trail of super users viewing the app as another user';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_impersonation_events_user_id;
+DROP INDEX IF EXISTS idx_impersonation_events_impersonator_id;
+DROP TABLE IF EXISTS impersonation_events;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE verification_tokens
+    ADD COLUMN IF NOT EXISTS purpose TEXT NOT NULL DEFAULT 'registration'
+        CHECK (purpose IN ('registration', 'email_change')),
+    ADD COLUMN IF NOT EXISTS cancel_token TEXT UNIQUE;
+
+COMMENT ON COLUMN verification_tokens.purpose IS 'What confirming the token does: complete registration or apply an email change';
+COMMENT ON COLUMN verification_tokens.cancel_token IS 'Token sent to the previous address so an email change can be cancelled';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM verification_tokens WHERE purpose = 'email_change';
+ALTER TABLE verification_tokens
+    DROP COLUMN IF EXISTS cancel_token,
+    DROP COLUMN IF EXISTS purpose;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE facilities
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN facilities.archived_at IS 'When the facility was archived; archived facilities are hidden and their users cannot sign in';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE facilities DROP COLUMN IF EXISTS archived_at;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS facility_settings (
+    facility_id INTEGER PRIMARY KEY REFERENCES facilities(id) ON DELETE CASCADE,
+    default_first_weekday SMALLINT NOT NULL DEFAULT 6 CHECK (default_first_weekday BETWEEN 0 AND 6),
+    default_second_weekday SMALLINT NOT NULL DEFAULT 0 CHECK (default_second_weekday BETWEEN 0 AND 6),
+    publish_weeks_ahead INTEGER NOT NULL DEFAULT 4 CHECK (publish_weeks_ahead BETWEEN 1 AND 52),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN facility_settings.default_first_weekday IS 'First regular day off suggested for new schedules';
+COMMENT ON COLUMN facility_settings.default_second_weekday IS 'Second regular day off suggested for new schedules';
+COMMENT ON COLUMN facility_settings.publish_weeks_ahead IS 'How many weeks ahead the schedule is published';
+
+CREATE TABLE IF NOT EXISTS facility_onboarding (
+    facility_id INTEGER PRIMARY KEY REFERENCES facilities(id) ON DELETE CASCADE,
+    step TEXT NOT NULL DEFAULT 'admin' CHECK (step IN ('admin', 'settings', 'import', 'complete')),
+    started_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    admin_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_facility_onboarding_incomplete ON facility_onboarding(created_at) WHERE completed_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_facility_onboarding_incomplete;
+DROP TABLE IF EXISTS facility_onboarding;
+DROP TABLE IF EXISTS facility_settings;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE facility_settings
+    ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN week_start SMALLINT NOT NULL DEFAULT 1 CHECK (week_start BETWEEN 0 AND 6),
+    ADD COLUMN publication_policy TEXT NOT NULL DEFAULT 'manual' CHECK (publication_policy IN ('manual', 'rolling')),
+    ADD COLUMN availability_deadline_days INTEGER NOT NULL DEFAULT 0 CHECK (availability_deadline_days BETWEEN 0 AND 90);
+
+COMMENT ON COLUMN facility_settings.time_zone IS 'IANA time zone used for today, the calendar and publication cutoffs';
+COMMENT ON COLUMN facility_settings.week_start IS 'First day of the week on the calendar';
+COMMENT ON COLUMN facility_settings.publication_policy IS 'manual: admins publish through a date; rolling: always published publish_weeks_ahead from today';
+COMMENT ON COLUMN facility_settings.availability_deadline_days IS 'Availability can no longer be changed this many days before a date';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE facility_settings
+    DROP COLUMN IF EXISTS availability_deadline_days,
+    DROP COLUMN IF EXISTS publication_policy,
+    DROP COLUMN IF EXISTS week_start,
+    DROP COLUMN IF EXISTS time_zone;
+-- +goose StatementEnd
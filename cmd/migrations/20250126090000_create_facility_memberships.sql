@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS facility_memberships (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    role user_role NOT NULL CHECK (role <> 'super'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, facility_id)
+);
+
+CREATE INDEX idx_facility_memberships_facility ON facility_memberships(facility_id);
+
+COMMENT ON TABLE facility_memberships IS 'Role held by a user at each facility they work at. Super users have access everywhere and need none.';
+
+-- Every existing user is a member of their home facility
+INSERT INTO facility_memberships (user_id, facility_id, role)
+SELECT id, facility_id, role
+FROM users
+WHERE role <> 'super'
+ON CONFLICT DO NOTHING;
+
+CREATE TRIGGER update_facility_memberships_updated_at
+    BEFORE UPDATE ON facility_memberships
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_facility_memberships_updated_at ON facility_memberships;
+DROP INDEX IF EXISTS idx_facility_memberships_facility;
+DROP TABLE IF EXISTS facility_memberships;
+-- +goose StatementEnd
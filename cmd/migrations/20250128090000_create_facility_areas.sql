@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS facility_areas (
+    id SERIAL PRIMARY KEY,
+    facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (facility_id, name)
+);
+
+COMMENT ON TABLE facility_areas IS 'Areas of specialization or crews that split a facility''s controllers';
+
+ALTER TABLE facility_memberships
+    ADD COLUMN area_id INTEGER REFERENCES facility_areas(id) ON DELETE SET NULL,
+    ADD COLUMN area_supervisor BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN facility_memberships.area_supervisor IS 'Lets the member manage schedules and availability within their area';
+
+CREATE INDEX idx_facility_memberships_area ON facility_memberships(area_id);
+
+CREATE TRIGGER update_facility_areas_updated_at
+    BEFORE UPDATE ON facility_areas
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_facility_areas_updated_at ON facility_areas;
+DROP INDEX IF EXISTS idx_facility_memberships_area;
+ALTER TABLE facility_memberships
+    DROP COLUMN IF EXISTS area_supervisor,
+    DROP COLUMN IF EXISTS area_id;
+DROP TABLE IF EXISTS facility_areas;
+-- +goose StatementEnd
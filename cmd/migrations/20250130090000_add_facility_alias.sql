@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE facilities
+    ADD COLUMN alias VARCHAR(24);
+
+COMMENT ON COLUMN facilities.alias IS 'Optional name shown alongside the code, also accepted at registration';
+
+CREATE UNIQUE INDEX idx_facilities_alias ON facilities (UPPER(alias)) WHERE alias IS NOT NULL;
+
+-- Facility codes are stored upper case
+UPDATE facilities SET code = UPPER(code) WHERE code <> UPPER(code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_facilities_alias;
+ALTER TABLE facilities DROP COLUMN IF EXISTS alias;
+-- +goose StatementEnd
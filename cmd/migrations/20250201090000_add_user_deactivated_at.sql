@@ -0,0 +1,104 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.deactivated_at IS 'When the user was deactivated; deactivated users cannot sign in but keep their history';
+
+-- Schedules of deactivated users no longer generate protected dates, and
+-- the dates they already have are left in place
+CREATE OR REPLACE FUNCTION update_protected_dates()
+RETURNS TRIGGER AS $$
+DECLARE
+    check_date date;
+    counter integer;
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND deactivated_at IS NOT NULL) THEN
+        RETURN NEW;
+    END IF;
+
+    -- Delete existing protected dates for this schedule
+    DELETE FROM protected_dates WHERE schedule_id = NEW.id;
+    
+    -- First weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.first_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id, 
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    -- Second weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.second_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id,
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_protected_dates()
+RETURNS TRIGGER AS $$
+DECLARE
+    check_date date;
+    counter integer;
+BEGIN
+    -- Delete existing protected dates for this schedule
+    DELETE FROM protected_dates WHERE schedule_id = NEW.id;
+    
+    -- First weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.first_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id, 
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    -- Second weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.second_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id,
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+-- +goose StatementEnd
@@ -0,0 +1,134 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A schedule is closed when its user transfers to another facility. Users
+-- keep one open schedule; closed ones hold the history at the old facility.
+ALTER TABLE schedules
+    ADD COLUMN IF NOT EXISTS end_date DATE;
+
+COMMENT ON COLUMN schedules.end_date IS 'First day the schedule no longer applies; NULL while it is open';
+
+ALTER TABLE schedules DROP CONSTRAINT IF EXISTS unique_user_schedule;
+CREATE UNIQUE INDEX idx_schedules_open_user ON schedules(user_id) WHERE end_date IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_transfers (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    to_facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    effective_date DATE NOT NULL,
+    transferred_by INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_user_transfers_user_id ON user_transfers(user_id);
+
+CREATE OR REPLACE FUNCTION update_protected_dates()
+RETURNS TRIGGER AS $$
+DECLARE
+    check_date date;
+    counter integer;
+BEGIN
+    -- Closed schedules keep the dates generated before they ended
+    IF NEW.end_date IS NOT NULL THEN
+        RETURN NEW;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND deactivated_at IS NOT NULL) THEN
+        RETURN NEW;
+    END IF;
+
+    -- Delete existing protected dates for this schedule
+    DELETE FROM protected_dates WHERE schedule_id = NEW.id;
+    
+    -- First weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.first_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id, 
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    -- Second weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.second_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id,
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_protected_dates()
+RETURNS TRIGGER AS $$
+DECLARE
+    check_date date;
+    counter integer;
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND deactivated_at IS NOT NULL) THEN
+        RETURN NEW;
+    END IF;
+
+    -- Delete existing protected dates for this schedule
+    DELETE FROM protected_dates WHERE schedule_id = NEW.id;
+    
+    -- First weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.first_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id, 
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    -- Second weekday
+    check_date := NEW.start_date;
+    counter := 0;
+    WHILE check_date < (NEW.start_date + interval '1 year') LOOP
+        IF EXTRACT(DOW FROM check_date) = NEW.second_weekday THEN
+            counter := counter + 1;
+            IF counter % 3 = 0 THEN
+                INSERT INTO protected_dates (schedule_id, date, available, user_id, facility_id)
+                VALUES (NEW.id, check_date, false, NEW.user_id,
+                    (SELECT facility_id FROM users WHERE id = NEW.user_id));
+            END IF;
+        END IF;
+        check_date := check_date + interval '1 day';
+    END LOOP;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS user_transfers;
+
+-- Only the open schedule of each user can be kept
+DELETE FROM schedules WHERE end_date IS NOT NULL;
+DROP INDEX IF EXISTS idx_schedules_open_user;
+ALTER TABLE schedules ADD CONSTRAINT unique_user_schedule UNIQUE(user_id);
+ALTER TABLE schedules DROP COLUMN IF EXISTS end_date;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS qualifications (
+    id SERIAL PRIMARY KEY,
+    facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'position'
+        CHECK (kind IN ('position', 'cpc', 'ojti', 'cic')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (facility_id, name)
+);
+
+COMMENT ON TABLE qualifications IS 'Positions and certifications a facility''s controllers can hold';
+
+CREATE TABLE IF NOT EXISTS user_qualifications (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    qualification_id INTEGER NOT NULL REFERENCES qualifications(id) ON DELETE CASCADE,
+    granted_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    expires_on DATE,
+    granted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expiry_warned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, qualification_id)
+);
+
+COMMENT ON COLUMN user_qualifications.expires_on IS 'Last day the qualification is current, NULL if it does not expire';
+COMMENT ON COLUMN user_qualifications.expiry_warned_at IS 'When admins were warned the qualification is expiring; cleared when it is renewed';
+
+CREATE INDEX idx_user_qualifications_qualification ON user_qualifications(qualification_id);
+CREATE INDEX idx_user_qualifications_expires_on ON user_qualifications(expires_on)
+    WHERE expires_on IS NOT NULL;
+
+CREATE TRIGGER update_qualifications_updated_at
+    BEFORE UPDATE ON qualifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_user_qualifications_updated_at
+    BEFORE UPDATE ON user_qualifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_user_qualifications_updated_at ON user_qualifications;
+DROP TRIGGER IF EXISTS update_qualifications_updated_at ON qualifications;
+DROP TABLE IF EXISTS user_qualifications;
+DROP TABLE IF EXISTS qualifications;
+-- +goose StatementEnd
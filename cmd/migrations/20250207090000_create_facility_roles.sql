@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS facility_roles (
+    id SERIAL PRIMARY KEY,
+    facility_id INTEGER NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (facility_id, name)
+);
+
+COMMENT ON TABLE facility_roles IS 'Named sets of permissions a facility grants its members on top of their admin or user role';
+COMMENT ON COLUMN facility_roles.permissions IS 'Permission names such as schedule.edit or publication.update';
+
+ALTER TABLE facility_memberships
+    ADD COLUMN facility_role_id INTEGER REFERENCES facility_roles(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_facility_memberships_facility_role ON facility_memberships(facility_role_id);
+
+CREATE TRIGGER update_facility_roles_updated_at
+    BEFORE UPDATE ON facility_roles
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_facility_roles_updated_at ON facility_roles;
+DROP INDEX IF EXISTS idx_facility_memberships_facility_role;
+ALTER TABLE facility_memberships DROP COLUMN IF EXISTS facility_role_id;
+DROP TABLE IF EXISTS facility_roles;
+-- +goose StatementEnd
@@ -0,0 +1,122 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Initials address users within a facility, so any that already collide are
+-- made unique by appending letters derived from the user's id to all but the
+-- earliest user. Initials may only contain letters.
+DO $$
+DECLARE
+    dup_id INTEGER;
+    dup_initials TEXT;
+    candidate TEXT;
+    suffix TEXT;
+    n INTEGER;
+    m INTEGER;
+BEGIN
+    LOOP
+        WITH placements AS (
+            SELECT id AS user_id, facility_id FROM users
+            UNION
+            SELECT user_id, facility_id FROM facility_memberships
+        )
+        SELECT p.user_id, u.initials INTO dup_id, dup_initials
+        FROM placements p
+        JOIN users u ON u.id = p.user_id
+        WHERE EXISTS (
+            SELECT 1
+            FROM placements other
+            JOIN users ou ON ou.id = other.user_id
+            WHERE other.facility_id = p.facility_id
+            AND UPPER(ou.initials) = UPPER(u.initials)
+            AND ou.id < u.id
+        )
+        ORDER BY p.user_id
+        LIMIT 1;
+
+        EXIT WHEN dup_id IS NULL;
+
+        -- Write the id in base 26 as letters, moving on to the next value
+        -- if anyone already uses the result
+        n := dup_id;
+        LOOP
+            suffix := '';
+            m := n;
+            LOOP
+                suffix := CHR(65 + m % 26) || suffix;
+                m := m / 26;
+                EXIT WHEN m = 0;
+            END LOOP;
+            candidate := LEFT(dup_initials, 10 - LENGTH(suffix)) || suffix;
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM users WHERE UPPER(initials) = UPPER(candidate));
+            n := n + 1;
+        END LOOP;
+
+        UPDATE users
+        SET initials = candidate
+        WHERE id = dup_id;
+
+        RAISE NOTICE 'renamed duplicate initials of user % to %', dup_id, candidate;
+    END LOOP;
+END $$;
+
+CREATE UNIQUE INDEX users_facility_initials_key ON users (facility_id, UPPER(initials));
+
+-- Members visiting from another facility are not covered by the index, so
+-- they are checked whenever a user or membership changes
+CREATE OR REPLACE FUNCTION check_facility_initials()
+RETURNS TRIGGER AS $$
+DECLARE
+    target_id INTEGER;
+    target_initials TEXT;
+    all_facilities BOOLEAN;
+BEGIN
+    IF TG_TABLE_NAME = 'users' THEN
+        -- A change of initials must suit every facility the user works at
+        target_id := NEW.id;
+        target_initials := NEW.initials;
+        all_facilities := TRUE;
+    ELSE
+        target_id := NEW.user_id;
+        SELECT initials INTO target_initials FROM users WHERE id = NEW.user_id;
+        all_facilities := FALSE;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1
+        FROM users u
+        JOIN (
+            SELECT NEW.facility_id AS facility_id
+            UNION
+            SELECT m.facility_id FROM facility_memberships m
+            WHERE all_facilities AND m.user_id = target_id
+        ) tf ON u.facility_id = tf.facility_id OR EXISTS (
+            SELECT 1 FROM facility_memberships m
+            WHERE m.user_id = u.id AND m.facility_id = tf.facility_id
+        )
+        WHERE u.id != target_id
+        AND UPPER(u.initials) = UPPER(target_initials)
+    ) THEN
+        RAISE EXCEPTION 'initials % already used at facility', target_initials
+            USING ERRCODE = 'unique_violation', CONSTRAINT = 'users_facility_initials_key';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_users_facility_initials
+    BEFORE UPDATE OF initials, facility_id ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION check_facility_initials();
+
+CREATE TRIGGER check_facility_memberships_initials
+    BEFORE INSERT OR UPDATE OF user_id, facility_id ON facility_memberships
+    FOR EACH ROW
+    EXECUTE FUNCTION check_facility_initials();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS check_facility_memberships_initials ON facility_memberships;
+DROP TRIGGER IF EXISTS check_users_facility_initials ON users;
+DROP FUNCTION IF EXISTS check_facility_initials();
+DROP INDEX IF EXISTS users_facility_initials_key;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    actor_email TEXT NOT NULL DEFAULT '',
+    impersonator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    facility_id INTEGER REFERENCES facilities(id) ON DELETE SET NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    method TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_facility_created ON audit_events(facility_id, created_at DESC);
+CREATE INDEX idx_audit_events_created ON audit_events(created_at DESC);
+CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id);
+CREATE INDEX idx_audit_events_entity ON audit_events(entity_type, entity_id);
+
+COMMENT ON TABLE audit_events IS 'Who changed what, with the values before and after the change';
+COMMENT ON COLUMN audit_events.actor_email IS 'Email of the actor when the change was made, kept if the user is purged';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_audit_events_entity;
+DROP INDEX IF EXISTS idx_audit_events_actor_id;
+DROP INDEX IF EXISTS idx_audit_events_created;
+DROP INDEX IF EXISTS idx_audit_events_facility_created;
+DROP TABLE IF EXISTS audit_events;
+-- +goose StatementEnd
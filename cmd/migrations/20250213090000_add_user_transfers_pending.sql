@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Transfers are scheduled when requested and applied on their effective
+-- date. Transfers recorded before this change were applied immediately.
+ALTER TABLE user_transfers
+    ADD COLUMN role user_role CHECK (role <> 'super'),
+    ADD COLUMN applied_at TIMESTAMPTZ,
+    ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+UPDATE user_transfers SET applied_at = created_at;
+
+CREATE UNIQUE INDEX idx_user_transfers_pending_user ON user_transfers(user_id)
+    WHERE applied_at IS NULL AND cancelled_at IS NULL;
+
+COMMENT ON COLUMN user_transfers.role IS 'Role the user takes at the new facility';
+COMMENT ON COLUMN user_transfers.applied_at IS 'When the user moved; NULL while the transfer is pending';
+COMMENT ON COLUMN user_transfers.cancelled_at IS 'When a pending transfer was dropped because the user left the old facility';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM user_transfers WHERE applied_at IS NULL;
+DROP INDEX IF EXISTS idx_user_transfers_pending_user;
+ALTER TABLE user_transfers
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS applied_at,
+    DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A revoked invitation stays revoked until an admin resends it, so the
+-- invitee cannot request a fresh registration link themselves
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS invitation_revoked_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN users.invitation_revoked_at IS 'When an admin revoked the unregistered user''s invitation; NULL if not revoked';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS invitation_revoked_at;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE oidc_providers
+    ADD COLUMN secret_sealed BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN oidc_providers.client_secret IS 'Encrypted with OIDC_SECRET_KEY once secret_sealed is set';
+COMMENT ON COLUMN oidc_providers.secret_sealed IS 'Existing plain text secrets are sealed at startup and ignored until then';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE oidc_providers
+    DROP COLUMN IF EXISTS secret_sealed;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN totp_secret_sealed BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step BIGINT;
+
+COMMENT ON COLUMN users.totp_secret IS 'TOTP secret, set during enrollment and encrypted with SECRET_KEY once totp_secret_sealed is set';
+COMMENT ON COLUMN users.totp_secret_sealed IS 'Existing plain text secrets are sealed at startup and ignored until then';
+COMMENT ON COLUMN users.totp_last_step IS 'Time step of the last accepted TOTP code, so a code cannot be used twice';
+COMMENT ON COLUMN oidc_providers.client_secret IS 'Encrypted with SECRET_KEY once secret_sealed is set';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+COMMENT ON COLUMN oidc_providers.client_secret IS 'Encrypted with OIDC_SECRET_KEY once secret_sealed is set';
+COMMENT ON COLUMN users.totp_secret IS 'Base32 TOTP secret, set during enrollment';
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_secret_sealed;
+-- +goose StatementEnd
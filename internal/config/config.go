@@ -3,8 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +19,21 @@ type Config struct {
 	SessionKey  string
 	BaseURL     string
 
+	// Proxies whose X-Forwarded-For header is trusted, as IPs or CIDR
+	// ranges. With none, the client IP is the address of the connection.
+	TrustedProxies []*net.IPNet
+
+	// Optional session encryption key and keys being rotated out. Sessions
+	// signed with a previous key remain valid and are re-encoded with the
+	// current key on their next save.
+	SessionEncryptionKey string
+	PreviousSessionKeys  []SessionKeyPair
+
+	// Session lifetime: idle sessions expire after SessionIdleTimeout and
+	// every session expires SessionMaxLifetime after sign in
+	SessionIdleTimeout time.Duration
+	SessionMaxLifetime time.Duration
+
 	// Database Configuration
 	DBHost      string
 	DBPort      string
@@ -24,9 +42,42 @@ type Config struct {
 	DBName      string
 	DatabaseURL string
 
-	// Email config
+	// Email config. MailTransport is postmark, smtp or file, and defaults
+	// to postmark when a server token is set and file otherwise. The file
+	// transport is only allowed in development.
+	MailTransport       string
 	PostmarkServerToken string
 	FromEmail           string
+
+	// SMTP server, when MailTransport is smtp. SMTPSecurity is starttls,
+	// tls or none.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPSecurity string
+
+	// Directory the file transport writes messages to, or stdout if empty
+	MailFileDir string
+
+	// Admins are warned about qualifications expiring within this window
+	QualificationExpiryWindow time.Duration
+
+	// Optional global OpenID Connect provider. Facilities may configure
+	// their own provider, which takes precedence.
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+
+	// Key sealing secrets stored in the database, such as facility client
+	// secrets and TOTP secrets
+	SecretKey string
+}
+
+// SessionKeyPair is an authentication key with an optional encryption key
+type SessionKeyPair struct {
+	AuthKey       string
+	EncryptionKey string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -56,8 +107,22 @@ func Load() (*Config, error) {
 	}
 
 	config.BaseURL = getEnvWithDefault("BASE_URL", "http://localhost")
+	config.TrustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
 	config.PostmarkServerToken = getEnvWithDefault("POSTMARK_SERVER_TOKEN", "")
 	config.FromEmail = getEnvWithDefault("FROM_EMAIL", "")
+	if err := loadMailConfig(config); err != nil {
+		return nil, err
+	}
+
+	config.OIDCIssuerURL = os.Getenv("OIDC_ISSUER_URL")
+	config.OIDCClientID = os.Getenv("OIDC_CLIENT_ID")
+	config.OIDCClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	if config.OIDCIssuerURL != "" && config.OIDCClientID == "" {
+		return nil, errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+	}
 
 	// Session key is required and must be at least 32 characters
 	config.SessionKey = os.Getenv("SESSION_KEY")
@@ -67,6 +132,42 @@ func Load() (*Config, error) {
 		return nil, errors.New("SESSION_KEY must be at least 32 characters long")
 	}
 
+	// Secret key is required and must be a valid AES key
+	config.SecretKey = os.Getenv("SECRET_KEY")
+	if config.SecretKey == "" {
+		missingVars = append(missingVars, "SECRET_KEY")
+	} else if err := validateEncryptionKey("SECRET_KEY", config.SecretKey); err != nil {
+		return nil, err
+	}
+
+	config.SessionEncryptionKey = os.Getenv("SESSION_ENCRYPTION_KEY")
+	if err := validateEncryptionKey("SESSION_ENCRYPTION_KEY", config.SessionEncryptionKey); err != nil {
+		return nil, err
+	}
+
+	config.PreviousSessionKeys, err = parseSessionKeyPairs(os.Getenv("SESSION_PREVIOUS_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Session lifetimes
+	config.SessionIdleTimeout, err = getDurationWithDefault("SESSION_IDLE_TIMEOUT", 12*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	config.SessionMaxLifetime, err = getDurationWithDefault("SESSION_MAX_LIFETIME", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if config.SessionIdleTimeout > config.SessionMaxLifetime {
+		return nil, errors.New("SESSION_IDLE_TIMEOUT must not exceed SESSION_MAX_LIFETIME")
+	}
+
+	config.QualificationExpiryWindow, err = getDurationWithDefault("QUALIFICATION_EXPIRY_WINDOW", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
 	// Database Configuration
 	config.DBHost = getEnvWithDefault("DB_HOST", "localhost")
 	config.DBPort = getEnvWithDefault("DB_PORT", "5432")
@@ -103,6 +204,53 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// loadMailConfig reads and checks the settings of the chosen mail transport
+func loadMailConfig(config *Config) error {
+	defaultTransport := "file"
+	if config.PostmarkServerToken != "" {
+		defaultTransport = "postmark"
+	}
+	config.MailTransport = strings.ToLower(getEnvWithDefault("MAIL_TRANSPORT", defaultTransport))
+
+	config.SMTPHost = os.Getenv("SMTP_HOST")
+	config.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	config.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	config.SMTPSecurity = strings.ToLower(getEnvWithDefault("SMTP_SECURITY", "starttls"))
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid SMTP_PORT value: %s", port)
+		}
+		config.SMTPPort = p
+	}
+	config.MailFileDir = os.Getenv("MAIL_FILE_DIR")
+
+	switch config.MailTransport {
+	case "postmark":
+		if config.PostmarkServerToken == "" {
+			return errors.New("POSTMARK_SERVER_TOKEN is required when MAIL_TRANSPORT is postmark")
+		}
+	case "smtp":
+		if config.SMTPHost == "" {
+			return errors.New("SMTP_HOST is required when MAIL_TRANSPORT is smtp")
+		}
+		switch config.SMTPSecurity {
+		case "starttls", "tls", "none":
+		default:
+			return fmt.Errorf("invalid SMTP_SECURITY value: %s", config.SMTPSecurity)
+		}
+	case "file":
+		// Messages hold password reset and invitation links, which must
+		// not end up in files or logs outside development
+		if config.Environment != "development" {
+			return fmt.Errorf("MAIL_TRANSPORT must be postmark or smtp when ENVIRONMENT is %s", config.Environment)
+		}
+	default:
+		return fmt.Errorf("invalid MAIL_TRANSPORT value: %s", config.MailTransport)
+	}
+	return nil
+}
+
 // getEnvWithDefault returns the environment variable value or the default if not set
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -111,6 +259,101 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// SessionKeyPairs returns the session key pairs for securecookie, current
+// pair first followed by previous pairs still accepted for decoding
+func (c *Config) SessionKeyPairs() [][]byte {
+	pairs := [][]byte{[]byte(c.SessionKey), encryptionKeyBytes(c.SessionEncryptionKey)}
+	for _, p := range c.PreviousSessionKeys {
+		pairs = append(pairs, []byte(p.AuthKey), encryptionKeyBytes(p.EncryptionKey))
+	}
+	return pairs
+}
+
+// encryptionKeyBytes returns nil for an empty key so securecookie skips encryption
+func encryptionKeyBytes(key string) []byte {
+	if key == "" {
+		return nil
+	}
+	return []byte(key)
+}
+
+// parseSessionKeyPairs parses a comma separated list of authKey[:encryptionKey] pairs
+func parseSessionKeyPairs(value string) ([]SessionKeyPair, error) {
+	var pairs []SessionKeyPair
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		authKey, encryptionKey, _ := strings.Cut(entry, ":")
+		if len(authKey) < 32 {
+			return nil, errors.New("SESSION_PREVIOUS_KEYS authentication keys must be at least 32 characters long")
+		}
+		if err := validateEncryptionKey("SESSION_PREVIOUS_KEYS", encryptionKey); err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, SessionKeyPair{AuthKey: authKey, EncryptionKey: encryptionKey})
+	}
+	return pairs, nil
+}
+
+// parseTrustedProxies parses a comma separated list of IPs and CIDR ranges
+func parseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES value: %s", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES value: %s", entry)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges, nil
+}
+
+// validateEncryptionKey checks an optional AES key is 16, 24 or 32 bytes
+func validateEncryptionKey(name, key string) error {
+	switch len(key) {
+	case 0, 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("%s encryption keys must be 16, 24 or 32 characters long", name)
+	}
+}
+
+// getDurationWithDefault parses a duration such as "30m" or "12h" from the
+// environment, returning the default if not set
+func getDurationWithDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s value: %s", key, value)
+	}
+	return d, nil
+}
+
 // isValidPort checks if the port value is valid
 func isValidPort(port string) bool {
 	// Add any port validation logic you need
@@ -138,7 +381,8 @@ func (c *Config) String() string {
 			"DBPort: %s, "+
 			"DBUser: %s, "+
 			"DBName: %s, "+
-			"DatabaseURL: [REDACTED]"+
+			"DatabaseURL: [REDACTED], "+
+			"MailTransport: %s"+
 			"}",
 		c.Port,
 		c.Environment,
@@ -146,5 +390,6 @@ func (c *Config) String() string {
 		c.DBPort,
 		c.DBUser,
 		c.DBName,
+		c.MailTransport,
 	)
 }
@@ -0,0 +1,58 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSessionKeyPairs(t *testing.T) {
+	auth := "0123456789abcdef0123456789abcdef"
+	other := "fedcba9876543210fedcba9876543210"
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []SessionKeyPair
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"auth key only", auth, []SessionKeyPair{{AuthKey: auth}}, false},
+		{"with encryption key", auth + ":" + other, []SessionKeyPair{{AuthKey: auth, EncryptionKey: other}}, false},
+		{"several, with spaces", auth + " , " + other + ":" + auth[:16], []SessionKeyPair{
+			{AuthKey: auth},
+			{AuthKey: other, EncryptionKey: auth[:16]},
+		}, false},
+		{"short auth key", "too-short", nil, true},
+		{"bad encryption key", auth + ":short", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSessionKeyPairs(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSessionKeyPairs() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSessionKeyPairs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionKeyPairs(t *testing.T) {
+	c := &Config{
+		SessionKey: "current-auth-key",
+		PreviousSessionKeys: []SessionKeyPair{
+			{AuthKey: "previous-auth-key", EncryptionKey: "0123456789abcdef"},
+		},
+	}
+
+	want := [][]byte{
+		[]byte("current-auth-key"), nil,
+		[]byte("previous-auth-key"), []byte("0123456789abcdef"),
+	}
+	if got := c.SessionKeyPairs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SessionKeyPairs() = %q, want %q", got, want)
+	}
+}
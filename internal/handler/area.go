@@ -0,0 +1,285 @@
+// internal/handler/area.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/repository/area"
+	"github.com/DukeRupert/haven/internal/repository/membership"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Longest area name accepted
+const maxAreaNameLength = 100
+
+// GET /app/:facility_code/areas
+func (h *Handler) HandleGetAreas(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleGetAreas").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	areas, err := h.repos.Area.ListByFacility(c.Request().Context(), facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list areas")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load areas. Please try again later.",
+		)
+	}
+
+	props := dto.AreasPageProps{
+		Title:       "Areas",
+		Description: "Areas of specialization and crews that controllers are assigned to.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Areas:       areas,
+	}
+
+	return render(c, page.Areas(props))
+}
+
+// POST /app/:facility_code/areas
+func (h *Handler) HandleCreateArea(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleCreateArea").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" || len(name) > maxAreaNameLength {
+		return response.Validation(c, []string{
+			fmt.Sprintf("Area name is required and must be at most %d characters", maxAreaNameLength),
+		})
+	}
+
+	a, err := h.repos.Area.Create(c.Request().Context(), facility.ID, name)
+	if err != nil {
+		if errors.Is(err, area.ErrDuplicate) {
+			return response.Validation(c, []string{fmt.Sprintf("%s already has an area named %s", facility.Code, name)})
+		}
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to create area")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("area_id", a.ID).
+		Str("name", a.Name).
+		Msg("area created")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditArea,
+		EntityID:   strconv.Itoa(a.ID),
+		Action:     entity.AuditCreate,
+	}, nil, a)
+
+	return render(c, ComponentGroup(
+		alert.Success("Area Created", fmt.Sprintf("%s has been added to %s.", a.Name, facility.Code)),
+		page.AreaListItem(facility.Code, *a),
+	))
+}
+
+// DELETE /app/:facility_code/areas/:area_id
+func (h *Handler) HandleDeleteArea(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleDeleteArea").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	areaID, err := strconv.Atoi(c.Param("area_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid area"})
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	before, err := h.repos.Area.Get(c.Request().Context(), facility.ID, areaID)
+	if err != nil {
+		if errors.Is(err, area.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found", []string{"Area not found"})
+		}
+		logger.Error().Err(err).Int("area_id", areaID).Msg("failed to get area")
+		return response.System(c)
+	}
+
+	if err := h.repos.Area.Delete(c.Request().Context(), facility.ID, areaID); err != nil {
+		if errors.Is(err, area.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found", []string{"Area not found"})
+		}
+		logger.Error().Err(err).Int("area_id", areaID).Msg("failed to delete area")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("area_id", areaID).
+		Msg("area deleted")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditArea,
+		EntityID:   strconv.Itoa(areaID),
+		Action:     entity.AuditDelete,
+	}, before, nil)
+
+	return response.Success(c, "Area Deleted", "Its members are no longer assigned to an area.")
+}
+
+// PUT /app/:facility_code/:user_initials/area
+func (h *Handler) HandleUpdateMemberArea(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleUpdateMemberArea").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	if err := ensureRouteParams(route); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	var areaID *int
+	if v := c.FormValue("area_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return response.Validation(c, []string{"Please choose a valid area"})
+		}
+		areaID = &id
+	}
+	supervisor := c.FormValue("area_supervisor") == "on"
+
+	member, err := h.repos.Membership.GetByInitials(ctx, facility.ID, route.UserInitials)
+	if err != nil {
+		if errors.Is(err, membership.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found",
+				[]string{"Only members of the facility can be assigned to an area"})
+		}
+		logger.Error().Err(err).Str("initials", route.UserInitials).Msg("failed to get membership")
+		return response.System(c)
+	}
+
+	updated, err := h.repos.Membership.SetArea(ctx, member.UserID, facility.ID, areaID, supervisor)
+	if err != nil {
+		if errors.Is(err, membership.ErrAreaNotFound) {
+			return response.Validation(c, []string{"Please choose a valid area"})
+		}
+		logger.Error().Err(err).Int("user_id", member.UserID).Msg("failed to set area")
+		return response.System(c)
+	}
+
+	areas, err := h.repos.Area.ListByFacility(ctx, facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list areas")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", updated.UserID).
+		Int("facility_id", facility.ID).
+		Bool("area_supervisor", updated.AreaSupervisor).
+		Msg("member area updated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditMembership,
+		EntityID:   strconv.Itoa(member.UserID),
+		Action:     entity.AuditUpdate,
+	}, member, updated)
+
+	return render(c, ComponentGroup(
+		alert.Success("Area Updated", fmt.Sprintf("%s's area has been updated.", route.UserInitials)),
+		page.MemberAreaForm(facility.Code, route.UserInitials, areas, *updated),
+	))
+}
+
+// areaFilter reads the area chosen on a facility page. Area supervisors see
+// their own area unless they ask for everyone with area=all.
+func (h *Handler) areaFilter(c echo.Context, auth *dto.AuthContext, facilityID int) ([]entity.Area, *int, error) {
+	areas, err := h.repos.Area.ListByFacility(c.Request().Context(), facilityID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch v := c.QueryParam("area"); v {
+	case "all":
+		return areas, nil, nil
+	case "":
+		return areas, auth.SupervisedArea(), nil
+	default:
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return areas, nil, nil
+		}
+		for _, a := range areas {
+			if a.ID == id {
+				return areas, &id, nil
+			}
+		}
+		return areas, nil, nil
+	}
+}
@@ -0,0 +1,287 @@
+// internal/handler/audit.go
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Longest actor filter accepted on the audit log
+const maxAuditActorLength = 100
+
+// audit records a change made by the current request. The event names the
+// entity and action; the actor, request and, when not set, the facility in
+// the route are filled in. before and after are stored as JSON and may be
+// nil for creations and deletions.
+func (h *Handler) audit(c echo.Context, event entity.AuditEvent, before, after any) {
+	logger := h.logger.With().
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Str("entity_type", event.EntityType).
+		Str("entity_id", event.EntityID).
+		Str("action", event.Action).
+		Logger()
+
+	if auth, err := middleware.GetAuthContext(c); err == nil {
+		event.ActorID = &auth.UserID
+		if auth.ImpersonatorID != 0 {
+			event.ImpersonatorID = &auth.ImpersonatorID
+		}
+	}
+	event.Method = c.Request().Method
+	event.Path = c.Request().URL.Path
+	event.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+	var err error
+	if event.Before, err = auditJSON(before); err != nil {
+		logger.Error().Err(err).Msg("failed to encode audit before value")
+	}
+	if event.After, err = auditJSON(after); err != nil {
+		logger.Error().Err(err).Msg("failed to encode audit after value")
+	}
+
+	// Record even if the client has gone away
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if event.FacilityID == nil {
+		if route, err := middleware.GetRouteContext(c); err == nil && route.FacilityCode != "" {
+			if facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode); err == nil {
+				event.FacilityID = &facility.ID
+			}
+		}
+	}
+
+	if err := h.repos.Audit.Record(ctx, event); err != nil {
+		logger.Error().Err(err).Msg("failed to record audit event")
+		return
+	}
+	middleware.MarkAudited(c)
+}
+
+// auditJSON encodes a value for the audit trail. Nil values, including nil
+// pointers, are stored as missing rather than as JSON null.
+func auditJSON(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GET /app/:facility_code/audit
+func (h *Handler) HandleFacilityAudit(c echo.Context) error {
+	return h.handleAudit(c, true)
+}
+
+// GET /app/audit
+func (h *Handler) HandleAudit(c echo.Context) error {
+	return h.handleAudit(c, false)
+}
+
+// GET /app/:facility_code/audit/export
+func (h *Handler) HandleExportFacilityAudit(c echo.Context) error {
+	return h.handleExportAudit(c, true)
+}
+
+// GET /app/audit/export
+func (h *Handler) HandleExportAudit(c echo.Context) error {
+	return h.handleExportAudit(c, false)
+}
+
+// handleAudit shows a page of the audit log, either of the facility in the
+// route or of every facility
+func (h *Handler) handleAudit(c echo.Context, forFacility bool) error {
+	logger := h.logger.With().
+		Str("handler", "handleAudit").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	filter, basePath, err := h.auditListParams(c, route, forFacility)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	events, total, err := h.repos.Audit.List(c.Request().Context(), filter)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list audit events")
+		return response.System(c)
+	}
+
+	description := "Every change made at the facility, newest first."
+	if !forFacility {
+		description = "Every change made across all facilities, newest first."
+	}
+
+	props := dto.AuditPageProps{
+		Title:        "Audit Log",
+		Description:  description,
+		NavItems:     BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:      *auth,
+		RouteCtx:     *route,
+		BasePath:     basePath,
+		ShowFacility: !forFacility,
+		Events:       events,
+		Filter:       filter,
+		Total:        total,
+	}
+
+	return render(c, page.Audit(props))
+}
+
+// handleExportAudit downloads the events matching the audit log's filters
+// as CSV
+func (h *Handler) handleExportAudit(c echo.Context, forFacility bool) error {
+	logger := h.logger.With().
+		Str("handler", "handleExportAudit").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	filter, _, err := h.auditListParams(c, route, forFacility)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	events, err := h.repos.Audit.Export(c.Request().Context(), filter)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to export audit events")
+		return response.System(c)
+	}
+
+	name := "audit"
+	if forFacility {
+		name = fmt.Sprintf("audit-%s", route.FacilityCode)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.csv", name, time.Now().Format("20060102"))))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{
+		"time", "facility", "actor", "impersonator", "entity_type", "entity_id",
+		"action", "before", "after", "method", "path", "request_id",
+	})
+	for _, e := range events {
+		row := []string{
+			e.CreatedAt.Format(time.RFC3339),
+			e.FacilityCode,
+			e.ActorEmail,
+			e.ImpersonatorEmail,
+			e.EntityType,
+			e.EntityID,
+			e.Action,
+			string(e.Before),
+			string(e.After),
+			e.Method,
+			e.Path,
+			e.RequestID,
+		}
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+		w.Write(row)
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		logger.Error().Err(err).Msg("failed to write audit export")
+	}
+
+	logger.Info().
+		Str("facility_code", route.FacilityCode).
+		Int("events", len(events)).
+		Msg("audit log exported")
+
+	return nil
+}
+
+// csvCell stops spreadsheet applications from running a cell that starts
+// like a formula
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// auditListParams reads the audit log's filters and page, scoped to the
+// facility in the route when forFacility is set. It also returns the path
+// of the log the filters apply to, and an error only if that facility
+// cannot be found.
+func (h *Handler) auditListParams(c echo.Context, route *dto.RouteContext, forFacility bool) (params.ListAuditParams, string, error) {
+	p := params.ListAuditParams{
+		Actor: strings.TrimSpace(c.QueryParam("actor")),
+		Page:  1,
+	}
+	basePath := "/app/audit"
+
+	if forFacility {
+		facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+		if err != nil {
+			return p, "", err
+		}
+		p.FacilityID = &facility.ID
+		basePath = fmt.Sprintf("/app/%s/audit", facility.Code)
+	}
+
+	p.Actor = truncateRunes(p.Actor, maxAuditActorLength)
+	for _, t := range entity.AuditEntityTypes {
+		if c.QueryParam("entity_type") == t {
+			p.EntityType = t
+		}
+	}
+	for _, a := range entity.AuditActions {
+		if c.QueryParam("action") == a {
+			p.Action = a
+		}
+	}
+	if from, err := time.ParseInLocation("2006-01-02", c.QueryParam("from"), time.Local); err == nil {
+		p.From = &from
+	}
+	if to, err := time.ParseInLocation("2006-01-02", c.QueryParam("to"), time.Local); err == nil {
+		p.To = &to
+	}
+	if page, err := strconv.Atoi(c.QueryParam("page")); err == nil && page > 1 {
+		p.Page = page
+	}
+
+	return p, basePath, nil
+}
@@ -0,0 +1,62 @@
+// internal/handler/audit_test.go
+package handler
+
+import (
+	"testing"
+)
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "KHLN", "KHLN"},
+		{"json", `{"role":"admin"}`, `{"role":"admin"}`},
+		{"formula", "=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"plus", "+1", "'+1"},
+		{"minus", "-1", "'-1"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"inner equals", "a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csvCell(tt.in); got != tt.want {
+				t.Errorf("csvCell(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditJSON(t *testing.T) {
+	type facility struct {
+		Code string `json:"code"`
+	}
+	var missing *facility
+
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{"nil", nil, ""},
+		{"nil pointer", missing, ""},
+		{"value", facility{Code: "KHLN"}, `{"code":"KHLN"}`},
+		{"map", map[string]bool{"require_two_factor": true}, `{"require_two_factor":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auditJSON(tt.in)
+			if err != nil {
+				t.Fatalf("auditJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("auditJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
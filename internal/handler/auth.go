@@ -8,8 +8,12 @@ import (
 	"time"
 
 	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	"github.com/DukeRupert/haven/internal/response"
 	"github.com/DukeRupert/haven/internal/store"
 	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
@@ -18,12 +22,25 @@ import (
 // Common errors
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account temporarily locked")
+	ErrAccountDeactivated = errors.New("account deactivated")
+)
+
+// errAccountDeactivatedMessage is shown when a deactivated user signs in
+const errAccountDeactivatedMessage = "Your account has been deactivated. Please contact your administrator."
+
+// Failed logins allowed before an account is temporarily locked
+const (
+	loginLockoutThreshold = 5
+	loginLockoutDuration  = 15 * time.Minute
 )
 
 // LoginParams represents the expected login request body
 type LoginParams struct {
 	Email    string `json:"email" form:"email" validate:"required,email"`
-	Password string `json:"password" form:"password" validate:"required"`
+	Password string `json:"password" form:"password"`
+	SSO      bool   `json:"sso" form:"sso"`           // Sign in with the identity provider instead
+	Facility string `json:"facility" form:"facility"` // Facility whose provider to use for single sign-on
 }
 
 // LoginResponse handles both the alert and potential redirect for login attempts
@@ -64,8 +81,30 @@ func (h *Handler) LoginHandler(c echo.Context) error {
 			[]string{"Please check your input"}, "")
 	}
 
+	// Single sign-on does not name an account
+	if params.SSO {
+		return h.startSSO(c, sess, params.Facility, logger)
+	}
+
+	// Throttle attempts against a single account
+	if result, ok := h.allowRequest(c, ratelimit.LoginAccount, params.Email); !ok {
+		return response.TooManyRequests(c, result.RetryAfter)
+	}
+
 	// Authenticate using the service (don't verify password again)
-	user, err := h.Authenticate(c.Request().Context(), params.Email, params.Password)
+	user, err := h.Authenticate(c.Request().Context(), params.Email, params.Password, c.RealIP())
+	if errors.Is(err, ErrAccountLocked) {
+		// Answer as for a wrong password so the lock does not confirm the
+		// account exists
+		logger.Info().Str("email", params.Email).Msg("login attempt on locked account")
+		return h.LoginResponse(c, http.StatusUnauthorized, "Login Failed",
+			[]string{"Invalid email or password"}, "")
+	}
+	if errors.Is(err, ErrAccountDeactivated) {
+		logger.Info().Str("email", params.Email).Msg("login attempt on deactivated account")
+		return h.LoginResponse(c, http.StatusForbidden, "Account Deactivated",
+			[]string{errAccountDeactivatedMessage}, "")
+	}
 	if err != nil {
 		logger.Debug().Err(err).Str("email", params.Email).Msg("authentication failed")
 		return h.LoginResponse(c, http.StatusUnauthorized, "Login Failed",
@@ -79,20 +118,35 @@ func (h *Handler) LoginHandler(c echo.Context) error {
 		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
 			[]string{"Unable to complete login"}, "")
 	}
+	closed, err := h.facilityClosed(c.Request().Context(), user, facility)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check facility memberships")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+	if closed {
+		logger.Info().Int("user_id", user.ID).Msg("login attempt at archived facility")
+		return h.LoginResponse(c, http.StatusForbidden, "Facility Archived",
+			[]string{errFacilityArchivedMessage}, "")
+	}
 
-	// Set session values
-	sess.Values[store.SessionKeyUserID] = user.ID
-	sess.Values[store.SessionKeyRole] = user.Role
-	sess.Values[store.SessionKeyInitials] = user.Initials
-	sess.Values[store.SessionKeyFacilityCode] = facility.Code
-	sess.Values[store.SessionKeyFacilityID] = facility.ID
-	sess.Values[store.SessionKeyLastAccess] = time.Now()
-
-	h.logger.Debug().
-		Interface("session_values", sess.Values).
-		Msg("session values before save")
+	// Require a second factor before creating the session
+	enabled, err := h.repos.TwoFactor.IsEnabled(c.Request().Context(), user.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check two-factor status")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+	if enabled {
+		if err := h.startTwoFactorChallenge(c, sess, user); err != nil {
+			logger.Error().Err(err).Msg("failed to save session")
+			return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+				[]string{"Unable to complete login process"}, "")
+		}
+		return h.LoginResponse(c, http.StatusOK, "", nil, "/login/2fa")
+	}
 
-	if err := sess.Save(c.Request(), c.Response()); err != nil {
+	if err := h.createSession(c, sess, user, facility); err != nil {
 		logger.Error().Err(err).Msg("failed to save session")
 		return h.LoginResponse(c,
 			http.StatusInternalServerError,
@@ -101,7 +155,26 @@ func (h *Handler) LoginHandler(c echo.Context) error {
 			"")
 	}
 
-	logger.Debug().
+	redirectURL := fmt.Sprintf("/app/calendar")
+	return h.LoginResponse(c, http.StatusOK, "", nil, redirectURL)
+}
+
+// createSession stores the authenticated user's details in the session
+func (h *Handler) createSession(c echo.Context, sess *sessions.Session, user *entity.User, facility *entity.Facility) error {
+	// Clear any pending two-factor challenge
+	clearTwoFactorChallenge(sess)
+	delete(sess.Values, store.SessionKeyImpersonatorID)
+
+	// Set session values
+	setSessionIdentity(sess, user, facility)
+	// The absolute session lifetime starts at sign in
+	sess.Values[store.SessionKeyCreatedAt] = time.Now()
+
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return err
+	}
+
+	h.logger.Debug().
 		Interface("session_values", map[string]interface{}{
 			"user_id":       sess.Values[store.SessionKeyUserID],
 			"role":          sess.Values[store.SessionKeyRole],
@@ -111,8 +184,40 @@ func (h *Handler) LoginHandler(c echo.Context) error {
 		}).
 		Msg("session values after save")
 
-	redirectURL := fmt.Sprintf("/app/calendar")
-	return h.LoginResponse(c, http.StatusOK, "", nil, redirectURL)
+	return nil
+}
+
+// errFacilityArchivedMessage is shown when a user of an archived facility signs in
+const errFacilityArchivedMessage = "Your facility has been archived. Please contact your administrator."
+
+// facilityClosed reports whether the user's home facility has been archived
+// and they are not a member of any other open facility. Super users are not
+// limited to their facility and can still sign in.
+func (h *Handler) facilityClosed(ctx context.Context, user *entity.User, facility *entity.Facility) (bool, error) {
+	if !facility.IsArchived() || user.Role == types.UserRoleSuper {
+		return false, nil
+	}
+
+	memberships, err := h.repos.Membership.ListByUser(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range memberships {
+		if !m.IsArchived() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// setSessionIdentity points the session at a user without touching its lifetime
+func setSessionIdentity(sess *sessions.Session, user *entity.User, facility *entity.Facility) {
+	sess.Values[store.SessionKeyUserID] = user.ID
+	sess.Values[store.SessionKeyRole] = user.Role
+	sess.Values[store.SessionKeyInitials] = user.Initials
+	sess.Values[store.SessionKeyFacilityCode] = facility.Code
+	sess.Values[store.SessionKeyFacilityID] = facility.ID
+	sess.Values[store.SessionKeyLastAccess] = time.Now()
 }
 
 func (h *Handler) LogoutHandler(c echo.Context) error {
@@ -165,8 +270,9 @@ func (h *Handler) LogoutHandler(c echo.Context) error {
 	return c.Redirect(http.StatusSeeOther, "/login")
 }
 
-// Authenticate verifies user credentials
-func (h *Handler) Authenticate(ctx context.Context, email, password string) (*entity.User, error) {
+// Authenticate verifies user credentials. Failed attempts count towards a
+// temporary lockout of the account.
+func (h *Handler) Authenticate(ctx context.Context, email, password, ipAddress string) (*entity.User, error) {
 	log := h.logger.With().Str("method", "Authenticate").Logger()
 
 	user, err := h.repos.User.GetByEmail(ctx, email)
@@ -175,16 +281,55 @@ func (h *Handler) Authenticate(ctx context.Context, email, password string) (*en
 		return nil, ErrInvalidCredentials
 	}
 
+	// Reject locked accounts before checking the password
+	lockedUntil, err := h.repos.Lockout.LockedUntil(ctx, user.ID)
+	if err != nil {
+		log.Error().Err(err).Int("user_id", user.ID).Msg("failed to check account lock")
+		return nil, err
+	}
+	if lockedUntil != nil {
+		// Still compare so a locked account answers as slowly as a wrong
+		// password, but ignore the result
+		_ = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+		return nil, ErrAccountLocked
+	}
+
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
 		log.Debug().Str("email", email).Msg("invalid password")
+		h.recordFailedLogin(ctx, user.ID, ipAddress)
 		return nil, ErrInvalidCredentials
 	}
 
+	if err := h.repos.Lockout.ResetFailures(ctx, user.ID); err != nil {
+		log.Error().Err(err).Int("user_id", user.ID).Msg("failed to reset failed logins")
+	}
+
+	// Only say the account is deactivated once the password is known
+	if user.IsDeactivated() {
+		return nil, ErrAccountDeactivated
+	}
+
 	return user, nil
 }
 
+// recordFailedLogin counts a failed attempt and locks the account when the threshold is reached
+func (h *Handler) recordFailedLogin(ctx context.Context, userID int, ipAddress string) {
+	lockedUntil, err := h.repos.Lockout.RecordFailure(ctx, userID, ipAddress, loginLockoutThreshold, loginLockoutDuration)
+	if err != nil {
+		h.logger.Error().Err(err).Int("user_id", userID).Msg("failed to record failed login")
+		return
+	}
+	if lockedUntil != nil {
+		h.logger.Warn().
+			Int("user_id", userID).
+			Str("ip", ipAddress).
+			Time("locked_until", *lockedUntil).
+			Msg("account locked after repeated failed logins")
+	}
+}
+
 // Clear any other auth-related cookies if they exist
 func clearCookie(c echo.Context, name string) {
 	c.SetCookie(&http.Cookie{
@@ -37,6 +37,31 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
 		)
 	}
 
+	var facilityCode string
+    // If we're on a facility-specific route, use that facility code
+    if route.FacilityCode != "" {
+        facilityCode = route.FacilityCode
+    } else {
+        // Default to user's facility code for the general calendar route
+        if auth.FacilityCode == "" {
+            return echo.NewHTTPError(http.StatusBadRequest, "no facility available")
+        }
+        facilityCode = auth.FacilityCode
+    }
+
+	// Dates are shown in the facility's time zone
+	today, settings, err := h.facilityToday(c.Request().Context(), facilityCode)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", facilityCode).
+			Msg("failed to fetch facility settings")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load calendar data",
+		)
+	}
+
 	// Get view date from query params or default to current month
     monthParam := c.QueryParam("month")
     logger.Debug().
@@ -44,7 +69,7 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
         Bool("has_month_param", monthParam != "").
         Msg("Month parameter check")
 
-    viewDate, err := getViewDate(monthParam)
+    viewDate, err := getViewDate(monthParam, today)
     if err != nil {
         logger.Error().
             Err(err).
@@ -56,22 +81,36 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
         )
     }
 
-	var facilityCode string
-    // If we're on a facility-specific route, use that facility code
-    if route.FacilityCode != "" {
-        facilityCode = route.FacilityCode
-    } else {
-        // Default to user's facility code for the general calendar route
-        if auth.FacilityCode == "" {
-            return echo.NewHTTPError(http.StatusBadRequest, "no facility available")
-        }
-        facilityCode = auth.FacilityCode
-    }
+	areas, areaID, err := h.areaFilter(c, auth, settings.FacilityID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", facilityCode).
+			Msg("failed to fetch areas")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load calendar data",
+		)
+	}
+
+	quals, qualificationID, err := h.qualificationFilter(c, settings.FacilityID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", facilityCode).
+			Msg("failed to fetch qualifications")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load calendar data",
+		)
+	}
 
 	// Get protected dates
 	protectedDates, err := h.repos.Schedule.GetProtectedDatesByFacilityCode(
 		c.Request().Context(),
 		facilityCode,
+		areaID,
+		qualificationID,
 	)
 	if err != nil {
 		logger.Error().
@@ -90,6 +129,10 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
 	 // Build calendar props
     calendarProps := dto.CalendarProps{
         CurrentMonth:    viewDate,
+        Today:           today,
+        WeekStart:       settings.WeekStart,
+        AreaID:          areaID,
+        QualificationID: qualificationID,
         ProtectedDates: protectedDates,
         AuthCtx:       *auth,
         RouteCtx:  *route,
@@ -103,6 +146,9 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
 		AuthCtx: 	*auth,
 		RouteCtx: 	*route,
 		Calendar:    calendarProps,
+		Settings:    *settings,
+		Areas:       areas,
+		Qualifications: quals,
 	}
 
 	// Handle HTMX requests
@@ -121,10 +167,12 @@ func (h *Handler) HandleCalendar(c echo.Context) error {
 }
 
 // Helper functions
-func getViewDate(monthStr string) (time.Time, error) {
+
+// getViewDate returns the first of the requested month, or of the month
+// containing today when none is given
+func getViewDate(monthStr string, today time.Time) (time.Time, error) {
 	if monthStr != "" {
 		return time.Parse("2006-01", monthStr)
 	}
-	now := time.Now()
-	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local), nil
-}
\ No newline at end of file
+	return time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,209 @@
+// internal/handler/deactivation.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// GET /app/:facility_code/users/deactivated
+func (h *Handler) HandleDeactivatedUsers(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleDeactivatedUsers").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	users, err := h.repos.User.ListDeactivated(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to retrieve deactivated users")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load deactivated users. Please try again later.",
+		)
+	}
+
+	// Ensure users is never nil
+	if users == nil {
+		users = []entity.User{}
+	}
+
+	props := dto.DeactivatedUsersPageProps{
+		Title:       "Deactivated Users",
+		Description: "Users who can no longer sign in. Their schedules and history are kept for reporting.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Users:       users,
+	}
+
+	return render(c, page.DeactivatedUsers(props))
+}
+
+// POST /app/:facility_code/:user_initials/reactivate
+func (h *Handler) HandleReactivateUser(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleReactivateUser").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, err := h.deactivatedUser(c, route)
+	if err != nil {
+		return deactivatedUserError(c, err, logger)
+	}
+
+	if !canDeleteUser(auth, user) {
+		logger.Warn().
+			Int("target_user_id", user.ID).
+			Int("requesting_user_id", auth.UserID).
+			Msg("unauthorized reactivation attempt")
+		return response.Error(c, http.StatusForbidden,
+			"Access Denied",
+			[]string{"You don't have permission to reactivate this user"})
+	}
+
+	reactivated, err := h.repos.User.SetDeactivated(c.Request().Context(), user.ID, false)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to reactivate user")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Int("reactivated_by", auth.UserID).
+		Msg("user reactivated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &user.FacilityID,
+		EntityType: entity.AuditUser,
+		EntityID:   strconv.Itoa(user.ID),
+		Action:     entity.AuditReactivate,
+	}, user, reactivated)
+
+	return render(c, alert.Success("User Reactivated",
+		fmt.Sprintf("%s %s can sign in again. Update their schedule to restore upcoming protected days.", user.FirstName, user.LastName)))
+}
+
+// DELETE /app/:facility_code/:user_initials/purge
+// Permanently deletes a deactivated user with their schedule and history
+func (h *Handler) HandlePurgeUser(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandlePurgeUser").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, err := h.deactivatedUser(c, route)
+	if err != nil {
+		return deactivatedUserError(c, err, logger)
+	}
+
+	if err := h.repos.User.Delete(c.Request().Context(), user.ID); err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to purge user")
+		return response.System(c)
+	}
+
+	logger.Warn().
+		Int("user_id", user.ID).
+		Str("email", user.Email).
+		Int("facility_id", user.FacilityID).
+		Int("purged_by", auth.UserID).
+		Msg("user purged")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &user.FacilityID,
+		EntityType: entity.AuditUser,
+		EntityID:   strconv.Itoa(user.ID),
+		Action:     entity.AuditDelete,
+	}, user, nil)
+
+	return render(c, alert.Success("User Purged",
+		fmt.Sprintf("%s %s and their history have been permanently deleted.", user.FirstName, user.LastName)))
+}
+
+// errNotDeactivated is returned for users who are active or based elsewhere
+var errNotDeactivated = errors.New("user is not a deactivated user of the facility")
+
+// deactivatedUser loads the deactivated user named in the route. Only users
+// based at the facility can be reactivated or purged from it.
+func (h *Handler) deactivatedUser(c echo.Context, route *dto.RouteContext) (*entity.User, error) {
+	ctx := c.Request().Context()
+	user, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		return nil, err
+	}
+
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.FacilityID != facility.ID || !user.IsDeactivated() {
+		return nil, errNotDeactivated
+	}
+	return user, nil
+}
+
+// deactivatedUserError responds to a failed deactivatedUser lookup
+func deactivatedUserError(c echo.Context, err error, logger zerolog.Logger) error {
+	switch {
+	case errors.Is(err, userRepo.ErrNotFound):
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	case errors.Is(err, errNotDeactivated):
+		return response.Error(c, http.StatusConflict, "Not Deactivated",
+			[]string{"Only deactivated users based at this facility can be reactivated or purged"})
+	default:
+		logger.Error().Err(err).Msg("failed to fetch deactivated user")
+		return response.System(c)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// emailChangeTTL is how long a new address has to confirm an email change
+const emailChangeTTL = 24 * time.Hour
+
+// requestEmailChange emails a confirmation link to the new address and a
+// cancel link to the current one. The user's email is not changed until the
+// new address is confirmed.
+func (h *Handler) requestEmailChange(ctx context.Context, user *entity.User, newEmail string, logger zerolog.Logger) error {
+	token, err := generateSecureToken()
+	if err != nil {
+		return fmt.Errorf("generating email change token: %w", err)
+	}
+	cancelToken, err := generateSecureToken()
+	if err != nil {
+		return fmt.Errorf("generating cancel token: %w", err)
+	}
+
+	changeToken := &entity.EmailChangeToken{
+		UserID:      user.ID,
+		Token:       token,
+		CancelToken: cancelToken,
+		Email:       newEmail,
+		ExpiresAt:   time.Now().Add(emailChangeTTL),
+	}
+	if err := h.repos.Token.StoreEmailChange(ctx, changeToken); err != nil {
+		return err
+	}
+
+	confirmData := map[string]interface{}{
+		"ConfirmURL": fmt.Sprintf("%s/confirm-email?token=%s", h.config.BaseURL, token),
+		"NewEmail":   newEmail,
+		"ExpiresIn":  "24 hours",
+		"FromName":   "MirandaShift Support",
+		"Subject":    "Confirm Your New MirandaShift Email Address",
+		"FirstName":  user.FirstName,
+		"LastName":   user.LastName,
+	}
+	if err := h.mailer.SendTemplate(ctx, "email_change", newEmail, confirmData); err != nil {
+		return fmt.Errorf("sending email change confirmation: %w", err)
+	}
+
+	noticeData := map[string]interface{}{
+		"CancelURL": fmt.Sprintf("%s/cancel-email-change?token=%s", h.config.BaseURL, cancelToken),
+		"NewEmail":  newEmail,
+		"FromName":  "MirandaShift Support",
+		"Subject":   "Your MirandaShift Email Address Is Changing",
+		"FirstName": user.FirstName,
+		"LastName":  user.LastName,
+	}
+	if err := h.mailer.SendTemplate(ctx, "email_change_notice", user.Email, noticeData); err != nil {
+		// The change still needs confirming from the new address
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to send email change notice")
+	}
+
+	logger.Info().Int("user_id", user.ID).Msg("email change requested")
+	return nil
+}
+
+// HandleConfirmEmailChange applies a pending email change and signs the user
+// out of every session so they sign in again with the new address
+func (h *Handler) HandleConfirmEmailChange(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleConfirmEmailChange").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	change, err := h.repos.Token.ConsumeEmailChange(ctx, c.QueryParam("token"))
+	if err != nil {
+		logger.Debug().Err(err).Msg("email change token rejected")
+		c.Response().Status = http.StatusBadRequest
+		return render(c, page.EmailChangeResult("Invalid Link",
+			"This confirmation link is invalid, has expired, or was cancelled. Please request the change again."))
+	}
+
+	if err := h.repos.User.UpdateEmail(ctx, change.UserID, change.Email); err != nil {
+		if errors.Is(err, userRepo.ErrEmailExists) {
+			c.Response().Status = http.StatusConflict
+			return render(c, page.EmailChangeResult("Email Unavailable",
+				"This email address is now in use by another account. Your email address was not changed."))
+		}
+		logger.Error().Err(err).Int("user_id", change.UserID).Msg("failed to update email")
+		c.Response().Status = http.StatusInternalServerError
+		return render(c, page.EmailChangeResult("System Error",
+			"We were unable to update your email address. Please try again later."))
+	}
+
+	revoked, err := h.repos.Session.DeleteByUserID(ctx, change.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", change.UserID).Msg("failed to revoke sessions after email change")
+	}
+
+	logger.Info().
+		Int("user_id", change.UserID).
+		Int64("sessions_revoked", revoked).
+		Msg("email change confirmed")
+
+	return render(c, page.EmailChangeResult("Email Address Updated",
+		"Your email address has been changed. Please sign in with your new address."))
+}
+
+// HandleCancelEmailChange voids a pending email change from the link sent to
+// the previous address
+func (h *Handler) HandleCancelEmailChange(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleCancelEmailChange").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	userID, err := h.repos.Token.CancelEmailChange(c.Request().Context(), c.QueryParam("token"))
+	if err != nil {
+		logger.Debug().Err(err).Msg("email change cancel token rejected")
+		c.Response().Status = http.StatusBadRequest
+		return render(c, page.EmailChangeResult("Invalid Link",
+			"This link is invalid or the change has already been confirmed. If you did not request the change, reset your password and contact your administrator."))
+	}
+
+	logger.Info().Int("user_id", userID).Msg("email change cancelled")
+
+	return render(c, page.EmailChangeResult("Email Change Cancelled",
+		"The pending email change has been cancelled. Your email address has not been changed."))
+}
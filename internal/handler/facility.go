@@ -1,19 +1,22 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/DukeRupert/haven/internal/middleware"
 	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/repository/facility"
 	"github.com/DukeRupert/haven/internal/response"
 	"github.com/DukeRupert/haven/internal/validation"
 	"github.com/DukeRupert/haven/web/view/alert"
 	"github.com/DukeRupert/haven/web/view/page"
-	"github.com/DukeRupert/haven/web/view/super"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
@@ -54,6 +57,15 @@ func (h *Handler) HandleGetFacilities(c echo.Context) error {
 		)
 	}
 
+	onboarding, err := h.repos.Onboarding.ListIncomplete(c.Request().Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to retrieve facility setups")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load facilities. Please try again later.",
+		)
+	}
+
 	// Build nav items
 	navItems := BuildNav(route, auth, c.Request().URL.Path)
 
@@ -65,6 +77,7 @@ func (h *Handler) HandleGetFacilities(c echo.Context) error {
 		AuthCtx: *auth,
 		RouteCtx: *route,
 		Facilities: facilities,
+		Onboarding: onboarding,
 	}
 
 	logger.Debug().
@@ -77,17 +90,99 @@ func (h *Handler) HandleGetFacilities(c echo.Context) error {
 	).Render(c.Request().Context(), c.Response().Writer)
 }
 
+// POST /app/facilities
 func (h *Handler) HandleCreateFacility(c echo.Context) error {
-	return render(c, page.CreateFacilityForm())
+	logger := h.logger.With().
+		Str("handler", "HandleCreateFacility").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	var params params.CreateFacilityParams
+	if err := c.Bind(&params); err != nil {
+		logger.Debug().Err(err).Msg("invalid form data")
+		return response.Error(c, http.StatusBadRequest, "Invalid Request",
+			[]string{"Please check the form data and try again"})
+	}
+
+	f, errs, err := h.createFacility(c.Request().Context(), params)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+	if err != nil {
+		logger.Error().Err(err).Interface("params", params).Msg("failed to create facility")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", f.ID).
+		Str("code", f.Code).
+		Msg("facility created")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &f.ID,
+		EntityType: entity.AuditFacility,
+		EntityID:   strconv.Itoa(f.ID),
+		Action:     entity.AuditCreate,
+	}, nil, f)
+
+	return render(c, ComponentGroup(
+		alert.Success("Facility Created", fmt.Sprintf("Successfully created %s (%s)", f.Name, f.Code)),
+		page.FacilityListItem(*f),
+	))
+}
+
+// createFacility validates the name and code and creates the facility.
+// Validation problems are returned as messages for the user.
+func (h *Handler) createFacility(ctx context.Context, params params.CreateFacilityParams) (*entity.Facility, []string, error) {
+	var errs []string
+	if _, err := validation.ValidateFacilityName(params.Name); err != nil {
+		errs = append(errs, err.Error())
+	}
+	code, err := validation.ValidateFacilityCode(params.Code)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	alias, err := validation.ValidateFacilityAlias(params.Alias)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	// Keep the name as entered
+	params.Name = strings.TrimSpace(params.Name)
+	params.Code = string(code)
+	params.Alias = string(alias)
+
+	unique, err := h.repos.Facility.IsCodeUnique(ctx, params.Code, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !unique {
+		return nil, []string{"This facility code is already in use"}, nil
+	}
+
+	f, err := h.repos.Facility.Create(ctx, params)
+	if errors.Is(err, facility.ErrDuplicateCode) {
+		return nil, []string{"This facility code is already in use"}, nil
+	}
+	if errors.Is(err, facility.ErrDuplicateAlias) {
+		return nil, []string{"This alias is already used by another facility"}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, nil, nil
 }
 
 func (h *Handler) HandleUpdateFacility(c echo.Context) error {
 	logger := zerolog.Ctx(c.Request().Context())
-	id, err := strconv.Atoi(c.Param("fid"))
+	id, err := strconv.Atoi(c.Param("facility_id"))
 	if err != nil {
 		logger.Error().
 			Err(err).
-			Str("facility_id", c.Param("fid")).
+			Str("facility_id", c.Param("facility_id")).
 			Msg("invalid facility ID format")
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid facility ID")
 	}
@@ -119,6 +214,14 @@ func (h *Handler) HandleUpdateFacility(c echo.Context) error {
 		params.Code = string(code)
 	}
 
+	// Validate optional alias
+	alias, err := validation.ValidateFacilityAlias(params.Alias)
+	if err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		params.Alias = string(alias)
+	}
+
 	if len(errors) > 0 {
 		logger.Error().
 			Strs("validation_errors", errors).
@@ -129,7 +232,23 @@ func (h *Handler) HandleUpdateFacility(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, strings.Join(errors, "; "))
 	}
 
-	facility, err := h.repos.Facility.Update(c.Request().Context(), id, params)
+	// Kept for the audit trail
+	before, err := h.repos.Facility.GetByID(c.Request().Context(), id)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("facility_id", id).
+			Msg("failed to retrieve facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	updated, err := h.repos.Facility.Update(c.Request().Context(), id, params)
+	if err == facility.ErrDuplicateCode {
+		return echo.NewHTTPError(http.StatusBadRequest, "This facility code is already in use")
+	}
+	if err == facility.ErrDuplicateAlias {
+		return echo.NewHTTPError(http.StatusBadRequest, "This alias is already used by another facility")
+	}
 	if err != nil {
 		logger.Error().
 			Err(err).
@@ -141,15 +260,147 @@ func (h *Handler) HandleUpdateFacility(c echo.Context) error {
 
 	logger.Info().
 		Int("facility_id", id).
-		Str("name", facility.Name).
-		Str("code", facility.Code).
+		Str("name", updated.Name).
+		Str("code", updated.Code).
 		Msg("facility updated successfully")
 
-	return render(c, super.FacilityListItem(*facility))
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &updated.ID,
+		EntityType: entity.AuditFacility,
+		EntityID:   strconv.Itoa(updated.ID),
+		Action:     entity.AuditUpdate,
+	}, before, updated)
+
+	return render(c, page.FacilityListItem(*updated))
+}
+
+// POST /app/facilities/:facility_id/archive
+func (h *Handler) HandleArchiveFacility(c echo.Context) error {
+	return h.setFacilityArchived(c, "HandleArchiveFacility", true)
+}
+
+// POST /app/facilities/:facility_id/restore
+func (h *Handler) HandleRestoreFacility(c echo.Context) error {
+	return h.setFacilityArchived(c, "HandleRestoreFacility", false)
 }
 
+func (h *Handler) setFacilityArchived(c echo.Context, name string, archived bool) error {
+	logger := h.logger.With().
+		Str("handler", name).
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	before, err := h.repos.Facility.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	f, err := h.repos.Facility.SetArchived(c.Request().Context(), id, archived)
+	if errors.Is(err, facility.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to update facility archive state")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", f.ID).
+		Str("code", f.Code).
+		Bool("archived", archived).
+		Msg("facility archive state updated")
+
+	action := entity.AuditArchive
+	if !archived {
+		action = entity.AuditRestore
+	}
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &f.ID,
+		EntityType: entity.AuditFacility,
+		EntityID:   strconv.Itoa(f.ID),
+		Action:     action,
+	}, before, f)
+
+	return render(c, page.FacilityListItem(*f))
+}
+
+// GET /app/facilities/:facility_id/delete
+func (h *Handler) GetDeleteFacilityForm(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetDeleteFacilityForm").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	f, err := h.repos.Facility.GetByID(c.Request().Context(), id)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	return render(c, page.DeleteFacilityForm(*f))
+}
+
+// DELETE /app/facilities/:facility_id
+// The facility code must be typed to confirm, and only facilities without
+// users, protected dates or transfers can be deleted.
 func (h *Handler) HandleDeleteFacility(c echo.Context) error {
-	return render(c, page.CreateFacilityForm())
+	logger := h.logger.With().
+		Str("handler", "HandleDeleteFacility").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	f, err := h.repos.Facility.GetByID(c.Request().Context(), id)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(c.FormValue("confirm_code")), f.Code) {
+		return response.Validation(c, []string{"Type the facility code to confirm deletion"})
+	}
+
+	err = h.repos.Facility.Delete(c.Request().Context(), id)
+	if errors.Is(err, facility.ErrNotEmpty) {
+		return response.Error(c, http.StatusConflict, "Facility Not Empty",
+			[]string{"Only facilities without users, protected dates or transfers can be deleted. Archive the facility instead to keep its data."})
+	}
+	if errors.Is(err, facility.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to delete facility")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", id).
+		Str("code", f.Code).
+		Msg("facility deleted")
+
+	// The facility is gone, so the event is not tied to it
+	h.audit(c, entity.AuditEvent{
+		EntityType: entity.AuditFacility,
+		EntityID:   strconv.Itoa(id),
+		Action:     entity.AuditDelete,
+	}, f, nil)
+
+	// The alert swaps out of band; the list item is replaced with nothing
+	return render(c, alert.Success("Facility Deleted", fmt.Sprintf("%s (%s) has been deleted", f.Name, f.Code)))
 }
 
 // GET /app/facilities/create
@@ -157,14 +408,14 @@ func (h *Handler) GetCreateFacilityForm(c echo.Context) error {
 	return render(c, page.CreateFacilityForm())
 }
 
-// Get /app/facilities/update
+// GET /app/facilities/:facility_id/update
 func (h *Handler) GetUpdateFacilityForm(c echo.Context) error {
 	logger := zerolog.Ctx(c.Request().Context())
-	id, err := strconv.Atoi(c.Param("fid"))
+	id, err := strconv.Atoi(c.Param("facility_id"))
 	if err != nil {
 		logger.Error().
 			Err(err).
-			Str("facility_id", c.Param("fid")).
+			Str("facility_id", c.Param("facility_id")).
 			Msg("invalid facility ID format")
 		return render(c, alert.Error(
 			"Invalid request",
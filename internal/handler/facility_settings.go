@@ -0,0 +1,208 @@
+// internal/handler/facility_settings.go
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Maximum days before a date that availability can be locked
+const maxAvailabilityDeadlineDays = 90
+
+// GET /app/:facility_code/settings
+func (h *Handler) HandleGetFacilitySettings(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleGetFacilitySettings").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	settings, err := h.repos.Facility.GetSettings(c.Request().Context(), facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to get facility settings")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load facility settings. Please try again later.",
+		)
+	}
+
+	props := dto.FacilitySettingsPageProps{
+		Title:       "Facility Settings",
+		Description: "Time zone, calendar and scheduling rules for this facility.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Facility:    *facility,
+		Settings:    *settings,
+	}
+
+	return render(c, page.FacilitySettings(props))
+}
+
+// PUT /app/:facility_code/settings
+func (h *Handler) HandleUpdateFacilitySettings(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleUpdateFacilitySettings").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	settings, errs := parseFacilitySettings(c, facility.ID)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// Kept for the audit trail
+	before, err := h.repos.Facility.GetSettings(c.Request().Context(), facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to fetch facility settings")
+		return response.System(c)
+	}
+
+	saved, err := h.saveFacilitySettings(c.Request().Context(), settings, false)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to save facility settings")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Str("time_zone", saved.TimeZone).
+		Str("publication_policy", string(saved.PublicationPolicy)).
+		Msg("facility settings updated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditSettings,
+		EntityID:   strconv.Itoa(facility.ID),
+		Action:     entity.AuditUpdate,
+	}, before, saved)
+
+	return render(c, ComponentGroup(
+		alert.Success("Settings Saved", fmt.Sprintf("Settings for %s have been updated.", facility.Code)),
+		page.FacilitySettingsForm(facility.Code, *saved),
+	))
+}
+
+// saveFacilitySettings stores the settings and, for rolling publication or
+// when publish is set, moves the facility's publication date to the end of
+// the rolling window
+func (h *Handler) saveFacilitySettings(ctx context.Context, settings entity.FacilitySettings, publish bool) (*entity.FacilitySettings, error) {
+	saved, err := h.repos.Facility.UpdateSettings(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if publish || saved.PublicationPolicy == entity.PublicationRolling {
+		through := saved.RollingPublishedThrough(saved.Today(time.Now()))
+		if _, err := h.repos.Publication.Update(ctx, saved.FacilityID, through); err != nil {
+			return nil, fmt.Errorf("updating publication date: %w", err)
+		}
+	}
+
+	return saved, nil
+}
+
+// parseFacilitySettings reads and validates the settings form
+func parseFacilitySettings(c echo.Context, facilityID int) (entity.FacilitySettings, []string) {
+	var errs []string
+
+	weekday := func(name string) (time.Weekday, bool) {
+		d, err := strconv.Atoi(c.FormValue(name))
+		return time.Weekday(d), err == nil && d >= 0 && d <= 6
+	}
+
+	first, ok1 := weekday("first_weekday")
+	second, ok2 := weekday("second_weekday")
+	if !ok1 || !ok2 {
+		errs = append(errs, "Please choose both default days off")
+	} else if first == second {
+		errs = append(errs, "The default days off must be different")
+	}
+
+	weekStart, ok := weekday("week_start")
+	if !ok {
+		errs = append(errs, "Please choose the first day of the week")
+	}
+
+	zone := strings.TrimSpace(c.FormValue("time_zone"))
+	if _, err := time.LoadLocation(zone); err != nil || zone == "" || zone == "Local" {
+		errs = append(errs, "Please choose a valid time zone, such as America/Chicago")
+	}
+
+	weeks, err := strconv.Atoi(c.FormValue("publish_weeks_ahead"))
+	if err != nil || weeks < 1 || weeks > 52 {
+		errs = append(errs, "Publish ahead must be between 1 and 52 weeks")
+	}
+
+	policy := entity.PublicationPolicy(c.FormValue("publication_policy"))
+	if policy != entity.PublicationManual && policy != entity.PublicationRolling {
+		errs = append(errs, "Please choose a publication policy")
+	}
+
+	deadline, err := strconv.Atoi(c.FormValue("availability_deadline_days"))
+	if err != nil || deadline < 0 || deadline > maxAvailabilityDeadlineDays {
+		errs = append(errs, fmt.Sprintf("The availability deadline must be between 0 and %d days", maxAvailabilityDeadlineDays))
+	}
+
+	return entity.FacilitySettings{
+		FacilityID:               facilityID,
+		DefaultFirstWeekday:      first,
+		DefaultSecondWeekday:     second,
+		PublishWeeksAhead:        weeks,
+		TimeZone:                 zone,
+		WeekStart:                weekStart,
+		PublicationPolicy:        policy,
+		AvailabilityDeadlineDays: deadline,
+	}, errs
+}
+
+// facilityToday returns the current date at the facility along with its settings
+func (h *Handler) facilityToday(ctx context.Context, facilityCode string) (time.Time, *entity.FacilitySettings, error) {
+	settings, err := h.repos.Facility.GetSettingsByCode(ctx, facilityCode)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return settings.Today(time.Now()), settings, nil
+}
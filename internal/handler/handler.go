@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/DukeRupert/haven/internal/mail"
 	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/oidc"
+	"github.com/DukeRupert/haven/internal/ratelimit"
 	"github.com/DukeRupert/haven/internal/repository"
+	"github.com/DukeRupert/haven/internal/repository/sso"
+	"github.com/DukeRupert/haven/internal/secret"
 	"github.com/DukeRupert/haven/web/view/page"
 
 	"github.com/labstack/echo/v4"
@@ -22,17 +28,22 @@ type Config struct {
 	Logger       zerolog.Logger
 	BaseURL      string
 	MailerConfig MailerConfig
+	OIDC         *oidc.Config // Global single sign-on provider, if any
+	Secrets      *secret.Box  // Seals facility client secrets and TOTP secrets
 }
 
 type MailerConfig struct {
-	BaseURL     string
-	ServerToken string
-	FromEmail   string
-	FromName    string
+	BaseURL   string
+	Transport mail.Transport
+	FromEmail string
+	FromName  string
 }
 
 type Handler struct {
 	repos    *repository.Repositories
+	limiter  *ratelimit.Limiter
+	sso      *oidc.Registry
+	secrets  *secret.Box
 	logger   zerolog.Logger
 	config   Cfg
 	mailer   *mail.Mailer
@@ -40,31 +51,41 @@ type Handler struct {
 }
 
 func New(cfg Config) (*Handler, error) {
-	// Initialize mail client
-	mailClient := mail.NewClient(cfg.MailerConfig.ServerToken, cfg.Logger.With().Str("component", "postmark_client").Logger())
 	mailer, err := mail.NewMailer(
-		mailClient,
+		cfg.MailerConfig.Transport,
 		cfg.MailerConfig.FromEmail,
 		cfg.MailerConfig.FromName,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Secrets == nil {
+		return nil, errors.New("secret box is required")
+	}
 
 	return &Handler{
-		repos:  cfg.Repos,
-		logger: cfg.Logger.With().Str("component", "handler").Logger(),
-		config: Cfg{BaseURL: cfg.BaseURL},
-		mailer: mailer,
+		repos:   cfg.Repos,
+		limiter: ratelimit.New(cfg.Repos.RateLimit),
+		sso:     oidc.NewRegistry(cfg.OIDC, cfg.Repos.SSO, cfg.Secrets, sso.ErrNotFound, cfg.BaseURL+"/login/oidc/callback"),
+		secrets: cfg.Secrets,
+		logger:  cfg.Logger.With().Str("component", "handler").Logger(),
+		config:  Cfg{BaseURL: cfg.BaseURL},
+		mailer:  mailer,
 	}, nil
 }
 
 func (h *Handler) GetLogin(c echo.Context) error {
-	// If redirected from set-password
+	// If redirected after setting or resetting a password,
 	// notify the user of the successful password update
-	verify := c.QueryParam("verify") == "success"
+	var notice string
+	switch {
+	case c.QueryParam("verify") == "success":
+		notice = "Your password has been set successfully. You can now log in to your account."
+	case c.QueryParam("reset") == "success":
+		notice = "Your password has been reset and you have been signed out on every device. You can now log in with your new password."
+	}
 	// Else simply return the login page
-	return render(c, page.Login(verify))
+	return render(c, page.Login(notice))
 }
 
 func (h *Handler) GetHome(c echo.Context) error {
@@ -9,12 +9,16 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/DukeRupert/haven/internal/model/dto"
 	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/params"
 	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	"github.com/DukeRupert/haven/internal/repository/membership"
 	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/validation"
 	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
@@ -40,86 +44,108 @@ func ensureRouteParams(routeCtx *dto.RouteContext) error {
 	return nil
 }
 
-func canUpdateUser(auth *dto.AuthContext, targetUserID int) bool {
-	if auth.Role == types.UserRoleSuper {
+// managedUser is the target of a change to another user's account: whether
+// they are a super user and every permission they hold at the facility
+type managedUser struct {
+	ID    int
+	Super bool
+	Perms []types.Permission
+}
+
+// canManageUser reports whether the authenticated user may change the
+// target's profile, password, second factor or sessions at the facility.
+// Users may always change their own account. Others need users.manage and
+// must hold every permission the target holds, so nobody can take over an
+// account that outranks them. Only super users may change super users.
+func canManageUser(auth *dto.AuthContext, facilityCode string, target managedUser) bool {
+	if auth.UserID == target.ID {
 		return true
 	}
-	if auth.Role == types.UserRoleAdmin {
-		return true
+	if target.Super && auth.Role != types.UserRoleSuper {
+		return false
 	}
-	return auth.UserID == targetUserID
+	return auth.CanAt(facilityCode, types.PermUsersManage) &&
+		auth.CanGrantAt(facilityCode, target.Perms)
+}
+
+// managedUser loads the target of an account change at the facility
+func (h *Handler) managedUser(ctx context.Context, userID int, facilityCode string) (managedUser, error) {
+	user, err := h.repos.User.GetByID(ctx, userID)
+	if err != nil {
+		return managedUser{}, fmt.Errorf("getting user: %w", err)
+	}
+	target := managedUser{
+		ID:    user.ID,
+		Super: user.Role == types.UserRoleSuper,
+	}
+
+	facility, err := h.repos.Facility.GetByCode(ctx, facilityCode)
+	if err != nil {
+		return managedUser{}, fmt.Errorf("getting facility: %w", err)
+	}
+	m, err := h.repos.Membership.Get(ctx, userID, facility.ID)
+	if errors.Is(err, membership.ErrNotFound) {
+		if user.FacilityID == facility.ID {
+			target.Perms = user.Role.Permissions()
+		}
+		return target, nil
+	}
+	if err != nil {
+		return managedUser{}, fmt.Errorf("getting membership: %w", err)
+	}
+	target.Super = target.Super || m.Role == types.UserRoleSuper
+	target.Perms = append(append(target.Perms, m.Role.Permissions()...), m.FacilityRolePerms...)
+	return target, nil
 }
 
 func isHtmxRequest(c echo.Context) bool {
 	return c.Request().Header.Get("HX-Request") == "true"
 }
 
-// Verify if the authenticated user can change the target user's role
-func (h *Handler) verifyRoleChange(auth *dto.AuthContext, existingRole, newRole types.UserRole) error {
-    // No role change, no validation needed
-    if existingRole == newRole {
-        return nil
-    }
-
-    // Validate role change permissions
-    if auth.Role != types.UserRoleSuper && newRole == types.UserRoleSuper {
-        return response.Error(echo.New().AcquireContext(),
-            http.StatusForbidden,
-            "Invalid Role",
-            []string{"Only super admins can assign super admin role"})
-    }
-
-    if auth.Role == types.UserRoleUser {
-        return response.Error(echo.New().AcquireContext(),
-            http.StatusForbidden,
-            "Invalid Role",
-            []string{"Users cannot change roles"})
-    }
-
-    return nil
-}
+// errRoleNotGrantable is shown when a user tries to hand out permissions
+// they do not hold
+const errRoleNotGrantable = "You can only assign roles whose permissions you hold yourself"
 
-func getUserID(c echo.Context) (int, error) {
-	return strconv.Atoi(c.Param("user_id"))
-}
+// roleChangeError explains why the authenticated user cannot change the
+// target user's built-in role at the facility, or returns "" if they can
+func roleChangeError(auth *dto.AuthContext, facilityCode string, targetUserID int, existingRole, newRole types.UserRole) string {
+	// No role change, no validation needed
+	if existingRole == newRole {
+		return ""
+	}
 
-func canAccessUserForm(auth *dto.AuthContext, targetUserID int) bool {
-	// Super admins can edit anyone
-	if auth.Role == types.UserRoleSuper {
-		return true
+	if auth.Role != types.UserRoleSuper && newRole == types.UserRoleSuper {
+		return "Only super admins can assign super admin role"
+	}
+	if !auth.CanAt(facilityCode, types.PermUsersManage) {
+		return "You don't have permission to change roles"
+	}
+	if auth.Role != types.UserRoleSuper && auth.UserID == targetUserID {
+		return "You can't change your own role"
 	}
 
-	// Admins can edit non-super users
-	if auth.Role == types.UserRoleAdmin {
-		return true
+	// Users can neither promote others beyond their own permissions nor
+	// demote those who hold more
+	if !auth.CanGrantAt(facilityCode, newRole.Permissions()) ||
+		!auth.CanGrantAt(facilityCode, existingRole.Permissions()) {
+		return errRoleNotGrantable
 	}
 
-	// Users can only edit themselves
-	return auth.UserID == targetUserID
+	return ""
 }
 
-func canDeleteUser(auth *dto.AuthContext, targetUser *entity.User) bool {
-	switch auth.Role {
-	case types.UserRoleSuper:
-		return true
-	case types.UserRoleAdmin:
-		// Admin can delete users in their facility except themselves
-		return auth.UserID != targetUser.ID &&
-			auth.FacilityID == targetUser.FacilityID
-	default:
-		return false
-	}
+func getUserID(c echo.Context) (int, error) {
+	return strconv.Atoi(c.Param("user_id"))
 }
 
-func canUpdatePassword(auth *dto.AuthContext, targetUserID int) bool {
-	switch auth.Role {
-	case types.UserRoleSuper:
-		return true
-	case types.UserRoleAdmin:
+func canDeleteUser(auth *dto.AuthContext, targetUser *entity.User) bool {
+	if auth.Role == types.UserRoleSuper {
 		return true
-	default:
-		return auth.UserID == targetUserID
 	}
+	// Others can delete users in their facility except themselves
+	return auth.Can(types.PermUsersManage) &&
+		auth.UserID != targetUser.ID &&
+		auth.FacilityID == targetUser.FacilityID
 }
 
 type passwordUpdateData struct {
@@ -127,9 +153,9 @@ type passwordUpdateData struct {
 	Password string
 }
 
-func (h *Handler) validatePasswordUpdate(c echo.Context, user entity.User, auth *dto.AuthContext) (*passwordUpdateData, error) {
+func (h *Handler) validatePasswordUpdate(c echo.Context, user entity.User, target managedUser, facilityCode string, auth *dto.AuthContext) (*passwordUpdateData, error) {
 	// Check authorization
-	if !canUpdatePassword(auth, user.ID) {
+	if !canManageUser(auth, facilityCode, target) {
 		return nil, response.Error(c,
 			http.StatusForbidden,
 			"Unauthorized",
@@ -180,13 +206,14 @@ func hashPassword(password string) ([]byte, error) {
 }
 
 type createUserData struct {
-	FirstName  string
-	LastName   string
-	Email      string
-	Password   string
-	Initials   string
-	Role       types.UserRole
-	FacilityID int
+	FirstName    string
+	LastName     string
+	Email        string
+	Password     string
+	Initials     string
+	Role         types.UserRole
+	FacilityID   int
+	FacilityCode string
 }
 
 func (h *Handler) validateCreateUser(c echo.Context) (*createUserData, error) {
@@ -209,6 +236,7 @@ func (h *Handler) validateCreateUser(c echo.Context) (*createUserData, error) {
 
 	// Validate facility
 	var facilityID int
+	var facilityCode string
 	if formParams.FacilityCode == "" {
 		errors = append(errors, "Facility code is required")
 	} else {
@@ -224,6 +252,22 @@ func (h *Handler) validateCreateUser(c echo.Context) (*createUserData, error) {
 			errors = append(errors, "Invalid facility code")
 		} else {
 			facilityID = facility.ID
+			facilityCode = facility.Code
+		}
+	}
+
+	// Initials address the user within the facility so must be unique there
+	initials, err := validation.ValidateUserInitials(formParams.Initials)
+	if err != nil {
+		errors = append(errors, err.Error())
+	} else if facilityID != 0 {
+		available, err := h.repos.User.IsInitialsAvailable(c.Request().Context(), facilityID, string(initials), nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check initials availability")
+			return nil, response.System(c)
+		}
+		if !available {
+			errors = append(errors, fmt.Sprintf("Someone at %s already uses the initials %s", facilityCode, initials))
 		}
 	}
 
@@ -238,13 +282,14 @@ func (h *Handler) validateCreateUser(c echo.Context) (*createUserData, error) {
 	}
 
 	return &createUserData{
-		FirstName:  formParams.FirstName,
-		LastName:   formParams.LastName,
-		Email:      formParams.Email,
-		Password:   formParams.Password,
-		Initials:   formParams.Initials,
-		Role:       formParams.Role,
-		FacilityID: facilityID,
+		FirstName:    formParams.FirstName,
+		LastName:     formParams.LastName,
+		Email:        formParams.Email,
+		Password:     formParams.Password,
+		Initials:     string(initials),
+		Role:         formParams.Role,
+		FacilityID:   facilityID,
+		FacilityCode: facilityCode,
 	}, nil
 }
 
@@ -258,14 +303,10 @@ func validateEmail(email string) error {
 }
 
 func canCreateUsers(auth *dto.AuthContext, facilityID int) bool {
-	switch auth.Role {
-	case types.UserRoleSuper:
+	if auth.Role == types.UserRoleSuper {
 		return true
-	case types.UserRoleAdmin:
-		return auth.FacilityID == facilityID
-	default:
-		return false
 	}
+	return auth.Can(types.PermUsersManage) && auth.FacilityID == facilityID
 }
 
 // Reduce boilerplate for simple templ component renders
@@ -286,6 +327,24 @@ func ComponentGroup(components ...templ.Component) templ.Component {
 	})
 }
 
+// allowRequest checks an account-level rate limit for the subject. Store
+// errors fail open so a database hiccup does not block sign in.
+func (h *Handler) allowRequest(c echo.Context, policy ratelimit.Policy, subject string) (ratelimit.Result, bool) {
+	result, err := h.limiter.Allow(c.Request().Context(), policy, subject)
+	if err != nil {
+		h.logger.Error().Err(err).Str("policy", policy.Name).Msg("rate limit check failed")
+		return ratelimit.Result{Allowed: true}, true
+	}
+	if !result.Allowed {
+		h.logger.Warn().
+			Str("policy", policy.Name).
+			Str("ip", c.RealIP()).
+			Dur("retry_after", result.RetryAfter).
+			Msg("rate limit exceeded")
+	}
+	return result, result.Allowed
+}
+
 // generateSecureToken creates a cryptographically secure token for registration
 func generateSecureToken() (string, error) {
 	// Generate 32 bytes of random data
@@ -314,3 +373,12 @@ func ValidateFacilityCode(c echo.Context, logger zerolog.Logger) (string, error)
 	}
 	return facilityCode, nil
 }
+
+// truncateRunes shortens s to at most n characters without splitting a
+// multi-byte character
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
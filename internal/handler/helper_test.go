@@ -0,0 +1,106 @@
+// internal/handler/helper_test.go
+package handler
+
+import (
+	"testing"
+
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+func authAt(userID int, role types.UserRole, memberships ...entity.FacilityMembership) *dto.AuthContext {
+	return &dto.AuthContext{AuthContextData: dto.AuthContextData{
+		UserID:       userID,
+		Role:         role,
+		FacilityCode: "KHLN",
+		Memberships:  memberships,
+	}}
+}
+
+func TestCanManageUser(t *testing.T) {
+	admin := authAt(1, types.UserRoleAdmin,
+		entity.FacilityMembership{FacilityCode: "KHLN", Role: types.UserRoleAdmin})
+	manager := authAt(2, types.UserRoleUser,
+		entity.FacilityMembership{
+			FacilityCode:      "KHLN",
+			Role:              types.UserRoleUser,
+			FacilityRolePerms: []types.Permission{types.PermUsersManage},
+		})
+	super := authAt(3, types.UserRoleSuper)
+	plain := authAt(4, types.UserRoleUser,
+		entity.FacilityMembership{FacilityCode: "KHLN", Role: types.UserRoleUser})
+
+	user := managedUser{ID: 10}
+	adminTarget := managedUser{ID: 11, Perms: types.UserRoleAdmin.Permissions()}
+	superTarget := managedUser{ID: 12, Super: true, Perms: types.UserRoleSuper.Permissions()}
+	peer := managedUser{ID: 13, Perms: []types.Permission{types.PermUsersManage}}
+
+	tests := []struct {
+		name   string
+		auth   *dto.AuthContext
+		code   string
+		target managedUser
+		want   bool
+	}{
+		{"own account", plain, "KHLN", managedUser{ID: 4}, true},
+		{"plain user on another", plain, "KHLN", user, false},
+		{"admin on user", admin, "KHLN", user, true},
+		{"admin on admin", admin, "KHLN", adminTarget, true},
+		{"admin on super", admin, "KHLN", superTarget, false},
+		{"admin at another facility", admin, "KSEA", user, false},
+		{"users.manage on user", manager, "KHLN", user, true},
+		{"users.manage on peer", manager, "KHLN", peer, true},
+		{"users.manage on admin", manager, "KHLN", adminTarget, false},
+		{"users.manage on super", manager, "KHLN", superTarget, false},
+		{"super on super", super, "KHLN", superTarget, true},
+		{"super on admin", super, "KSEA", adminTarget, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canManageUser(tt.auth, tt.code, tt.target); got != tt.want {
+				t.Errorf("canManageUser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleChangeError(t *testing.T) {
+	admin := authAt(1, types.UserRoleAdmin,
+		entity.FacilityMembership{FacilityCode: "KHLN", Role: types.UserRoleAdmin})
+	manager := authAt(2, types.UserRoleUser,
+		entity.FacilityMembership{
+			FacilityCode:      "KHLN",
+			Role:              types.UserRoleUser,
+			FacilityRolePerms: []types.Permission{types.PermUsersManage},
+		})
+	super := authAt(3, types.UserRoleSuper)
+
+	tests := []struct {
+		name     string
+		auth     *dto.AuthContext
+		target   int
+		from, to types.UserRole
+		wantOK   bool
+	}{
+		{"unchanged", manager, 10, types.UserRoleAdmin, types.UserRoleAdmin, true},
+		{"admin promotes user", admin, 10, types.UserRoleUser, types.UserRoleAdmin, true},
+		{"admin demotes admin", admin, 10, types.UserRoleAdmin, types.UserRoleUser, true},
+		{"admin promotes to super", admin, 10, types.UserRoleUser, types.UserRoleSuper, false},
+		{"admin changes own role", admin, 1, types.UserRoleAdmin, types.UserRoleUser, false},
+		{"users.manage promotes to admin", manager, 10, types.UserRoleUser, types.UserRoleAdmin, false},
+		{"users.manage demotes admin", manager, 10, types.UserRoleAdmin, types.UserRoleUser, false},
+		{"super promotes to super", super, 10, types.UserRoleAdmin, types.UserRoleSuper, true},
+		{"super changes own role", super, 3, types.UserRoleSuper, types.UserRoleAdmin, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := roleChangeError(tt.auth, "KHLN", tt.target, tt.from, tt.to)
+			if (msg == "") != tt.wantOK {
+				t.Errorf("roleChangeError() = %q, want allowed %v", msg, tt.wantOK)
+			}
+		})
+	}
+}
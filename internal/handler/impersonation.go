@@ -0,0 +1,151 @@
+// internal/handler/impersonation.go
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/store"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// POST /app/:facility_code/:user_initials/impersonate
+func (h *Handler) HandleStartImpersonation(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleStartImpersonation").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	target, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("initials", route.UserInitials).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to get user")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	}
+
+	if target.ID == auth.UserID || target.Role == types.UserRoleSuper {
+		return response.Error(c, http.StatusForbidden, "Not Allowed",
+			[]string{"You can't view the app as this user"})
+	}
+
+	facility, err := h.repos.Facility.GetByID(ctx, target.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", target.FacilityID).Msg("failed to get facility")
+		return response.System(c)
+	}
+
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get session")
+		return response.System(c)
+	}
+
+	setSessionIdentity(sess, target, facility)
+	sess.Values[store.SessionKeyImpersonatorID] = auth.UserID
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return response.System(c)
+	}
+
+	h.recordImpersonation(c, auth.UserID, target.ID, entity.ImpersonationStart)
+
+	logger.Info().
+		Int("impersonator_id", auth.UserID).
+		Int("user_id", target.ID).
+		Msg("impersonation started")
+
+	c.Response().Header().Set("HX-Redirect", "/app/calendar")
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /app/impersonate/stop
+func (h *Handler) HandleStopImpersonation(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleStopImpersonation").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	if !auth.IsImpersonating() {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request",
+			[]string{"You are not viewing as another user"})
+	}
+
+	impersonator, err := h.repos.User.GetByID(ctx, auth.ImpersonatorID)
+	if err != nil {
+		logger.Error().Err(err).Int("impersonator_id", auth.ImpersonatorID).Msg("failed to get impersonator")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByID(ctx, impersonator.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", impersonator.FacilityID).Msg("failed to get facility")
+		return response.System(c)
+	}
+
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get session")
+		return response.System(c)
+	}
+
+	setSessionIdentity(sess, impersonator, facility)
+	delete(sess.Values, store.SessionKeyImpersonatorID)
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return response.System(c)
+	}
+
+	h.recordImpersonation(c, impersonator.ID, auth.UserID, entity.ImpersonationStop)
+
+	logger.Info().
+		Int("impersonator_id", impersonator.ID).
+		Int("user_id", auth.UserID).
+		Msg("impersonation stopped")
+
+	// Return to the profile of the user that was being viewed
+	c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/%s/%s", auth.FacilityCode, auth.Initials))
+	return c.NoContent(http.StatusOK)
+}
+
+// recordImpersonation adds a start or stop event to the audit trail
+func (h *Handler) recordImpersonation(c echo.Context, impersonatorID, userID int, action string) {
+	err := h.repos.Impersonation.Record(c.Request().Context(), entity.ImpersonationEvent{
+		ImpersonatorID: impersonatorID,
+		UserID:         userID,
+		Action:         action,
+		Method:         c.Request().Method,
+		Path:           c.Request().URL.Path,
+		Status:         http.StatusOK,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Str("action", action).Msg("failed to record impersonation event")
+	}
+}
@@ -0,0 +1,270 @@
+// internal/handler/invitation.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	"github.com/DukeRupert/haven/internal/repository/invitation"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// errInvitationLimited is returned when an invitation was resent too recently
+var errInvitationLimited = errors.New("invitation resent too recently")
+
+// GET /app/:facility_code/users/invitations
+func (h *Handler) HandleInvitations(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleInvitations").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	invitations, err := h.repos.Invitation.ListByFacility(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to retrieve invitations")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load invitations. Please try again later.",
+		)
+	}
+
+	// Ensure invitations is never nil
+	if invitations == nil {
+		invitations = []entity.Invitation{}
+	}
+
+	props := dto.InvitationsPageProps{
+		Title:       "Pending Invitations",
+		Description: "Users who have been invited but have not finished registering.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Invitations: invitations,
+	}
+
+	return render(c, page.Invitations(props))
+}
+
+// POST /app/:facility_code/users/invitations/:user_id/resend
+func (h *Handler) HandleResendInvitation(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleResendInvitation").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid user ID"})
+	}
+
+	inv, err := h.resendInvitation(c, route.FacilityCode, userID, logger)
+	if errors.Is(err, invitation.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Invitation not found"})
+	}
+	if errors.Is(err, errInvitationLimited) {
+		return response.Error(c, http.StatusTooManyRequests, "Too Many Requests",
+			[]string{"This invitation was resent recently. Please wait before sending it again."})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to resend invitation")
+		return response.System(c)
+	}
+
+	return render(c, ComponentGroup(
+		alert.Success("Invitation Sent", fmt.Sprintf("A new invitation has been sent to %s.", inv.Email)),
+		page.InvitationListItem(route.FacilityCode, *inv),
+	))
+}
+
+// DELETE /app/:facility_code/users/invitations/:user_id
+func (h *Handler) HandleRevokeInvitation(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleRevokeInvitation").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid user ID"})
+	}
+
+	inv, err := h.revokeInvitation(c.Request().Context(), route.FacilityCode, userID, logger)
+	if errors.Is(err, invitation.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Invitation not found"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to revoke invitation")
+		return response.System(c)
+	}
+
+	return render(c, ComponentGroup(
+		alert.Success("Invitation Revoked", fmt.Sprintf("The invitation for %s can no longer be used.", inv.Email)),
+		page.InvitationListItem(route.FacilityCode, *inv),
+	))
+}
+
+// POST /app/:facility_code/users/invitations/resend
+func (h *Handler) HandleBulkResendInvitations(c echo.Context) error {
+	return h.bulkInvitationAction(c, "HandleBulkResendInvitations", "resent",
+		func(facilityCode string, userID int, logger zerolog.Logger) error {
+			_, err := h.resendInvitation(c, facilityCode, userID, logger)
+			return err
+		})
+}
+
+// POST /app/:facility_code/users/invitations/revoke
+func (h *Handler) HandleBulkRevokeInvitations(c echo.Context) error {
+	return h.bulkInvitationAction(c, "HandleBulkRevokeInvitations", "revoked",
+		func(facilityCode string, userID int, logger zerolog.Logger) error {
+			_, err := h.revokeInvitation(c.Request().Context(), facilityCode, userID, logger)
+			return err
+		})
+}
+
+// bulkInvitationAction applies an action to every selected invitation and
+// re-renders the list
+func (h *Handler) bulkInvitationAction(c echo.Context, name, verb string, action func(string, int, zerolog.Logger) error) error {
+	logger := h.logger.With().
+		Str("handler", name).
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Please check your input and try again."})
+	}
+	if len(form["user_id"]) == 0 {
+		return response.Validation(c, []string{"Select at least one invitation"})
+	}
+
+	var done int
+	var failures []string
+	for _, raw := range form["user_id"] {
+		userID, err := strconv.Atoi(raw)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("Invalid user ID %q", raw))
+			continue
+		}
+		switch err := action(route.FacilityCode, userID, logger); {
+		case err == nil:
+			done++
+		case errors.Is(err, invitation.ErrNotFound):
+			failures = append(failures, fmt.Sprintf("User %d has no pending invitation", userID))
+		case errors.Is(err, errInvitationLimited):
+			failures = append(failures, fmt.Sprintf("User %d was sent an invitation recently", userID))
+		default:
+			logger.Error().Err(err).Int("user_id", userID).Msg("bulk invitation action failed")
+			failures = append(failures, fmt.Sprintf("User %d could not be updated", userID))
+		}
+	}
+
+	invitations, err := h.repos.Invitation.ListByFacility(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to retrieve invitations")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("succeeded", done).
+		Int("failed", len(failures)).
+		Msg("bulk invitation action completed")
+
+	result := alert.Success("Invitations Updated", fmt.Sprintf("%d invitation(s) %s.", done, verb))
+	if len(failures) > 0 {
+		result = alert.Error(fmt.Sprintf("%d invitation(s) %s", done, verb), failures)
+	}
+
+	return render(c, ComponentGroup(
+		result,
+		page.InvitationList(route.FacilityCode, invitations),
+	))
+}
+
+// resendInvitation invalidates any outstanding links and emails a new one
+func (h *Handler) resendInvitation(c echo.Context, facilityCode string, userID int, logger zerolog.Logger) (*entity.Invitation, error) {
+	ctx := c.Request().Context()
+
+	inv, err := h.repos.Invitation.Get(ctx, facilityCode, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Share the public resend limit so admins cannot flood an inbox
+	if _, ok := h.allowRequest(c, ratelimit.ResendAccount, inv.Email); !ok {
+		return nil, errInvitationLimited
+	}
+
+	user, err := h.repos.User.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.repos.Invitation.Renew(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := h.SendVerificationEmail(ctx, user, logger); err != nil {
+		return nil, err
+	}
+
+	logger.Info().Int("user_id", userID).Msg("invitation resent")
+	return h.repos.Invitation.Get(ctx, facilityCode, userID)
+}
+
+// revokeInvitation invalidates any outstanding links without deleting the user
+func (h *Handler) revokeInvitation(ctx context.Context, facilityCode string, userID int, logger zerolog.Logger) (*entity.Invitation, error) {
+	if _, err := h.repos.Invitation.Get(ctx, facilityCode, userID); err != nil {
+		return nil, err
+	}
+
+	if err := h.repos.Invitation.Revoke(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	logger.Info().Int("user_id", userID).Msg("invitation revoked")
+	return h.repos.Invitation.Get(ctx, facilityCode, userID)
+}
@@ -0,0 +1,111 @@
+// internal/handler/lockout.go
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/repository/lockout"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// How far back the lockouts page looks
+const lockoutHistory = 30 * 24 * time.Hour
+
+// GET /app/:facility_code/users/lockouts
+func (h *Handler) HandleLockouts(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleLockouts").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	lockouts, err := h.repos.Lockout.ListByFacility(c.Request().Context(), route.FacilityCode, time.Now().Add(-lockoutHistory))
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to retrieve lockouts")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load lockouts. Please try again later.",
+		)
+	}
+
+	// Ensure lockouts is never nil
+	if lockouts == nil {
+		lockouts = []entity.AccountLockout{}
+	}
+
+	props := dto.LockoutsPageProps{
+		Title:       "Account Lockouts",
+		Description: "Accounts locked after repeated failed sign in attempts in the last 30 days.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Lockouts:    lockouts,
+	}
+
+	return render(c, page.Lockouts(props))
+}
+
+// POST /app/:facility_code/users/lockouts/:lockout_id/unlock
+func (h *Handler) HandleUnlockAccount(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleUnlockAccount").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	lockoutID, err := strconv.Atoi(c.Param("lockout_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid lockout ID"})
+	}
+
+	l, err := h.repos.Lockout.Unlock(c.Request().Context(), lockoutID, route.FacilityCode, auth.UserID)
+	if errors.Is(err, lockout.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Lockout not found"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("lockout_id", lockoutID).Msg("failed to unlock account")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("lockout_id", l.ID).
+		Int("user_id", l.UserID).
+		Int("unlocked_by", auth.UserID).
+		Msg("account unlocked by admin")
+
+	return render(c, page.LockoutListItem(route.FacilityCode, *l))
+}
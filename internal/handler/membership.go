@@ -0,0 +1,153 @@
+// internal/handler/membership.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/repository/membership"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/store"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// POST /app/facility
+func (h *Handler) HandleSwitchFacility(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleSwitchFacility").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(c.FormValue("facility_code")))
+	m, ok := auth.Membership(code)
+	if !ok || m.IsArchived() {
+		logger.Warn().
+			Int("user_id", auth.UserID).
+			Str("facility_code", code).
+			Msg("switch to facility without membership")
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{"You are not a member of that facility"})
+	}
+
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get session")
+		return response.System(c)
+	}
+
+	sess.Values[store.SessionKeyFacilityID] = m.FacilityID
+	sess.Values[store.SessionKeyFacilityCode] = m.FacilityCode
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", auth.UserID).
+		Str("facility_code", m.FacilityCode).
+		Msg("switched facility")
+
+	c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/%s/calendar", m.FacilityCode))
+	return c.NoContent(http.StatusOK)
+}
+
+// POST /app/:facility_code/users/members
+func (h *Handler) HandleAddMember(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleAddMember").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	ctx := c.Request().Context()
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	email := strings.ToLower(strings.TrimSpace(c.FormValue("email")))
+	role := types.UserRole(c.FormValue("role"))
+	if role != types.UserRoleAdmin && role != types.UserRoleUser {
+		return response.Validation(c, []string{"Please choose a role of admin or user"})
+	}
+	if !auth.CanGrantAt(facility.Code, role.Permissions()) {
+		return response.Error(c, http.StatusForbidden, "Invalid Role", []string{errRoleNotGrantable})
+	}
+
+	user, err := h.repos.User.GetByEmail(ctx, email)
+	if errors.Is(err, userRepo.ErrNotFound) {
+		return response.Validation(c, []string{"No account uses that email address"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get user by email")
+		return response.System(c)
+	}
+	if user.Role == types.UserRoleSuper {
+		return response.Validation(c, []string{"Super admins already have access to every facility"})
+	}
+	if user.IsDeactivated() {
+		return response.Validation(c, []string{fmt.Sprintf("%s has been deactivated", user.Email)})
+	}
+	if user.FacilityID == facility.ID {
+		return response.Validation(c, []string{fmt.Sprintf("%s is already based at %s", user.Email, facility.Code)})
+	}
+
+	m, err := h.repos.Membership.Add(ctx, user.ID, facility.ID, role)
+	if err != nil {
+		if errors.Is(err, membership.ErrInitialsTaken) {
+			return response.Validation(c, []string{
+				fmt.Sprintf("Someone at %s already uses the initials %s", facility.Code, user.Initials),
+			})
+		}
+		logger.Error().Err(err).Int("user_id", user.ID).Int("facility_id", facility.ID).Msg("failed to add membership")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Int("facility_id", facility.ID).
+		Str("role", string(m.Role)).
+		Msg("facility member added")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditMembership,
+		EntityID:   strconv.Itoa(user.ID),
+		Action:     entity.AuditCreate,
+	}, nil, m)
+
+	user.Role = m.Role
+	return render(c, ComponentGroup(
+		alert.Success("Member Added", fmt.Sprintf("%s %s can now work at %s as %s.", user.FirstName, user.LastName, facility.Code, m.Role)),
+		page.UserListItem(facility.Code, *user),
+	))
+}
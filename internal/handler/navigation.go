@@ -16,7 +16,7 @@ import (
 type RouteConfig struct {
 	Title            string
 	Icon             string
-	MinRole          types.UserRole
+	Permission       types.Permission // Required to see the item, empty for everyone
 	RequiresFacility bool
 	Children         []string
 }
@@ -26,7 +26,6 @@ var RouteConfigs = map[string]RouteConfig{
 	"/calendar": {
 		Title:            "Calendar",
 		Icon:             "calendar",
-		MinRole:          types.UserRoleUser,
 		Children: []string{
 			"/calendar/:date",
 			"/calendar/:date/events",
@@ -35,13 +34,12 @@ var RouteConfigs = map[string]RouteConfig{
 	"/profile": {
 		Title:    "Profile",
 		Icon:     "user",
-		MinRole:  types.UserRoleUser,
 		Children: []string{"/profile/edit"},
 	},
 	"/users": {
 		Title:            "Users",
 		Icon:             "users",
-		MinRole:          types.UserRoleAdmin,
+		Permission:       types.PermUsersManage,
 		RequiresFacility: true,
 		Children: []string{
 			"/users/new",
@@ -49,10 +47,16 @@ var RouteConfigs = map[string]RouteConfig{
 			"/users/:id/edit",
 		},
 	},
+	"/settings": {
+		Title:            "Settings",
+		Icon:             "cog",
+		Permission:       types.PermSettingsManage,
+		RequiresFacility: true,
+	},
 	"/facilities": {
 		Title:   "Facilities",
-		Icon:    "building",
-		MinRole: types.UserRoleSuper,
+		Icon:       "building",
+		Permission: types.PermFacilitiesManage,
 		Children: []string{
 			"/facilities/new",
 			"/facilities/:id",
@@ -135,7 +139,8 @@ var navOrder = map[string]int{
 	"Calendar":   1,
 	"Profile":    2,
 	"Users":      3,
-	"Facilities": 4,
+	"Settings":   4,
+	"Facilities": 5,
 	// Add other items with higher numbers if needed
 }
 
@@ -169,8 +174,8 @@ func BuildNav(routeCtx *dto.RouteContext, auth *dto.AuthContext, currentPath str
 	basePath := "/app"
 
 	for configPath, config := range RouteConfigs {
-		// Skip if user doesn't have required role
-		if !IsAtLeastRole(string(auth.Role), string(config.MinRole)) {
+		// Skip if user doesn't have the required permission
+		if config.Permission != "" && !auth.Can(config.Permission) {
 			continue
 		}
 
@@ -245,21 +250,3 @@ func convertPathToPattern(path string) string {
 	re := regexp.MustCompile(`:[^/]+`)
 	return re.ReplaceAllString(path, "*")
 }
-
-// Helper function to check role hierarchy
-func IsAtLeastRole(userRole string, minRole string) bool {
-	roleHierarchy := map[string]int{
-		string(types.UserRoleSuper): 3,
-		string(types.UserRoleAdmin): 2,
-		string(types.UserRoleUser):  1,
-	}
-
-	userRoleLevel, ok1 := roleHierarchy[userRole]
-	minRoleLevel, ok2 := roleHierarchy[minRole]
-
-	if !ok1 || !ok2 {
-		return false
-	}
-
-	return userRoleLevel >= minRoleLevel
-}
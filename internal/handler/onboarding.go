@@ -0,0 +1,528 @@
+// internal/handler/onboarding.go
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/repository/onboarding"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/validation"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// Limits for the optional user import
+const (
+	maxImportRows  = 500
+	maxImportBytes = 1 << 20
+)
+
+// GET /app/facilities/setup
+func (h *Handler) GetOnboardingStart(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetOnboardingStart").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	props, err := h.onboardingProps(c)
+	if err != nil {
+		logger.Error().Err(err).Msg("missing request context")
+		return response.System(c)
+	}
+
+	return render(c, page.OnboardingStart(*props))
+}
+
+// POST /app/facilities/setup
+// Creates the facility and starts tracking its setup.
+func (h *Handler) HandleOnboardingFacility(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleOnboardingFacility").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	var params params.CreateFacilityParams
+	if err := c.Bind(&params); err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request",
+			[]string{"Please check the form data and try again"})
+	}
+
+	f, errs, err := h.createFacility(c.Request().Context(), params)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+	if err != nil {
+		logger.Error().Err(err).Interface("params", params).Msg("failed to create facility")
+		return response.System(c)
+	}
+
+	if err := h.repos.Onboarding.Start(c.Request().Context(), f.ID, auth.UserID); err != nil {
+		logger.Error().Err(err).Int("facility_id", f.ID).Msg("failed to start onboarding")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", f.ID).
+		Str("code", f.Code).
+		Msg("facility onboarding started")
+
+	return redirectToOnboarding(c, f.ID)
+}
+
+// GET /app/facilities/:facility_id/setup
+// Renders whichever step the facility's setup stopped at.
+func (h *Handler) GetOnboarding(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetOnboarding").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	o, err := h.getOnboarding(c)
+	if err != nil {
+		return err
+	}
+	if o.CompletedAt != nil {
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/app/%s/users", o.FacilityCode))
+	}
+
+	props, err := h.onboardingProps(c)
+	if err != nil {
+		logger.Error().Err(err).Msg("missing request context")
+		return response.System(c)
+	}
+	props.Onboarding = *o
+
+	settings, err := h.repos.Facility.GetSettings(c.Request().Context(), o.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to get facility settings")
+		return response.System(c)
+	}
+	props.Settings = *settings
+
+	return render(c, page.Onboarding(*props))
+}
+
+// POST /app/facilities/:facility_id/setup/admin
+// Creates the facility's first admin and sends their invitation.
+func (h *Handler) HandleOnboardingAdmin(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleOnboardingAdmin").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	o, err := h.getOnboarding(c)
+	if err != nil {
+		return err
+	}
+	if o.Step != entity.OnboardingStepAdmin {
+		return redirectToOnboarding(c, o.FacilityID)
+	}
+
+	var form params.CreateUserParams
+	if err := c.Bind(&form); err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request",
+			[]string{"Please check the form data and try again"})
+	}
+	form.Role = types.UserRoleAdmin
+	form.FacilityID = o.FacilityID
+	form.FacilityCode = o.FacilityCode
+
+	user, errs, err := h.createInvitedUser(c, form, logger)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to create facility admin")
+		return response.System(c)
+	}
+
+	if err := h.repos.Onboarding.SetAdmin(ctx, o.FacilityID, user.ID); err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to record facility admin")
+		return response.System(c)
+	}
+
+	return redirectToOnboarding(c, o.FacilityID)
+}
+
+// POST /app/facilities/:facility_id/setup/settings
+// Saves the facility settings and publishes the initial schedule window.
+func (h *Handler) HandleOnboardingSettings(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleOnboardingSettings").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	o, err := h.getOnboarding(c)
+	if err != nil {
+		return err
+	}
+
+	settings, errs := parseFacilitySettings(c, o.FacilityID)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// Publish the initial window whatever the facility's policy
+	if _, err := h.saveFacilitySettings(ctx, settings, true); err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to save facility settings")
+		return response.System(c)
+	}
+
+	// Settings can be revisited without losing later progress
+	if o.Step == entity.OnboardingStepSettings {
+		if err := h.repos.Onboarding.Advance(ctx, o.FacilityID, entity.OnboardingStepImport); err != nil {
+			logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to advance onboarding")
+			return response.System(c)
+		}
+	}
+
+	return redirectToOnboarding(c, o.FacilityID)
+}
+
+// POST /app/facilities/:facility_id/setup/import
+// Invites every user in the uploaded CSV. The file is checked in full before
+// anyone is created so a corrected file can simply be uploaded again.
+func (h *Handler) HandleOnboardingImport(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleOnboardingImport").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	o, err := h.getOnboarding(c)
+	if err != nil {
+		return err
+	}
+	if o.Step != entity.OnboardingStepImport {
+		return redirectToOnboarding(c, o.FacilityID)
+	}
+
+	file, err := c.FormFile("users_csv")
+	if err != nil {
+		return response.Validation(c, []string{"Choose a CSV file to import, or skip this step"})
+	}
+	if file.Size > maxImportBytes {
+		return response.Validation(c, []string{"The CSV file must be smaller than 1 MB"})
+	}
+	src, err := file.Open()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to open uploaded file")
+		return response.System(c)
+	}
+	defer src.Close()
+
+	rows, errs := parseUserCSV(src, o.FacilityID, o.FacilityCode)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// Report conflicts with existing users before anyone is invited
+	conflicts, err := h.importConflicts(c.Request().Context(), o.FacilityID, rows)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to check import conflicts")
+		return response.System(c)
+	}
+	if len(conflicts) > 0 {
+		return response.Error(c, http.StatusConflict, "Users Already Exist", conflicts)
+	}
+
+	var created int
+	var failures []string
+	for i, row := range rows {
+		_, rowErrs, err := h.createInvitedUser(c, row, logger)
+		if err != nil {
+			logger.Error().Err(err).Str("email", row.Email).Msg("failed to import user")
+			rowErrs = append(rowErrs, "could not be created")
+		}
+		if len(rowErrs) > 0 {
+			failures = append(failures, fmt.Sprintf("Row %d (%s): %s", i+2, row.Email, strings.Join(rowErrs, "; ")))
+			continue
+		}
+		created++
+	}
+
+	logger.Info().
+		Int("facility_id", o.FacilityID).
+		Int("created", created).
+		Int("failed", len(failures)).
+		Msg("user import completed")
+
+	if len(failures) > 0 {
+		// Keep the step open so the remaining rows can be fixed and uploaded again
+		return response.Error(c, http.StatusUnprocessableEntity,
+			fmt.Sprintf("Imported %d of %d users", created, len(rows)), failures)
+	}
+
+	return h.completeOnboarding(c, o, logger)
+}
+
+// POST /app/facilities/:facility_id/setup/finish
+func (h *Handler) HandleOnboardingFinish(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleOnboardingFinish").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	o, err := h.getOnboarding(c)
+	if err != nil {
+		return err
+	}
+	if o.Step != entity.OnboardingStepImport {
+		return redirectToOnboarding(c, o.FacilityID)
+	}
+
+	return h.completeOnboarding(c, o, logger)
+}
+
+func (h *Handler) completeOnboarding(c echo.Context, o *entity.FacilityOnboarding, logger zerolog.Logger) error {
+	if err := h.repos.Onboarding.Advance(c.Request().Context(), o.FacilityID, entity.OnboardingStepComplete); err != nil {
+		logger.Error().Err(err).Int("facility_id", o.FacilityID).Msg("failed to complete onboarding")
+		return response.System(c)
+	}
+
+	logger.Info().Int("facility_id", o.FacilityID).Msg("facility onboarding completed")
+
+	c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/%s/users", o.FacilityCode))
+	return c.String(http.StatusOK, "")
+}
+
+// getOnboarding loads the setup progress for the facility in the URL
+func (h *Handler) getOnboarding(c echo.Context) (*entity.FacilityOnboarding, error) {
+	facilityID, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid facility ID")
+	}
+
+	o, err := h.repos.Onboarding.Get(c.Request().Context(), facilityID)
+	if errors.Is(err, onboarding.ErrNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "This facility has no setup in progress")
+	}
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unable to load facility setup")
+	}
+	return o, nil
+}
+
+func (h *Handler) onboardingProps(c echo.Context) (*dto.OnboardingPageProps, error) {
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		return nil, err
+	}
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OnboardingPageProps{
+		Title:       "Set Up Facility",
+		Description: "Create a facility, invite its first admin and choose its scheduling defaults.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+	}, nil
+}
+
+// createInvitedUser validates and creates a user without a password, then
+// emails their invitation. Validation problems are returned as messages.
+func (h *Handler) createInvitedUser(c echo.Context, form params.CreateUserParams, logger zerolog.Logger) (*entity.User, []string, error) {
+	ctx := c.Request().Context()
+
+	p, errs := validateInvitedUser(form)
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	user, err := h.repos.User.Create(ctx, p)
+	if errors.Is(err, userRepo.ErrInitialsTaken) {
+		return nil, []string{fmt.Sprintf("Initials %s are already used at this facility", p.Initials)}, nil
+	}
+	if errors.Is(err, userRepo.ErrEmailExists) {
+		return nil, []string{"This email address is already in use"}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := h.SendVerificationEmail(ctx, user, logger); err != nil {
+		// The invitation can be resent from the pending invitations page
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to send invitation")
+	}
+
+	return user, nil, nil
+}
+
+// importConflicts lists the rows whose initials or email address are already
+// used, numbered as they appear in the CSV file
+func (h *Handler) importConflicts(ctx context.Context, facilityID int, rows []params.CreateUserParams) ([]string, error) {
+	var conflicts []string
+	for i, row := range rows {
+		var rowErrs []string
+
+		available, err := h.repos.User.IsInitialsAvailable(ctx, facilityID, row.Initials, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !available {
+			rowErrs = append(rowErrs, fmt.Sprintf("initials %s are already used at this facility", row.Initials))
+		}
+
+		unique, err := h.repos.User.IsEmailUnique(ctx, row.Email, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !unique {
+			rowErrs = append(rowErrs, "email address is already in use")
+		}
+
+		if len(rowErrs) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("Row %d: %s", i+2, strings.Join(rowErrs, "; ")))
+		}
+	}
+	return conflicts, nil
+}
+
+// validateInvitedUser normalizes a new user's details
+func validateInvitedUser(form params.CreateUserParams) (params.CreateUserParams, []string) {
+	var errs []string
+
+	first, err := validation.ValidateUserName(form.FirstName, "first name")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	last, err := validation.ValidateUserName(form.LastName, "last name")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	initials, err := validation.ValidateUserInitials(form.Initials)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	email, err := validation.ValidateUserEmail(form.Email)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if form.Role != types.UserRoleAdmin && form.Role != types.UserRoleUser {
+		errs = append(errs, fmt.Sprintf("role must be %s or %s", types.UserRoleAdmin, types.UserRoleUser))
+	}
+
+	return params.CreateUserParams{
+		FirstName:    string(first),
+		LastName:     string(last),
+		Initials:     string(initials),
+		Email:        string(email),
+		Role:         form.Role,
+		FacilityCode: form.FacilityCode,
+		FacilityID:   form.FacilityID,
+	}, errs
+}
+
+// parseUserCSV reads users from a CSV with a header row of first_name,
+// last_name, initials, email and an optional role column. Every row is
+// checked and all problems are reported together.
+func parseUserCSV(r io.Reader, facilityID int, facilityCode string) ([]params.CreateUserParams, []string) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []string{"The CSV file is empty or unreadable"}
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"first_name", "last_name", "initials", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, []string{fmt.Sprintf("The CSV header must include %s", required)}
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []params.CreateUserParams
+	var errs []string
+	seenEmails := make(map[string]int)
+	seenInitials := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Row %d: %v", line, err))
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, []string{fmt.Sprintf("The CSV file can contain at most %d users", maxImportRows)}
+		}
+
+		role := types.UserRole(strings.ToLower(field(record, "role")))
+		if role == "" {
+			role = types.UserRoleUser
+		}
+
+		row, rowErrs := validateInvitedUser(params.CreateUserParams{
+			FirstName:    field(record, "first_name"),
+			LastName:     field(record, "last_name"),
+			Initials:     field(record, "initials"),
+			Email:        field(record, "email"),
+			Role:         role,
+			FacilityCode: facilityCode,
+			FacilityID:   facilityID,
+		})
+		if prev, ok := seenEmails[row.Email]; ok && row.Email != "" {
+			rowErrs = append(rowErrs, fmt.Sprintf("email repeats row %d", prev))
+		}
+		if prev, ok := seenInitials[row.Initials]; ok && row.Initials != "" {
+			rowErrs = append(rowErrs, fmt.Sprintf("initials repeat row %d", prev))
+		}
+		seenEmails[row.Email] = line
+		seenInitials[row.Initials] = line
+
+		if len(rowErrs) > 0 {
+			errs = append(errs, fmt.Sprintf("Row %d: %s", line, strings.Join(rowErrs, "; ")))
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(errs) == 0 && len(rows) == 0 {
+		errs = append(errs, "The CSV file has no users")
+	}
+	return rows, errs
+}
+
+func redirectToOnboarding(c echo.Context, facilityID int) error {
+	c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/facilities/%d/setup", facilityID))
+	return c.String(http.StatusOK, "")
+}
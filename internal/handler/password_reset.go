@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// passwordResetTTL is how long a password reset link remains valid
+const passwordResetTTL = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Password        string `json:"password" form:"password"`
+	ConfirmPassword string `json:"confirm_password" form:"confirm_password"`
+	Token           string `json:"token" form:"token"`
+}
+
+// GetForgotPassword renders the forgot password page
+func (h *Handler) GetForgotPassword(c echo.Context) error {
+	return render(c, page.ForgotPassword())
+}
+
+// HandleForgotPassword emails a password reset link. The response is the same
+// whether or not the email belongs to an account.
+func (h *Handler) HandleForgotPassword(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleForgotPassword").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	var req ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Please provide a valid email address."})
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Limit how many emails a single address can trigger
+	if result, ok := h.allowRequest(c, ratelimit.ForgotPasswordAccount, email); !ok {
+		return response.TooManyRequests(c, result.RetryAfter)
+	}
+
+	// Send in the background so response time does not reveal whether the account exists
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		h.sendPasswordReset(ctx, email, logger)
+	}()
+
+	return alert.Success(
+		"Check Your Email",
+		"If an account exists with this email, a password reset link will be sent shortly.",
+	).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// sendPasswordReset creates a reset token for the account and emails it.
+// Unknown emails, deactivated accounts and accounts that have not completed
+// registration are skipped.
+func (h *Handler) sendPasswordReset(ctx context.Context, email string, logger zerolog.Logger) {
+	user, err := h.repos.User.GetByEmail(ctx, email)
+	if err != nil {
+		logger.Debug().Err(err).Msg("password reset requested for unknown email")
+		return
+	}
+
+	if user.IsDeactivated() {
+		logger.Debug().Int("user_id", user.ID).Msg("password reset requested for deactivated account")
+		return
+	}
+
+	if user.Password == "" {
+		logger.Debug().Int("user_id", user.ID).Msg("password reset requested before registration completed")
+		return
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate password reset token")
+		return
+	}
+
+	resetToken := &entity.PasswordResetToken{
+		UserID:    user.ID,
+		Token:     token,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := h.repos.Token.StorePasswordReset(ctx, resetToken); err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to store password reset token")
+		return
+	}
+
+	emailData := map[string]interface{}{
+		"ResetURL":  fmt.Sprintf("%s/reset-password?token=%s", h.config.BaseURL, token),
+		"ExpiresIn": "1 hour",
+		"FromName":  "MirandaShift Support",
+		"Subject":   "Reset Your MirandaShift Password",
+		"FirstName": user.FirstName,
+		"LastName":  user.LastName,
+	}
+
+	if err := h.mailer.SendTemplate(ctx, "password_reset", user.Email, emailData); err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to send password reset email")
+		return
+	}
+
+	logger.Info().Int("user_id", user.ID).Msg("password reset email sent")
+}
+
+// GetResetPassword renders the reset password page for a valid token
+func (h *Handler) GetResetPassword(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetResetPassword").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.Redirect(http.StatusSeeOther, "/forgot-password")
+	}
+
+	if _, err := h.repos.Token.GetPasswordResetToken(c.Request().Context(), token); err != nil {
+		logger.Debug().Err(err).Msg("invalid password reset token")
+		return c.Redirect(http.StatusSeeOther, "/forgot-password")
+	}
+
+	return render(c, page.ResetPassword(token))
+}
+
+// HandleResetPassword sets a new password using a reset token and signs the
+// user out of every existing session
+func (h *Handler) HandleResetPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleResetPassword").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	var req ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Please check your input and try again."})
+	}
+
+	// Validate passwords match and meet requirements
+	if err := validatePasswords(req.Password, req.ConfirmPassword); err != nil {
+		return response.Validation(c, []string{err.Error()})
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to hash password")
+		return response.System(c)
+	}
+
+	// Consume token first so it cannot be replayed
+	userID, err := h.repos.Token.ConsumePasswordReset(ctx, req.Token)
+	if err != nil {
+		logger.Debug().Err(err).Msg("password reset token rejected")
+		return response.Error(c, http.StatusBadRequest, "Invalid Link",
+			[]string{"This password reset link is invalid or has expired. Please request a new one."})
+	}
+
+	if err := h.repos.User.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to update password")
+		return response.System(c)
+	}
+
+	// Invalidate all existing sessions
+	revoked, err := h.repos.Session.DeleteByUserID(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to revoke sessions after password reset")
+	}
+
+	logger.Info().
+		Int("user_id", userID).
+		Int64("sessions_revoked", revoked).
+		Msg("password reset completed")
+
+	c.Response().Header().Set("HX-Redirect", "/login?reset=success")
+	return c.String(http.StatusOK, "")
+}
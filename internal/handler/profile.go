@@ -4,9 +4,11 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/DukeRupert/haven/internal/middleware"
 	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/types"
 	"github.com/DukeRupert/haven/web/view/page"
 	"github.com/labstack/echo/v4"
@@ -36,8 +38,9 @@ func (h *Handler) HandleGetUser(c echo.Context) error {
 		)
 	}
 
-	// Get profile from parameters, default to user initials
-	initials := c.Param("user_initials")
+	// Get profile from the route, which resolves users addressed by ID,
+	// default to user initials
+	initials := route.UserInitials
 	if initials == "" {
 		initials = auth.Initials
 	}
@@ -77,6 +80,66 @@ func (h *Handler) HandleGetUser(c echo.Context) error {
 		)
 	}
 
+	// Get two-factor status
+	twoFactorEnabled, err := h.repos.TwoFactor.IsEnabled(c.Request().Context(), details.User.ID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("user_id", details.User.ID).
+			Msg("failed to get two-factor status")
+	}
+
+	// List active sessions when viewing your own profile
+	var sessions []entity.HTTPSession
+	var currentSession string
+	if details.User.ID == auth.UserID {
+		sessions, err = h.repos.Session.ListByUserID(c.Request().Context(), auth.UserID)
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Int("user_id", auth.UserID).
+				Msg("failed to list sessions")
+		}
+		currentSession = currentSessionID(c)
+	}
+
+	// Users who manage the facility's members assign them to areas and roles
+	// and grant qualifications
+	var areas []entity.Area
+	var member *entity.FacilityMembership
+	var quals []entity.Qualification
+	var grants []entity.UserQualification
+	var roles []entity.FacilityRole
+	var today time.Time
+	if route.FacilityCode != "" && canManageMembers(auth) {
+		f, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+		if err == nil {
+			member, err = h.repos.Membership.Get(c.Request().Context(), details.User.ID, f.ID)
+		}
+		if err == nil {
+			areas, err = h.repos.Area.ListByFacility(c.Request().Context(), f.ID)
+		}
+		if err == nil {
+			quals, err = h.repos.Qualification.ListByFacility(c.Request().Context(), f.ID)
+		}
+		if err == nil {
+			grants, err = h.repos.Qualification.ListByUser(c.Request().Context(), details.User.ID, f.ID)
+		}
+		if err == nil {
+			roles, err = h.repos.Role.ListByFacility(c.Request().Context(), f.ID)
+		}
+		if err == nil {
+			today, _, err = h.facilityToday(c.Request().Context(), f.Code)
+		}
+		if err != nil {
+			logger.Debug().
+				Err(err).
+				Int("user_id", details.User.ID).
+				Msg("no membership details for profile")
+			member = nil
+		}
+	}
+
 	// Build nav items
 	navItems := BuildNav(route, auth, c.Request().URL.Path)
 
@@ -88,6 +151,16 @@ func (h *Handler) HandleGetUser(c echo.Context) error {
 		AuthCtx:     *auth,
 		RouteCtx:    *route,
 		Details:     details,
+
+		TwoFactorEnabled: twoFactorEnabled,
+		Sessions:         sessions,
+		CurrentSessionID: currentSession,
+		Areas:            areas,
+		Membership:       member,
+		Qualifications:   quals,
+		Grants:           grants,
+		Roles:            roles,
+		Today:            today,
 	}
 
 	// Handle HTMX requests if needed
@@ -114,17 +187,30 @@ func determineProfileInitials(paramInitials, authInitials string) string {
 	return paramInitials
 }
 
+// canManageMembers reports whether the user manages any part of the
+// membership of the facility they are working at
+func canManageMembers(auth *dto.AuthContext) bool {
+	return auth.Can(types.PermUsersManage) ||
+		auth.Can(types.PermQualificationsManage) ||
+		auth.Can(types.PermRolesManage)
+}
+
 func canViewOtherProfiles(auth *dto.AuthContext, route *dto.RouteContext) bool {
 	// Super users can view all profiles
 	if auth.Role == types.UserRoleSuper {
 		return true
 	}
 
-	// Admins can only view profiles within their facility
-	if auth.Role == types.UserRoleAdmin {
+	// Users who manage users or schedules can view profiles within their facility
+	if auth.Can(types.PermUsersManage) || auth.Can(types.PermScheduleEdit) {
 		return auth.FacilityCode == route.FacilityCode
 	}
 
+	// Area supervisors can view the members of their area
+	if auth.SupervisedUserID != 0 {
+		return true
+	}
+
 	// Regular users cannot view other profiles
 	return false
 }
@@ -0,0 +1,436 @@
+// internal/handler/qualification.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/repository/membership"
+	"github.com/DukeRupert/haven/internal/repository/qualification"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+)
+
+// Longest qualification name accepted
+const maxQualificationNameLength = 100
+
+// GET /app/:facility_code/qualifications
+func (h *Handler) HandleGetQualifications(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleGetQualifications").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	quals, err := h.repos.Qualification.ListByFacility(c.Request().Context(), facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list qualifications")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load qualifications. Please try again later.",
+		)
+	}
+
+	props := dto.QualificationsPageProps{
+		Title:          "Qualifications",
+		Description:    "Positions and certifications controllers at the facility can hold.",
+		NavItems:       BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:        *auth,
+		RouteCtx:       *route,
+		Qualifications: quals,
+	}
+
+	return render(c, page.Qualifications(props))
+}
+
+// POST /app/:facility_code/qualifications
+func (h *Handler) HandleCreateQualification(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleCreateQualification").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	var errs []string
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" || len(name) > maxQualificationNameLength {
+		errs = append(errs, fmt.Sprintf("Qualification name is required and must be at most %d characters", maxQualificationNameLength))
+	}
+	kind := entity.QualificationKind(c.FormValue("kind"))
+	if !kind.Valid() {
+		errs = append(errs, "Please choose a kind of position, CPC, OJT instructor or CIC")
+	}
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	q, err := h.repos.Qualification.Create(c.Request().Context(), facility.ID, name, kind)
+	if err != nil {
+		if errors.Is(err, qualification.ErrDuplicate) {
+			return response.Validation(c, []string{fmt.Sprintf("%s already has a qualification named %s", facility.Code, name)})
+		}
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to create qualification")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("qualification_id", q.ID).
+		Str("name", q.Name).
+		Str("kind", string(q.Kind)).
+		Msg("qualification created")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditQualification,
+		EntityID:   strconv.Itoa(q.ID),
+		Action:     entity.AuditCreate,
+	}, nil, q)
+
+	return render(c, ComponentGroup(
+		alert.Success("Qualification Created", fmt.Sprintf("%s has been added to %s.", q.Name, facility.Code)),
+		page.QualificationListItem(facility.Code, *q),
+	))
+}
+
+// DELETE /app/:facility_code/qualifications/:qualification_id
+func (h *Handler) HandleDeleteQualification(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleDeleteQualification").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	qualificationID, err := strconv.Atoi(c.Param("qualification_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid qualification"})
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	before, err := h.repos.Qualification.Get(c.Request().Context(), facility.ID, qualificationID)
+	if err != nil {
+		if errors.Is(err, qualification.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found", []string{"Qualification not found"})
+		}
+		logger.Error().Err(err).Int("qualification_id", qualificationID).Msg("failed to get qualification")
+		return response.System(c)
+	}
+
+	if err := h.repos.Qualification.Delete(c.Request().Context(), facility.ID, qualificationID); err != nil {
+		if errors.Is(err, qualification.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found", []string{"Qualification not found"})
+		}
+		logger.Error().Err(err).Int("qualification_id", qualificationID).Msg("failed to delete qualification")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("qualification_id", qualificationID).
+		Msg("qualification deleted")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditQualification,
+		EntityID:   strconv.Itoa(qualificationID),
+		Action:     entity.AuditDelete,
+	}, before, nil)
+
+	return response.Success(c, "Qualification Deleted", "It has been removed from everyone who held it.")
+}
+
+// POST /app/:facility_code/:user_initials/qualifications
+// Grants a qualification, or renews one the member already holds
+func (h *Handler) HandleGrantQualification(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleGrantQualification").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	if err := ensureRouteParams(route); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	member, err := h.repos.Membership.GetByInitials(ctx, facility.ID, route.UserInitials)
+	if err != nil {
+		if errors.Is(err, membership.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found",
+				[]string{"Only members of the facility can hold its qualifications"})
+		}
+		logger.Error().Err(err).Str("initials", route.UserInitials).Msg("failed to get membership")
+		return response.System(c)
+	}
+
+	var errs []string
+	qualificationID, err := strconv.Atoi(c.FormValue("qualification_id"))
+	if err != nil {
+		errs = append(errs, "Please choose a qualification")
+	}
+	grantedOn, err := time.Parse("2006-01-02", c.FormValue("granted_on"))
+	if err != nil {
+		errs = append(errs, "Please provide a valid granted date (YYYY-MM-DD)")
+	}
+	var expiresOn *time.Time
+	if v := c.FormValue("expires_on"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			errs = append(errs, "Please provide a valid expiry date (YYYY-MM-DD)")
+		} else if !grantedOn.IsZero() && d.Before(grantedOn) {
+			errs = append(errs, "The expiry date cannot be before the granted date")
+		} else {
+			expiresOn = &d
+		}
+	}
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// A grant renews any the member already holds
+	before, err := h.heldQualification(ctx, member.UserID, facility.ID, qualificationID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", member.UserID).Msg("failed to load qualifications")
+		return response.System(c)
+	}
+
+	grant, err := h.repos.Qualification.Grant(ctx, params.GrantQualificationParams{
+		UserID:          member.UserID,
+		FacilityID:      facility.ID,
+		QualificationID: qualificationID,
+		GrantedOn:       grantedOn,
+		ExpiresOn:       expiresOn,
+		GrantedBy:       auth.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, qualification.ErrNotFound) {
+			return response.Validation(c, []string{"Please choose a qualification"})
+		}
+		logger.Error().Err(err).Int("user_id", member.UserID).Msg("failed to grant qualification")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", grant.UserID).
+		Int("qualification_id", grant.QualificationID).
+		Int("granted_by", auth.UserID).
+		Msg("qualification granted")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditGrant,
+		EntityID:   grantAuditID(grant.UserID, grant.QualificationID),
+		Action:     entity.AuditGrantAdd,
+	}, before, grant)
+
+	card, err := h.qualificationsCard(c, facility, member.UserID, route.UserInitials)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", member.UserID).Msg("failed to load qualifications")
+		return response.System(c)
+	}
+
+	return render(c, ComponentGroup(
+		alert.Success("Qualification Granted", fmt.Sprintf("%s now holds %s.", route.UserInitials, grant.Name)),
+		card,
+	))
+}
+
+// DELETE /app/:facility_code/:user_initials/qualifications/:qualification_id
+func (h *Handler) HandleRevokeQualification(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleRevokeQualification").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	if err := ensureRouteParams(route); err != nil {
+		return err
+	}
+
+	qualificationID, err := strconv.Atoi(c.Param("qualification_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid qualification"})
+	}
+
+	ctx := c.Request().Context()
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	user, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	}
+
+	before, err := h.heldQualification(ctx, user.ID, facility.ID, qualificationID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to load qualifications")
+		return response.System(c)
+	}
+
+	if err := h.repos.Qualification.Revoke(ctx, user.ID, facility.ID, qualificationID); err != nil {
+		if errors.Is(err, qualification.ErrGrantNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found",
+				[]string{fmt.Sprintf("%s does not hold this qualification", route.UserInitials)})
+		}
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to revoke qualification")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Int("qualification_id", qualificationID).
+		Msg("qualification revoked")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditGrant,
+		EntityID:   grantAuditID(user.ID, qualificationID),
+		Action:     entity.AuditRevoke,
+	}, before, nil)
+
+	card, err := h.qualificationsCard(c, facility, user.ID, route.UserInitials)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to load qualifications")
+		return response.System(c)
+	}
+
+	return render(c, ComponentGroup(
+		alert.Success("Qualification Revoked", fmt.Sprintf("The qualification has been removed from %s.", route.UserInitials)),
+		card,
+	))
+}
+
+// heldQualification returns the member's grant of a qualification, or nil
+// if they do not hold it
+func (h *Handler) heldQualification(ctx context.Context, userID, facilityID, qualificationID int) (*entity.UserQualification, error) {
+	held, err := h.repos.Qualification.ListByUser(ctx, userID, facilityID)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range held {
+		if q.QualificationID == qualificationID {
+			return &q, nil
+		}
+	}
+	return nil, nil
+}
+
+// grantAuditID identifies a member's grant of a qualification in the audit
+// trail
+func grantAuditID(userID, qualificationID int) string {
+	return fmt.Sprintf("%d/%d", userID, qualificationID)
+}
+
+// qualificationsCard renders a member's qualifications after a change
+func (h *Handler) qualificationsCard(c echo.Context, facility *entity.Facility, userID int, initials string) (templ.Component, error) {
+	ctx := c.Request().Context()
+	catalog, err := h.repos.Qualification.ListByFacility(ctx, facility.ID)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := h.repos.Qualification.ListByUser(ctx, userID, facility.ID)
+	if err != nil {
+		return nil, err
+	}
+	today, _, err := h.facilityToday(ctx, facility.Code)
+	if err != nil {
+		return nil, err
+	}
+	return page.QualificationsCard(facility.Code, initials, catalog, grants, today), nil
+}
+
+// qualificationFilter reads the qualification chosen on a facility page.
+// Only members holding it on the day shown are listed.
+func (h *Handler) qualificationFilter(c echo.Context, facilityID int) ([]entity.Qualification, *int, error) {
+	quals, err := h.repos.Qualification.ListByFacility(c.Request().Context(), facilityID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := strconv.Atoi(c.QueryParam("qualification"))
+	if err != nil {
+		return quals, nil, nil
+	}
+	for _, q := range quals {
+		if q.ID == id {
+			return quals, &id, nil
+		}
+	}
+	return quals, nil, nil
+}
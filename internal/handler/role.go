@@ -0,0 +1,450 @@
+// internal/handler/role.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/repository/membership"
+	"github.com/DukeRupert/haven/internal/repository/role"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Longest role name accepted
+const maxRoleNameLength = 50
+
+// GET /app/:facility_code/roles
+func (h *Handler) HandleGetRoles(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleGetRoles").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	roles, err := h.repos.Role.ListByFacility(c.Request().Context(), facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list roles")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load roles. Please try again later.",
+		)
+	}
+
+	props := dto.RolesPageProps{
+		Title:       "Roles",
+		Description: "What each role allows its members to do at the facility.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Roles:       roles,
+	}
+
+	return render(c, page.Roles(props))
+}
+
+// POST /app/:facility_code/roles
+func (h *Handler) HandleCreateRole(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleCreateRole").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	name, perms, errs := roleForm(c)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+	if !auth.CanGrantAt(facility.Code, perms) {
+		return response.Error(c, http.StatusForbidden, "Access Denied", []string{errRoleNotGrantable})
+	}
+
+	r, err := h.repos.Role.Create(c.Request().Context(), facility.ID, name, perms)
+	if err != nil {
+		if errors.Is(err, role.ErrDuplicate) {
+			return response.Validation(c, []string{fmt.Sprintf("%s already has a role named %s", facility.Code, name)})
+		}
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to create role")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("role_id", r.ID).
+		Str("name", r.Name).
+		Msg("role created")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditRole,
+		EntityID:   strconv.Itoa(r.ID),
+		Action:     entity.AuditCreate,
+	}, nil, r)
+
+	return render(c, ComponentGroup(
+		alert.Success("Role Created", fmt.Sprintf("%s has been added to %s.", r.Name, facility.Code)),
+		page.RoleListItem(facility.Code, *r),
+	))
+}
+
+// PUT /app/:facility_code/roles/:role_id
+func (h *Handler) HandleUpdateRole(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleUpdateRole").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	roleID, err := strconv.Atoi(c.Param("role_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid role"})
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	name, perms, errs := roleForm(c)
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// Kept for the audit trail
+	before, err := h.facilityRole(c.Request().Context(), facility.ID, roleID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list roles")
+		return response.System(c)
+	}
+	if before == nil {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Role not found"})
+	}
+	if msg := roleEditError(auth, facility.Code, *before, perms); msg != "" {
+		return response.Error(c, http.StatusForbidden, "Access Denied", []string{msg})
+	}
+
+	r, err := h.repos.Role.Update(c.Request().Context(), facility.ID, roleID, name, perms)
+	switch {
+	case errors.Is(err, role.ErrDuplicate):
+		return response.Validation(c, []string{fmt.Sprintf("%s already has a role named %s", facility.Code, name)})
+	case errors.Is(err, role.ErrNotFound):
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Role not found"})
+	case err != nil:
+		logger.Error().Err(err).Int("role_id", roleID).Msg("failed to update role")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("role_id", r.ID).
+		Strs("permissions", permissionNames(r.Permissions)).
+		Msg("role updated")
+
+	// The list shows how many members hold the role
+	r.MemberCount = before.MemberCount
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditRole,
+		EntityID:   strconv.Itoa(r.ID),
+		Action:     entity.AuditUpdate,
+	}, before, r)
+
+	return render(c, ComponentGroup(
+		alert.Success("Role Updated", fmt.Sprintf("%s has been updated.", r.Name)),
+		page.RoleListItem(facility.Code, *r),
+	))
+}
+
+// DELETE /app/:facility_code/roles/:role_id
+func (h *Handler) HandleDeleteRole(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleDeleteRole").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	roleID, err := strconv.Atoi(c.Param("role_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid role"})
+	}
+
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	before, err := h.facilityRole(c.Request().Context(), facility.ID, roleID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list roles")
+		return response.System(c)
+	}
+	if before == nil {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Role not found"})
+	}
+	if msg := roleEditError(auth, facility.Code, *before, nil); msg != "" {
+		return response.Error(c, http.StatusForbidden, "Access Denied", []string{msg})
+	}
+
+	if err := h.repos.Role.Delete(c.Request().Context(), facility.ID, roleID); err != nil {
+		if errors.Is(err, role.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found", []string{"Role not found"})
+		}
+		logger.Error().Err(err).Int("role_id", roleID).Msg("failed to delete role")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Int("role_id", roleID).
+		Msg("role deleted")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditRole,
+		EntityID:   strconv.Itoa(roleID),
+		Action:     entity.AuditDelete,
+	}, before, nil)
+
+	return response.Success(c, "Role Deleted", "Its members now have only their built-in role.")
+}
+
+// PUT /app/:facility_code/:user_initials/role
+func (h *Handler) HandleUpdateMemberRole(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleUpdateMemberRole").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	if err := ensureRouteParams(route); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	var roleID *int
+	if v := c.FormValue("facility_role_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return response.Validation(c, []string{"Please choose a valid role"})
+		}
+		roleID = &id
+	}
+
+	member, err := h.repos.Membership.GetByInitials(ctx, facility.ID, route.UserInitials)
+	if err != nil {
+		if errors.Is(err, membership.ErrNotFound) {
+			return response.Error(c, http.StatusNotFound, "Not Found",
+				[]string{"Only members of the facility can be given a role"})
+		}
+		logger.Error().Err(err).Str("initials", route.UserInitials).Msg("failed to get membership")
+		return response.System(c)
+	}
+
+	if auth.Role != types.UserRoleSuper && member.UserID == auth.UserID {
+		return response.Error(c, http.StatusForbidden, "Access Denied", []string{"You can't change your own role"})
+	}
+
+	// Users can neither give others permissions they lack nor take a role
+	// away from someone who holds more
+	var perms []types.Permission
+	if roleID != nil {
+		r, err := h.facilityRole(ctx, facility.ID, *roleID)
+		if err != nil {
+			logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list roles")
+			return response.System(c)
+		}
+		if r == nil {
+			return response.Validation(c, []string{"Please choose a valid role"})
+		}
+		perms = r.Permissions
+	}
+	if !auth.CanGrantAt(facility.Code, perms) || !auth.CanGrantAt(facility.Code, member.FacilityRolePerms) {
+		return response.Error(c, http.StatusForbidden, "Access Denied", []string{errRoleNotGrantable})
+	}
+
+	updated, err := h.repos.Membership.SetFacilityRole(ctx, member.UserID, facility.ID, roleID)
+	if err != nil {
+		if errors.Is(err, membership.ErrRoleNotFound) {
+			return response.Validation(c, []string{"Please choose a valid role"})
+		}
+		logger.Error().Err(err).Int("user_id", member.UserID).Msg("failed to set facility role")
+		return response.System(c)
+	}
+
+	roles, err := h.repos.Role.ListByFacility(ctx, facility.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to list roles")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", updated.UserID).
+		Int("facility_id", facility.ID).
+		Str("facility_role", updated.FacilityRoleName).
+		Msg("member role updated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditMembership,
+		EntityID:   strconv.Itoa(member.UserID),
+		Action:     entity.AuditUpdate,
+	}, member, updated)
+
+	return render(c, ComponentGroup(
+		alert.Success("Role Updated", fmt.Sprintf("%s's role has been updated.", route.UserInitials)),
+		page.MemberRoleForm(facility.Code, route.UserInitials, roles, *updated),
+	))
+}
+
+// facilityRole returns one of the facility's roles with its member count,
+// or nil if there is no such role
+func (h *Handler) facilityRole(ctx context.Context, facilityID, roleID int) (*entity.FacilityRole, error) {
+	roles, err := h.repos.Role.ListByFacility(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		if r.ID == roleID {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// roleEditError explains why the user cannot change a facility role to
+// grant perms, or returns "" if they can. Users may not edit the role they
+// hold themselves, nor a role granting permissions they lack.
+func roleEditError(auth *dto.AuthContext, facilityCode string, r entity.FacilityRole, perms []types.Permission) string {
+	if auth.Role == types.UserRoleSuper {
+		return ""
+	}
+	if m, ok := auth.Membership(facilityCode); ok && m.FacilityRoleID != nil && *m.FacilityRoleID == r.ID {
+		return "You can't change the role you hold yourself"
+	}
+	if !auth.CanGrantAt(facilityCode, r.Permissions) || !auth.CanGrantAt(facilityCode, perms) {
+		return errRoleNotGrantable
+	}
+	return ""
+}
+
+// roleForm reads a role's name and permissions from the submitted form
+func roleForm(c echo.Context) (string, []types.Permission, []string) {
+	var errs []string
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" || len(name) > maxRoleNameLength {
+		errs = append(errs, fmt.Sprintf("Role name is required and must be at most %d characters", maxRoleNameLength))
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return name, nil, append(errs, "Unable to read the submitted permissions")
+	}
+
+	var perms []types.Permission
+	for _, v := range form["permissions"] {
+		p := types.Permission(v)
+		if !p.Assignable() {
+			errs = append(errs, fmt.Sprintf("%s is not a permission a facility role can grant", v))
+			continue
+		}
+		perms = append(perms, p)
+	}
+
+	return name, perms, errs
+}
+
+func permissionNames(perms []types.Permission) []string {
+	names := make([]string, len(perms))
+	for i, p := range perms {
+		names[i] = string(p)
+	}
+	return names
+}
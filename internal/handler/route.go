@@ -4,6 +4,7 @@ package handler
 import (
 	"github.com/DukeRupert/haven/internal/middleware"
 	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/ratelimit"
 
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
@@ -26,7 +27,7 @@ const (
 
 func SetupRoutes(e *echo.Echo, h *Handler, m *middleware.Middleware) {
 	setupGlobalMiddleware(e, h)
-	setupPublicRoutes(e, h)
+	setupPublicRoutes(e, h, m)
 	setupAppRoutes(e, h, m)
 }
 
@@ -58,41 +59,93 @@ func setupGlobalMiddleware(e *echo.Echo, h *Handler) {
 	})
 }
 
-func setupPublicRoutes(e *echo.Echo, h *Handler) {
+func setupPublicRoutes(e *echo.Echo, h *Handler, m *middleware.Middleware) {
 	e.GET("/", h.GetHome)
 	e.GET("/login", h.GetLogin)
-	e.POST("/login", h.LoginHandler)
-	e.POST("/logout", h.LogoutHandler)
+	e.POST("/login", h.LoginHandler, m.RateLimit(ratelimit.LoginIP))
+	e.GET("/login/2fa", h.GetTwoFactorLogin)
+	e.POST("/login/2fa", h.HandleTwoFactorLogin, m.RateLimit(ratelimit.TwoFactorIP))
+	e.GET("/login/oidc/callback", h.HandleSSOCallback, m.RateLimit(ratelimit.LoginIP))
+	e.POST("/logout", h.LogoutHandler, m.CSRF())
 	e.GET("/register", h.GetRegistration)
 	e.POST("/register", h.HandleRegistration)
-	e.POST("/verify", h.InitiateEmailVerification)
+	e.POST("/verify", h.InitiateEmailVerification, m.RateLimit(ratelimit.VerifyIP))
 	e.GET("/verify", h.GetVerificationPage)
 	e.GET("/set-password", h.GetSetPassword)
-	e.POST("/set-password", h.HandleSetPassword)
-	e.GET("/resend-verification", h.HandleResendVerification)
+	e.POST("/set-password", h.HandleSetPassword, m.RateLimit(ratelimit.SetPasswordIP))
+	e.GET("/resend-verification", h.HandleResendVerification, m.RateLimit(ratelimit.ResendIP))
+	e.GET("/forgot-password", h.GetForgotPassword)
+	e.POST("/forgot-password", h.HandleForgotPassword, m.RateLimit(ratelimit.ForgotPasswordIP))
+	e.GET("/reset-password", h.GetResetPassword)
+	e.POST("/reset-password", h.HandleResetPassword, m.RateLimit(ratelimit.SetPasswordIP))
+	e.GET("/confirm-email", h.HandleConfirmEmailChange, m.RateLimit(ratelimit.EmailChangeIP))
+	e.GET("/cancel-email-change", h.HandleCancelEmailChange, m.RateLimit(ratelimit.EmailChangeIP))
 }
 
 func setupAppRoutes(e *echo.Echo, h *Handler, m *middleware.Middleware) {
 	// Base app group with auth
-	app := e.Group("/app", m.Auth(), m.RouteContext())
+	app := e.Group("/app", m.Auth(), m.CSRF(), m.RouteContext(), m.RequireTwoFactor(), m.AuditImpersonation(), m.Audit())
 	// Complete path: /app/calendar
 	app.GET("/calendar", h.HandleCalendar)
 	// Complete path: /app/profile
 	app.GET("/profile", h.HandleGetUser)
+	// Complete path: /app/profile/2fa
+	app.GET("/profile/2fa", h.HandleTwoFactorSetup, m.BlockImpersonation())
+	app.POST("/profile/2fa", h.HandleTwoFactorEnable, m.BlockImpersonation())
+	app.DELETE("/profile/2fa", h.HandleTwoFactorDisable, m.BlockImpersonation())
+	// Complete path: /app/profile/2fa/recovery-codes
+	app.POST("/profile/2fa/recovery-codes", h.HandleRegenerateRecoveryCodes, m.BlockImpersonation())
+	// Complete path: /app/profile/sessions
+	app.DELETE("/profile/sessions", h.HandleRevokeAllSessions, m.BlockImpersonation())
+	// Complete path: /app/profile/sessions/:session_id
+	app.DELETE("/profile/sessions/:session_id", h.HandleRevokeSession, m.BlockImpersonation())
+	// Complete path: /app/impersonate/stop
+	app.POST("/impersonate/stop", h.HandleStopImpersonation)
+	// Complete path: /app/facility
+	app.POST("/facility", h.HandleSwitchFacility)
+	// Complete path: /app/users/search
+	app.GET("/users/search", h.HandleSearchUsers, m.RequirePermission(types.PermUsersSearch))
+	// Complete path: /app/audit
+	app.GET("/audit", h.HandleAudit, m.RequirePermission(types.PermAuditViewAll))
+	// Complete path: /app/audit/export
+	app.GET("/audit/export", h.HandleExportAudit, m.RequirePermission(types.PermAuditViewAll))
 
-	// Facility management (require super role)
-	facilities := app.Group("/facilities", m.RequireRole(types.UserRoleSuper))
+	// Facility management (requires facilities.manage, held by super users)
+	facilities := app.Group("/facilities", m.RequirePermission(types.PermFacilitiesManage))
 	{
 		// Complete path: /app/facilities
 		facilities.GET("", h.HandleGetFacilities)
 		facilities.POST("", h.HandleCreateFacility)
 		// Complete path: /app/facilities/create
 		facilities.GET("/create", h.GetCreateFacilityForm)
-		// Complete path: /app/facilities/edit
-		facilities.GET("/edit", h.GetUpdateFacilityForm)
+		// Complete path: /app/facilities/setup
+		facilities.GET("/setup", h.GetOnboardingStart)
+		facilities.POST("/setup", h.HandleOnboardingFacility)
 		// Complete path: /app/facilities/:facility_id
 		facilities.PUT(PathFacilityID, h.HandleUpdateFacility)
 		facilities.DELETE(PathFacilityID, h.HandleDeleteFacility)
+		// Complete path: /app/facilities/:facility_id/update
+		facilities.GET(PathFacilityID+"/update", h.GetUpdateFacilityForm)
+		// Complete path: /app/facilities/:facility_id/delete
+		facilities.GET(PathFacilityID+"/delete", h.GetDeleteFacilityForm)
+		// Complete path: /app/facilities/:facility_id/archive
+		facilities.POST(PathFacilityID+"/archive", h.HandleArchiveFacility)
+		// Complete path: /app/facilities/:facility_id/restore
+		facilities.POST(PathFacilityID+"/restore", h.HandleRestoreFacility)
+		// Complete path: /app/facilities/:facility_id/sso
+		facilities.GET(PathFacilityID+"/sso", h.GetFacilitySSOForm)
+		facilities.PUT(PathFacilityID+"/sso", h.HandleSaveFacilitySSO)
+		facilities.DELETE(PathFacilityID+"/sso", h.HandleDeleteFacilitySSO)
+		// Complete path: /app/facilities/:facility_id/setup
+		facilities.GET(PathFacilityID+"/setup", h.GetOnboarding)
+		// Complete path: /app/facilities/:facility_id/setup/admin
+		facilities.POST(PathFacilityID+"/setup/admin", h.HandleOnboardingAdmin)
+		// Complete path: /app/facilities/:facility_id/setup/settings
+		facilities.POST(PathFacilityID+"/setup/settings", h.HandleOnboardingSettings)
+		// Complete path: /app/facilities/:facility_id/setup/import
+		facilities.POST(PathFacilityID+"/setup/import", h.HandleOnboardingImport)
+		// Complete path: /app/facilities/:facility_id/setup/finish
+		facilities.POST(PathFacilityID+"/setup/finish", h.HandleOnboardingFinish)
 	}
 
 	// Facility routes (require facility access)
@@ -101,17 +154,60 @@ func setupAppRoutes(e *echo.Echo, h *Handler, m *middleware.Middleware) {
 		// Complete path: /app/:facility_code/calendar
 		facility.GET("/calendar", h.HandleCalendar)
 		// Complete path: /app/:facility_code/publish
-		facility.PUT("/publish", h.HandleUpdatePublishedThrough)
+		facility.PUT("/publish", h.HandleUpdatePublishedThrough, m.RequirePermission(types.PermPublicationUpdate))
+		// Complete path: /app/:facility_code/two-factor
+		facility.PUT("/two-factor", h.HandleUpdateTwoFactorRequirement, m.RequirePermission(types.PermSettingsManage))
+		// Complete path: /app/:facility_code/settings
+		facility.GET("/settings", h.HandleGetFacilitySettings, m.RequirePermission(types.PermSettingsManage))
+		facility.PUT("/settings", h.HandleUpdateFacilitySettings, m.RequirePermission(types.PermSettingsManage))
+		// Complete path: /app/:facility_code/areas
+		facility.GET("/areas", h.HandleGetAreas, m.RequirePermission(types.PermSettingsManage))
+		facility.POST("/areas", h.HandleCreateArea, m.RequirePermission(types.PermSettingsManage))
+		// Complete path: /app/:facility_code/areas/:area_id
+		facility.DELETE("/areas/:area_id", h.HandleDeleteArea, m.RequirePermission(types.PermSettingsManage))
+		// Complete path: /app/:facility_code/qualifications
+		facility.GET("/qualifications", h.HandleGetQualifications, m.RequirePermission(types.PermQualificationsManage))
+		facility.POST("/qualifications", h.HandleCreateQualification, m.RequirePermission(types.PermQualificationsManage))
+		// Complete path: /app/:facility_code/qualifications/:qualification_id
+		facility.DELETE("/qualifications/:qualification_id", h.HandleDeleteQualification, m.RequirePermission(types.PermQualificationsManage))
+		// Complete path: /app/:facility_code/roles
+		facility.GET("/roles", h.HandleGetRoles, m.RequirePermission(types.PermRolesManage))
+		facility.POST("/roles", h.HandleCreateRole, m.RequirePermission(types.PermRolesManage))
+		// Complete path: /app/:facility_code/roles/:role_id
+		facility.PUT("/roles/:role_id", h.HandleUpdateRole, m.RequirePermission(types.PermRolesManage))
+		facility.DELETE("/roles/:role_id", h.HandleDeleteRole, m.RequirePermission(types.PermRolesManage))
+		// Complete path: /app/:facility_code/audit
+		facility.GET("/audit", h.HandleFacilityAudit, m.RequirePermission(types.PermAuditView))
+		// Complete path: /app/:facility_code/audit/export
+		facility.GET("/audit/export", h.HandleExportFacilityAudit, m.RequirePermission(types.PermAuditView))
 	}
 
-	// User management routes (requires admin role)
-	users := facility.Group("/users", m.RequireRole(types.UserRoleAdmin))
+	// User management routes (requires users.manage)
+	users := facility.Group("/users", m.RequirePermission(types.PermUsersManage))
 	{
 		// Complete path: /app/:facility_code/users
 		users.GET("", h.HandleUsers)
 		users.POST("", h.HandleCreateUser)
 		// Complete path: /app/:facility_code/users/create
 		users.GET("/create", h.GetCreateUserForm)
+		// Complete path: /app/:facility_code/users/members
+		users.POST("/members", h.HandleAddMember)
+		// Complete path: /app/:facility_code/users/lockouts
+		users.GET("/lockouts", h.HandleLockouts)
+		// Complete path: /app/:facility_code/users/lockouts/:lockout_id/unlock
+		users.POST("/lockouts/:lockout_id/unlock", h.HandleUnlockAccount)
+		// Complete path: /app/:facility_code/users/deactivated
+		users.GET("/deactivated", h.HandleDeactivatedUsers)
+		// Complete path: /app/:facility_code/users/invitations
+		users.GET("/invitations", h.HandleInvitations)
+		// Complete path: /app/:facility_code/users/invitations/resend
+		users.POST("/invitations/resend", h.HandleBulkResendInvitations)
+		// Complete path: /app/:facility_code/users/invitations/revoke
+		users.POST("/invitations/revoke", h.HandleBulkRevokeInvitations)
+		// Complete path: /app/:facility_code/users/invitations/:user_id
+		users.DELETE("/invitations/:user_id", h.HandleRevokeInvitation)
+		// Complete path: /app/:facility_code/users/invitations/:user_id/resend
+		users.POST("/invitations/:user_id/resend", h.HandleResendInvitation)
 	}
 
 	// User routes (requires profile access)
@@ -120,18 +216,39 @@ func setupAppRoutes(e *echo.Echo, h *Handler, m *middleware.Middleware) {
 		// Complete path: /app/:facility_code/:user_initials
 		user.GET("", h.HandleGetUser)
 		user.PUT("", h.HandleUpdateUser)
-		user.DELETE("", h.HandleDeleteUser, m.RequireRole(types.UserRoleAdmin))
+		user.DELETE("", h.HandleDeleteUser, m.RequirePermission(types.PermUsersManage))
+		// Complete path: /app/:facility_code/:user_initials/reactivate
+		user.POST("/reactivate", h.HandleReactivateUser, m.RequirePermission(types.PermUsersManage))
+		// Complete path: /app/:facility_code/:user_initials/purge
+		user.DELETE("/purge", h.HandlePurgeUser, m.RequirePermission(types.PermUsersPurge))
 		// Complete path: /app/:facility_code/:user_initials/edit
 		user.GET("/edit", h.GetUpdateUserForm)
 		// Complete path: /app/:facility_code/:user_initials/password
-		user.GET("/password", h.GetUpdatePasswordForm)
-		user.PUT("/password", h.HandleUpdatePassword)
+		user.GET("/password", h.GetUpdatePasswordForm, m.BlockImpersonation())
+		user.PUT("/password", h.HandleUpdatePassword, m.BlockImpersonation())
 		// Complete path: /app/:facility_code/:user_initials/availability/:id
 		user.POST("/availability/:id", h.HandleAvailabilityToggle)
+		// Complete path: /app/:facility_code/:user_initials/area
+		user.PUT("/area", h.HandleUpdateMemberArea, m.RequirePermission(types.PermUsersManage))
+		// Complete path: /app/:facility_code/:user_initials/role
+		user.PUT("/role", h.HandleUpdateMemberRole, m.RequirePermission(types.PermRolesManage))
+		// Complete path: /app/:facility_code/:user_initials/qualifications
+		user.POST("/qualifications", h.HandleGrantQualification, m.RequirePermission(types.PermQualificationsManage))
+		// Complete path: /app/:facility_code/:user_initials/qualifications/:qualification_id
+		user.DELETE("/qualifications/:qualification_id", h.HandleRevokeQualification, m.RequirePermission(types.PermQualificationsManage))
+		// Complete path: /app/:facility_code/:user_initials/2fa
+		user.DELETE("/2fa", h.HandleResetTwoFactor, m.RequirePermission(types.PermUsersManage), m.BlockImpersonation())
+		// Complete path: /app/:facility_code/:user_initials/sessions
+		user.DELETE("/sessions", h.HandleRevokeUserSessions, m.RequirePermission(types.PermUsersManage), m.BlockImpersonation())
+		// Complete path: /app/:facility_code/:user_initials/transfer
+		user.GET("/transfer", h.GetTransferForm, m.RequirePermission(types.PermUsersManage))
+		user.POST("/transfer", h.HandleTransferUser, m.RequirePermission(types.PermUsersManage))
+		// Complete path: /app/:facility_code/:user_initials/impersonate
+		user.POST("/impersonate", h.HandleStartImpersonation, m.RequirePermission(types.PermUsersImpersonate))
 	}
 
-	// Schedule routes (require admin role)
-	schedule := user.Group("/schedule", m.RequireRole(types.UserRoleAdmin))
+	// Schedule routes (require schedule.edit or area supervisor)
+	schedule := user.Group("/schedule", m.RequireAreaSupervisor())
 	{
 		// Complete path: /app/:facility_code/:user_initials/schedule
 		schedule.POST("", h.HandleCreateSchedule)
@@ -34,12 +34,11 @@ func (h *Handler) HandleUpdatePublishedThrough(c echo.Context) error {
 		return response.System(c)
 	}
 
-	// Verify admin role
-	if auth.Role != types.UserRoleAdmin {
+	if !auth.Can(types.PermPublicationUpdate) {
 		return response.Error(c,
 			http.StatusForbidden,
 			"Access Denied",
-			[]string{"Only administrators can update schedule publication dates"},
+			[]string{"You don't have permission to update schedule publication dates"},
 		)
 	}
 
@@ -55,6 +54,26 @@ func (h *Handler) HandleUpdatePublishedThrough(c echo.Context) error {
 		)
 	}
 
+	// Rolling publication is kept up to date automatically
+	settings, err := h.repos.Facility.GetSettings(c.Request().Context(), auth.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", auth.FacilityID).Msg("failed to fetch facility settings")
+		return response.System(c)
+	}
+	if settings.PublicationPolicy == entity.PublicationRolling {
+		return response.Error(c,
+			http.StatusConflict,
+			"Publication Is Automatic",
+			[]string{fmt.Sprintf("This facility publishes %d weeks ahead automatically", settings.PublishWeeksAhead)},
+		)
+	}
+
+	// Kept for the audit trail; the facility may not have published before
+	var before any
+	if prev, err := h.repos.Publication.GetByFacilityID(c.Request().Context(), auth.FacilityID); err == nil {
+		before = prev
+	}
+
 	// Update publication date
 	pub, err := h.repos.Publication.Update(
 		c.Request().Context(),
@@ -75,6 +94,13 @@ func (h *Handler) HandleUpdatePublishedThrough(c echo.Context) error {
 		Time("published_through", pub.PublishedThrough).
 		Msg("publication date updated successfully")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &auth.FacilityID,
+		EntityType: entity.AuditPublication,
+		EntityID:   strconv.Itoa(auth.FacilityID),
+		Action:     entity.AuditPublish,
+	}, before, pub)
+
 	return response.Success(c, "Success", "Schedule publication date has been updated")
 }
 
@@ -102,6 +128,11 @@ func (h *Handler) HandleCreateSchedule(c echo.Context) error {
         return err
     }
 
+	// Deactivated users keep their schedule but no longer generate protected days
+	if user, err := h.repos.User.GetByInitialsAndFacility(c.Request().Context(), route.UserInitials, route.FacilityCode); err == nil && user.IsDeactivated() {
+		return response.Validation(c, []string{"Reactivate this user before changing their schedule"})
+	}
+
 	// Parse form data
 	var formData params.CreateScheduleRequest
 	if err := c.Bind(&formData); err != nil {
@@ -150,6 +181,13 @@ func (h *Handler) HandleCreateSchedule(c echo.Context) error {
 		Str("second_weekday", schedule.SecondWeekday.String()).
 		Msg("schedule created successfully")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &schedule.FacilityID,
+		EntityType: entity.AuditSchedule,
+		EntityID:   strconv.Itoa(schedule.ID),
+		Action:     entity.AuditCreate,
+	}, nil, schedule)
+
 	return render(c, page.ScheduleCard(*auth, *route, *schedule))
 }
 
@@ -313,6 +351,23 @@ func (h *Handler) HandleAvailabilityToggle(c echo.Context) error {
 		)
 	}
 
+	// Enforce the facility's deadline and rolling publication window
+	settings, err := h.repos.Facility.GetSettings(c.Request().Context(), protectedDate.FacilityID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("facility_id", protectedDate.FacilityID).
+			Msg("failed to fetch facility settings")
+		return response.System(c)
+	}
+	if settings.AvailabilityLocked(protectedDate.Date, settings.Today(time.Now())) {
+		return response.Error(c,
+			http.StatusBadRequest,
+			"Availability Locked",
+			[]string{"Availability for this date can no longer be changed"},
+		)
+	}
+
 	// Toggle availability
 	updatedDate, err := h.repos.Schedule.ToggleProtectedDateAvailability(
 		c.Request().Context(),
@@ -340,6 +395,13 @@ func (h *Handler) HandleAvailabilityToggle(c echo.Context) error {
 		Bool("is_available", updatedDate.Available).
 		Msg("availability toggled successfully")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &updatedDate.FacilityID,
+		EntityType: entity.AuditAvailability,
+		EntityID:   strconv.Itoa(updatedDate.ID),
+		Action:     entity.AuditToggle,
+	}, protectedDate, updatedDate)
+
 	return render(c, component.ProtectedDay(
 		updatedDate,
 		*auth,
@@ -403,9 +465,17 @@ func (h *Handler) GetCreateScheduleForm(c echo.Context) error {
 		Str("user_initials", userInitials).
 		Msg("rendering schedule creation form")
 
+	// Preselect the facility's default rotation
+	settings, err := h.repos.Facility.GetSettingsByCode(c.Request().Context(), facilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", facilityCode).Msg("failed to get facility settings")
+		return response.System(c)
+	}
+
 	return render(c, component.CreateScheduleForm(
 		facilityCode,
 		userInitials,
+		*settings,
 	))
 }
 
@@ -456,8 +526,8 @@ func (h *Handler) validateFacilityAccess(c echo.Context, ctx *scheduleRequestCon
 		)
 	}
 
-	// Verify admin has access to this facility
-	if ctx.Auth.Role == types.UserRoleAdmin && ctx.Auth.FacilityID != facility.ID {
+	// Verify the user is working at this facility
+	if ctx.Auth.Role != types.UserRoleSuper && ctx.Auth.FacilityID != facility.ID {
 		h.logger.Warn().
 			Int("auth_facility_id", ctx.Auth.FacilityID).
 			Int("target_facility_id", facility.ID).
@@ -707,12 +777,27 @@ func (h *Handler) HandleUpdateSchedule(c echo.Context) error {
 		return response.System(c)
 	}
 
+	// Deactivated users keep their schedule but no longer generate protected days
+	if user, err := h.repos.User.GetByInitialsAndFacility(c.Request().Context(), route.UserInitials, route.FacilityCode); err == nil && user.IsDeactivated() {
+		return response.Validation(c, []string{"Reactivate this user before changing their schedule"})
+	}
+
 	// Validate input and permissions
 	updateData, auth, err := h.validateScheduleUpdate(c)
 	if err != nil {
 		return err // validateScheduleUpdate handles error responses
 	}
 
+	// Kept for the audit trail
+	before, err := h.repos.Schedule.GetByID(c.Request().Context(), updateData.ScheduleID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("schedule_id", updateData.ScheduleID).
+			Msg("failed to retrieve schedule")
+		return response.System(c)
+	}
+
 	// Update schedule
 	schedule, err := h.repos.Schedule.Update(
 		c.Request().Context(),
@@ -735,6 +820,13 @@ func (h *Handler) HandleUpdateSchedule(c echo.Context) error {
 		Str("second_weekday", schedule.SecondWeekday.String()).
 		Msg("schedule updated successfully")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &schedule.FacilityID,
+		EntityType: entity.AuditSchedule,
+		EntityID:   strconv.Itoa(schedule.ID),
+		Action:     entity.AuditUpdate,
+	}, before, schedule)
+
 	return render(c, page.ScheduleCard(*auth, *route, *schedule))
 }
 
@@ -812,14 +904,10 @@ func (h *Handler) validateCreateSchedule(c echo.Context) (*scheduleCreateData, e
 }
 
 func canCreateSchedule(auth *dto.AuthContext, facilityCode string) bool {
-	switch auth.Role {
-	case types.UserRoleSuper:
+	if auth.Role == types.UserRoleSuper {
 		return true
-	case types.UserRoleAdmin:
-		return auth.FacilityCode == facilityCode
-	default:
-		return false
 	}
+	return auth.Can(types.PermScheduleEdit) && auth.FacilityCode == facilityCode
 }
 
 // HandleAvailabilityToggle helpers
@@ -834,8 +922,13 @@ func canToggleAvailability(auth *dto.AuthContext, protectedDate *entity.PD) bool
 		return true
 	}
 
-	// Facility admins can toggle dates within their facility
-	if auth.Role == types.UserRoleAdmin && auth.FacilityID == protectedDate.FacilityID {
+	// Users who override availability can toggle dates within their facility
+	if auth.Can(types.PermAvailabilityOverride) && auth.FacilityID == protectedDate.FacilityID {
+		return true
+	}
+
+	// Area supervisors can toggle dates of members of their area
+	if auth.SupervisesUser(protectedDate.UserID) {
 		return true
 	}
 
@@ -991,14 +1084,13 @@ func validateWeekdays(first, second int) error {
 }
 
 func canViewSchedule(auth *dto.AuthContext, schedule *entity.Schedule) bool {
-	switch auth.Role {
-	case types.UserRoleSuper:
+	if auth.Role == types.UserRoleSuper {
 		return true
-	case types.UserRoleAdmin:
-		return auth.FacilityID == schedule.FacilityID
-	default:
-		return auth.UserID == schedule.UserID
 	}
+	if auth.Can(types.PermScheduleEdit) && auth.FacilityID == schedule.FacilityID {
+		return true
+	}
+	return auth.UserID == schedule.UserID || auth.SupervisesUser(schedule.UserID)
 }
 
 func canModifySchedule(auth *dto.AuthContext, schedule *entity.Schedule) bool {
@@ -1011,21 +1103,25 @@ func canModifySchedule(auth *dto.AuthContext, schedule *entity.Schedule) bool {
 		Int("schedule_facility_id", schedule.FacilityID).
 		Logger()
 
-	switch auth.Role {
-	case types.UserRoleSuper:
+	switch {
+	case auth.Role == types.UserRoleSuper:
 		logger.Debug().Msg("super user access granted")
 		return true
 
-	case types.UserRoleAdmin:
+	case auth.Can(types.PermScheduleEdit):
 		canModify := auth.FacilityID == schedule.FacilityID
 		if canModify {
-			logger.Debug().Msg("admin access granted - same facility")
+			logger.Debug().Msg("schedule editor access granted - same facility")
 		} else {
-			logger.Debug().Msg("admin access denied - different facility")
+			logger.Debug().Msg("schedule editor access denied - different facility")
 		}
 		return canModify
 
 	default:
+		if auth.SupervisesUser(schedule.UserID) {
+			logger.Debug().Msg("area supervisor access granted")
+			return true
+		}
 		canModify := auth.UserID == schedule.UserID
 		if canModify {
 			logger.Debug().Msg("user access granted - own schedule")
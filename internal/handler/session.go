@@ -0,0 +1,178 @@
+// internal/handler/session.go
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	sessionRepo "github.com/DukeRupert/haven/internal/repository/session"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/store"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// currentSessionID returns the ID of the session making the request
+func currentSessionID(c echo.Context) string {
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		return ""
+	}
+	return sess.ID
+}
+
+// signedOut sends the browser back to the login page after its own session was revoked
+func signedOut(c echo.Context) error {
+	clearCookie(c, store.DefaultSessionName)
+	c.Response().Header().Set("HX-Redirect", "/login")
+	return c.NoContent(http.StatusOK)
+}
+
+// DELETE /app/profile/sessions/:session_id
+func (h *Handler) HandleRevokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleRevokeSession").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	sessionID, err := strconv.ParseInt(c.Param("session_id"), 10, 64)
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid session ID"})
+	}
+
+	// Look up the session first so we know whether it is this device
+	sessions, err := h.repos.Session.ListByUserID(ctx, auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to list sessions")
+		return response.System(c)
+	}
+	current := currentSessionID(c)
+	isCurrent := false
+	for _, s := range sessions {
+		if s.ID == sessionID && s.Key == current {
+			isCurrent = true
+		}
+	}
+
+	err = h.repos.Session.DeleteForUser(ctx, sessionID, auth.UserID)
+	if errors.Is(err, sessionRepo.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Session not found"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int64("session_id", sessionID).Msg("failed to revoke session")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", auth.UserID).
+		Int64("session_id", sessionID).
+		Bool("current", isCurrent).
+		Msg("session revoked")
+
+	if isCurrent {
+		return signedOut(c)
+	}
+
+	sessions, err = h.repos.Session.ListByUserID(ctx, auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to list sessions")
+		return response.System(c)
+	}
+
+	return render(c, page.SessionsCard(sessions, current))
+}
+
+// DELETE /app/profile/sessions
+func (h *Handler) HandleRevokeAllSessions(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleRevokeAllSessions").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	revoked, err := h.repos.Session.DeleteByUserID(c.Request().Context(), auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to revoke sessions")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", auth.UserID).
+		Int64("sessions_revoked", revoked).
+		Msg("signed out everywhere")
+
+	return signedOut(c)
+}
+
+// DELETE /app/:facility_code/:user_initials/sessions
+func (h *Handler) HandleRevokeUserSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleRevokeUserSessions").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("initials", route.UserInitials).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to get user")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	}
+
+	// Nobody may lock out or sign out a user who outranks them
+	target, err := h.managedUser(ctx, user.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+	if !canManageUser(auth, route.FacilityCode, target) {
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{"You can only change users whose permissions you hold yourself"})
+	}
+
+	revoked, err := h.repos.Session.DeleteByUserID(ctx, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to revoke sessions")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Int("revoked_by", auth.UserID).
+		Int64("sessions_revoked", revoked).
+		Msg("sessions revoked by admin")
+
+	return response.Success(c, "Signed Out",
+		fmt.Sprintf("%s %s has been signed out of %d session(s).", user.FirstName, user.LastName, revoked))
+}
@@ -0,0 +1,207 @@
+// internal/handler/sso.go
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/oidc"
+	"github.com/DukeRupert/haven/internal/repository/sso"
+	"github.com/DukeRupert/haven/internal/store"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// errSSOFailed is shown for any callback failure so details are not leaked
+var errSSOFailed = echo.NewHTTPError(http.StatusUnauthorized,
+	"Single sign-on failed. Please try again or sign in with your password.")
+
+// startSSO redirects the browser to the identity provider for the given
+// facility, falling back to the global provider. The provider is chosen by
+// facility code rather than email so the response never reveals whether an
+// account exists.
+func (h *Handler) startSSO(c echo.Context, sess *sessions.Session, facilityCode string, logger zerolog.Logger) error {
+	ctx := c.Request().Context()
+
+	// Facility codes are public, and an unknown code uses the global provider
+	facilityID := 0
+	if code := strings.TrimSpace(facilityCode); code != "" {
+		if facility, err := h.repos.Facility.GetByCodeOrAlias(ctx, code); err == nil {
+			facilityID = facility.ID
+		}
+	}
+
+	provider, err := h.sso.ForFacility(ctx, facilityID)
+	if errors.Is(err, oidc.ErrNotConfigured) {
+		return h.LoginResponse(c, http.StatusBadRequest, "Single Sign-On Unavailable",
+			[]string{"Single sign-on is not set up for this facility. Please sign in with your password."}, "")
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facilityID).Msg("failed to load identity provider")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to reach the identity provider. Please try again."}, "")
+	}
+
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to create auth request")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to process login request"}, "")
+	}
+
+	sess.Values[store.SessionKeyOIDCState] = req.State
+	sess.Values[store.SessionKeyOIDCNonce] = req.Nonce
+	sess.Values[store.SessionKeyOIDCVerifier] = req.CodeVerifier
+	sess.Values[store.SessionKeyOIDCFacilityID] = facilityID
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to process login request"}, "")
+	}
+
+	return h.LoginResponse(c, http.StatusOK, "", nil, provider.AuthCodeURL(req))
+}
+
+// GET /login/oidc/callback
+func (h *Handler) HandleSSOCallback(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleSSOCallback").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get session")
+		return errSSOFailed
+	}
+
+	// Pull the values saved before the redirect; they are single use
+	req := oidc.AuthRequest{}
+	req.State, _ = sess.Values[store.SessionKeyOIDCState].(string)
+	req.Nonce, _ = sess.Values[store.SessionKeyOIDCNonce].(string)
+	req.CodeVerifier, _ = sess.Values[store.SessionKeyOIDCVerifier].(string)
+	facilityID, _ := sess.Values[store.SessionKeyOIDCFacilityID].(int)
+	delete(sess.Values, store.SessionKeyOIDCState)
+	delete(sess.Values, store.SessionKeyOIDCNonce)
+	delete(sess.Values, store.SessionKeyOIDCVerifier)
+	delete(sess.Values, store.SessionKeyOIDCFacilityID)
+
+	if e := c.QueryParam("error"); e != "" {
+		logger.Info().Str("error", e).Str("description", c.QueryParam("error_description")).Msg("identity provider returned an error")
+		return errSSOFailed
+	}
+
+	state := c.QueryParam("state")
+	if req.State == "" || subtle.ConstantTimeCompare([]byte(req.State), []byte(state)) != 1 {
+		logger.Warn().Str("ip", c.RealIP()).Msg("sso state mismatch")
+		return errSSOFailed
+	}
+
+	provider, err := h.sso.ForFacility(ctx, facilityID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", facilityID).Msg("failed to load identity provider")
+		return errSSOFailed
+	}
+
+	identity, err := provider.Exchange(ctx, c.QueryParam("code"), req)
+	if err != nil {
+		logger.Warn().Err(err).Msg("sso exchange failed")
+		return errSSOFailed
+	}
+
+	user, err := h.userForIdentity(c, identity, facilityID)
+	if err != nil {
+		logger.Info().Err(err).Str("email", identity.Email).Msg("no account for sso identity")
+		return echo.NewHTTPError(http.StatusForbidden,
+			"No account matches this identity. Ask your facility admin for an invitation.")
+	}
+
+	lockedUntil, err := h.repos.Lockout.LockedUntil(ctx, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to check account lock")
+		return errSSOFailed
+	}
+	if lockedUntil != nil {
+		return echo.NewHTTPError(http.StatusForbidden,
+			"This account is temporarily locked. Please try again later.")
+	}
+	if user.IsDeactivated() {
+		return echo.NewHTTPError(http.StatusForbidden, errAccountDeactivatedMessage)
+	}
+
+	if err := h.repos.SSO.LinkIdentity(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to link identity")
+		return errSSOFailed
+	}
+
+	facility, err := h.repos.Facility.GetByID(ctx, user.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get facility")
+		return errSSOFailed
+	}
+	closed, err := h.facilityClosed(ctx, user, facility)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check facility memberships")
+		return errSSOFailed
+	}
+	if closed {
+		return echo.NewHTTPError(http.StatusForbidden, errFacilityArchivedMessage)
+	}
+
+	// The identity provider replaces the password, not the second factor
+	enabled, err := h.repos.TwoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check two-factor status")
+		return errSSOFailed
+	}
+	if enabled {
+		if err := h.startTwoFactorChallenge(c, sess, user); err != nil {
+			logger.Error().Err(err).Msg("failed to save session")
+			return errSSOFailed
+		}
+		return c.Redirect(http.StatusSeeOther, "/login/2fa")
+	}
+
+	if err := h.createSession(c, sess, user, facility); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return errSSOFailed
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Str("issuer", identity.Issuer).
+		Msg("sso login successful")
+
+	return c.Redirect(http.StatusSeeOther, "/app/calendar")
+}
+
+// userForIdentity finds the user for a verified identity. A previously
+// linked identity wins; otherwise the verified email must match a user at
+// the facility the login started from.
+func (h *Handler) userForIdentity(c echo.Context, identity *oidc.Identity, facilityID int) (*entity.User, error) {
+	ctx := c.Request().Context()
+
+	userID, err := h.repos.SSO.GetUserIDByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return h.repos.User.GetByID(ctx, userID)
+	}
+	if !errors.Is(err, sso.ErrNotFound) {
+		return nil, err
+	}
+
+	user, err := h.repos.User.GetByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if facilityID != 0 && user.FacilityID != facilityID {
+		return nil, errors.New("identity belongs to a different facility")
+	}
+	return user, nil
+}
@@ -0,0 +1,202 @@
+// internal/handler/sso_provider.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/oidc"
+	"github.com/DukeRupert/haven/internal/repository/sso"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/alert"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /app/facilities/:facility_id/sso
+func (h *Handler) GetFacilitySSOForm(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetFacilitySSOForm").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	f, err := h.repos.Facility.GetByID(c.Request().Context(), id)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	provider, err := h.repos.SSO.GetProvider(c.Request().Context(), id)
+	if errors.Is(err, sso.ErrNotFound) {
+		provider = nil
+	} else if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve sso provider")
+		return response.System(c)
+	}
+
+	return render(c, page.FacilitySSOForm(*f, provider))
+}
+
+// PUT /app/facilities/:facility_id/sso
+// The client secret is sealed before it is stored; leaving it blank keeps
+// the current one.
+func (h *Handler) HandleSaveFacilitySSO(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleSaveFacilitySSO").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	f, err := h.repos.Facility.GetByID(ctx, id)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	var p params.SaveSSOProviderParams
+	if err := c.Bind(&p); err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid request payload"})
+	}
+	p.IssuerURL = strings.TrimRight(strings.TrimSpace(p.IssuerURL), "/")
+	p.ClientID = strings.TrimSpace(p.ClientID)
+
+	before, err := h.repos.SSO.GetProvider(ctx, id)
+	if errors.Is(err, sso.ErrNotFound) {
+		before = nil
+	} else if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve sso provider")
+		return response.System(c)
+	}
+
+	var errs []string
+	if u, err := url.Parse(p.IssuerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, "Issuer URL must be an https URL")
+	}
+	if p.ClientID == "" {
+		errs = append(errs, "Client ID is required")
+	}
+	if p.ClientSecret == "" && before == nil {
+		errs = append(errs, "Client secret is required")
+	}
+	if len(errs) > 0 {
+		return response.Validation(c, errs)
+	}
+
+	// Check the issuer can be discovered before users are sent to it
+	discoverCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := oidc.NewProvider(discoverCtx, oidc.Config{IssuerURL: p.IssuerURL, ClientID: p.ClientID}); err != nil {
+		logger.Info().Err(err).Str("issuer_url", p.IssuerURL).Msg("sso issuer discovery failed")
+		return response.Validation(c, []string{"Unable to reach the issuer's OpenID configuration. Check the issuer URL."})
+	}
+
+	provider := entity.OIDCProvider{
+		FacilityID: id,
+		IssuerURL:  p.IssuerURL,
+		ClientID:   p.ClientID,
+		Enabled:    p.Enabled,
+	}
+	if p.ClientSecret != "" {
+		provider.ClientSecret, err = h.secrets.Seal(p.ClientSecret)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to seal client secret")
+			return response.System(c)
+		}
+	}
+
+	saved, err := h.repos.SSO.SaveProvider(ctx, provider)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to save sso provider")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", id).
+		Str("issuer_url", saved.IssuerURL).
+		Bool("enabled", saved.Enabled).
+		Msg("sso provider saved")
+
+	action := entity.AuditUpdate
+	if before == nil {
+		action = entity.AuditCreate
+	}
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &f.ID,
+		EntityType: entity.AuditSSOProvider,
+		EntityID:   strconv.Itoa(saved.ID),
+		Action:     action,
+	}, before, saved)
+
+	return render(c, ComponentGroup(
+		alert.Success("Single Sign-On Saved", fmt.Sprintf("The identity provider for %s has been saved", f.Code)),
+		page.FacilityListItem(*f),
+	))
+}
+
+// DELETE /app/facilities/:facility_id/sso
+// Users of the facility fall back to the global provider, if any.
+func (h *Handler) HandleDeleteFacilitySSO(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleDeleteFacilitySSO").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	id, err := strconv.Atoi(c.Param("facility_id"))
+	if err != nil {
+		return response.Error(c, http.StatusBadRequest, "Invalid Request", []string{"Invalid facility ID"})
+	}
+
+	f, err := h.repos.Facility.GetByID(ctx, id)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"The requested facility does not exist"})
+	}
+
+	before, err := h.repos.SSO.GetProvider(ctx, id)
+	if errors.Is(err, sso.ErrNotFound) {
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"This facility has no identity provider of its own"})
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to retrieve sso provider")
+		return response.System(c)
+	}
+
+	if err := h.repos.SSO.DeleteProvider(ctx, id); err != nil && !errors.Is(err, sso.ErrNotFound) {
+		logger.Error().Err(err).Int("facility_id", id).Msg("failed to delete sso provider")
+		return response.System(c)
+	}
+
+	logger.Info().Int("facility_id", id).Msg("sso provider deleted")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &f.ID,
+		EntityType: entity.AuditSSOProvider,
+		EntityID:   strconv.Itoa(before.ID),
+		Action:     entity.AuditDelete,
+	}, before, nil)
+
+	return render(c, ComponentGroup(
+		alert.Success("Single Sign-On Removed", fmt.Sprintf("%s now uses the global identity provider, if one is configured", f.Code)),
+		page.FacilityListItem(*f),
+	))
+}
@@ -0,0 +1,289 @@
+// internal/handler/transfer.go
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/dto"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/repository/transfer"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// GET /app/:facility_code/:user_initials/transfer
+func (h *Handler) GetTransferForm(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "GetTransferForm").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, from, err := h.transferableUser(c, route)
+	if err != nil {
+		return transferUserError(c, err, logger)
+	}
+
+	destinations, err := h.transferDestinations(c.Request().Context(), auth, from)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list transfer destinations")
+		return response.System(c)
+	}
+	if len(destinations) == 0 {
+		return response.Error(c, http.StatusConflict, "No Facilities",
+			[]string{"There are no other facilities you can transfer users to"})
+	}
+
+	pending, err := h.repos.Transfer.GetPending(c.Request().Context(), user.ID)
+	if err != nil && !errors.Is(err, transfer.ErrNotFound) {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to get pending transfer")
+		return response.System(c)
+	}
+	if pending != nil {
+		return response.Error(c, http.StatusConflict, "Transfer Pending",
+			[]string{fmt.Sprintf("%s already has a transfer pending on %s", user.Initials, pending.EffectiveDate.Format("January 2, 2006"))})
+	}
+
+	settings, err := h.repos.Facility.GetSettings(c.Request().Context(), from.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", from.ID).Msg("failed to get facility settings")
+		return response.System(c)
+	}
+
+	return render(c, page.TransferForm(from.Code, *user, destinations, settings.Today(time.Now()).Format("2006-01-02")))
+}
+
+// POST /app/:facility_code/:user_initials/transfer
+// Moves a user's home facility from an effective date, at once when that is
+// today. Protected dates before that date stay with the old facility.
+func (h *Handler) HandleTransferUser(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleTransferUser").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, from, err := h.transferableUser(c, route)
+	if err != nil {
+		return transferUserError(c, err, logger)
+	}
+
+	ctx := c.Request().Context()
+	toCode := strings.ToUpper(strings.TrimSpace(c.FormValue("facility_code")))
+	to, err := h.repos.Facility.GetByCode(ctx, toCode)
+	if err != nil || to.IsArchived() {
+		return response.Validation(c, []string{"Please choose an open facility"})
+	}
+	if !canTransferTo(auth, to) {
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{fmt.Sprintf("You don't have permission to transfer users to %s", to.Code)})
+	}
+
+	role := types.UserRole(c.FormValue("role"))
+	if role != types.UserRoleAdmin && role != types.UserRoleUser {
+		return response.Validation(c, []string{"Please choose a role of admin or user"})
+	}
+	if !auth.CanGrantAt(to.Code, role.Permissions()) {
+		return response.Error(c, http.StatusForbidden, "Invalid Role", []string{errRoleNotGrantable})
+	}
+
+	// Effective dates are days at the facility the user is leaving
+	settings, err := h.repos.Facility.GetSettings(ctx, from.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("facility_id", from.ID).Msg("failed to get facility settings")
+		return response.System(c)
+	}
+	effective, err := time.Parse("2006-01-02", c.FormValue("effective_date"))
+	if err != nil {
+		return response.Validation(c, []string{"Please provide a valid effective date (YYYY-MM-DD)"})
+	}
+	today := settings.Today(time.Now())
+	if effective.Before(today) {
+		return response.Validation(c, []string{"The effective date cannot be in the past"})
+	}
+
+	t, err := h.repos.Transfer.Schedule(ctx, params.TransferUserParams{
+		UserID:        user.ID,
+		ToFacilityID:  to.ID,
+		Role:          role,
+		EffectiveDate: effective,
+		TransferredBy: auth.UserID,
+	})
+	// Transfers from today take effect at once; later ones are applied by
+	// the transfer worker when their day arrives
+	if err == nil && !effective.After(today) {
+		t, err = h.repos.Transfer.Apply(ctx, t.ID)
+	}
+	switch {
+	case errors.Is(err, transfer.ErrSameFacility):
+		return response.Validation(c, []string{fmt.Sprintf("%s is already based at %s", user.Initials, to.Code)})
+	case errors.Is(err, transfer.ErrInitialsTaken):
+		return response.Validation(c, []string{
+			fmt.Sprintf("Someone at %s already uses the initials %s", to.Code, user.Initials),
+		})
+	case errors.Is(err, transfer.ErrPending):
+		return response.Error(c, http.StatusConflict, "Transfer Pending",
+			[]string{fmt.Sprintf("%s already has a transfer pending", user.Initials)})
+	case err != nil:
+		logger.Error().
+			Err(err).
+			Int("user_id", user.ID).
+			Int("to_facility_id", to.ID).
+			Msg("failed to transfer user")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("transfer_id", t.ID).
+		Int("user_id", user.ID).
+		Str("from", from.Code).
+		Str("to", to.Code).
+		Time("effective_date", t.EffectiveDate).
+		Int("transferred_by", auth.UserID).
+		Bool("applied", t.AppliedAt != nil).
+		Msg("user transfer scheduled")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &from.ID,
+		EntityType: entity.AuditTransfer,
+		EntityID:   strconv.Itoa(t.ID),
+		Action:     entity.AuditCreate,
+	}, user, t)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		h.sendTransferNotices(ctx, user, from, to, t.EffectiveDate, logger)
+	}()
+
+	c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/%s/users", from.Code))
+	return c.NoContent(http.StatusOK)
+}
+
+// errNotTransferable is returned for users who cannot be transferred from
+// the facility in the route
+var errNotTransferable = errors.New("user cannot be transferred from the facility")
+
+// transferableUser loads the user named in the route along with the facility
+// they are leaving. Only active users based at the facility can transfer.
+func (h *Handler) transferableUser(c echo.Context, route *dto.RouteContext) (*entity.User, *entity.Facility, error) {
+	ctx := c.Request().Context()
+	user, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.FacilityID != from.ID || user.IsDeactivated() || user.Role == types.UserRoleSuper {
+		return nil, nil, errNotTransferable
+	}
+	return user, from, nil
+}
+
+// transferUserError responds to a failed transferableUser lookup
+func transferUserError(c echo.Context, err error, logger zerolog.Logger) error {
+	switch {
+	case errors.Is(err, userRepo.ErrNotFound):
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	case errors.Is(err, errNotTransferable):
+		return response.Error(c, http.StatusConflict, "Cannot Transfer",
+			[]string{"Only active users based at this facility can be transferred"})
+	default:
+		logger.Error().Err(err).Msg("failed to fetch user to transfer")
+		return response.System(c)
+	}
+}
+
+// transferDestinations lists the open facilities other than the current one
+// the user may transfer people to
+func (h *Handler) transferDestinations(ctx context.Context, auth *dto.AuthContext, from *entity.Facility) ([]entity.Facility, error) {
+	facilities, err := h.repos.Facility.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var destinations []entity.Facility
+	for _, f := range facilities {
+		if f.ID != from.ID && !f.IsArchived() && canTransferTo(auth, &f) {
+			destinations = append(destinations, f)
+		}
+	}
+	return destinations, nil
+}
+
+// canTransferTo reports whether the user may move people into a facility.
+// They must also manage users there.
+func canTransferTo(auth *dto.AuthContext, to *entity.Facility) bool {
+	return auth.CanAt(to.Code, types.PermUsersManage)
+}
+
+// sendTransferNotices emails the admins of both facilities about a transfer
+func (h *Handler) sendTransferNotices(ctx context.Context, user *entity.User, from, to *entity.Facility, effective time.Time, logger zerolog.Logger) {
+	sent := make(map[int]bool)
+	for _, f := range []*entity.Facility{from, to} {
+		admins, err := h.repos.User.ListAdmins(ctx, f.ID)
+		if err != nil {
+			logger.Error().Err(err).Int("facility_id", f.ID).Msg("failed to list facility admins")
+			continue
+		}
+		for _, admin := range admins {
+			if sent[admin.ID] || admin.ID == user.ID {
+				continue
+			}
+			sent[admin.ID] = true
+
+			data := map[string]interface{}{
+				"FirstName":     admin.FirstName,
+				"UserName":      user.FirstName + " " + user.LastName,
+				"Initials":      user.Initials,
+				"FromFacility":  from.Code,
+				"ToFacility":    to.Code,
+				"EffectiveDate": effective.Format("January 2, 2006"),
+				"FromName":      "MirandaShift Support",
+				"Subject":       fmt.Sprintf("%s Is Transferring from %s to %s", user.Initials, from.Code, to.Code),
+			}
+			if err := h.mailer.SendTemplate(ctx, "transfer_notice", admin.Email, data); err != nil {
+				logger.Error().Err(err).Int("admin_id", admin.ID).Msg("failed to send transfer notice")
+			}
+		}
+	}
+}
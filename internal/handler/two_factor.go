@@ -0,0 +1,628 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/middleware"
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/repository/twofactor"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/store"
+	"github.com/DukeRupert/haven/web/view/page"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	twoFactorIssuer       = "MirandaShift"
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorMaxAttempts  = 5
+	totpPeriod            = 30
+	recoveryCodeCount     = 10
+	recoveryCodeAlphabet  = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" form:"code"`
+}
+
+// startTwoFactorChallenge records a password-verified user in the session
+// until they complete the second factor
+func (h *Handler) startTwoFactorChallenge(c echo.Context, sess *sessions.Session, user *entity.User) error {
+	sess.Values[store.SessionKeyPendingUserID] = user.ID
+	sess.Values[store.SessionKeyPendingSince] = time.Now()
+	delete(sess.Values, store.SessionKeyPendingFailures)
+	return sess.Save(c.Request(), c.Response())
+}
+
+// clearTwoFactorChallenge drops a pending challenge so the user has to
+// sign in with their password again
+func clearTwoFactorChallenge(sess *sessions.Session) {
+	delete(sess.Values, store.SessionKeyPendingUserID)
+	delete(sess.Values, store.SessionKeyPendingSince)
+	delete(sess.Values, store.SessionKeyPendingFailures)
+}
+
+// pendingTwoFactorUser returns the user awaiting a second factor, if the
+// challenge has not expired
+func pendingTwoFactorUser(sess *sessions.Session) (int, bool) {
+	userID, ok := sess.Values[store.SessionKeyPendingUserID].(int)
+	if !ok || userID == 0 {
+		return 0, false
+	}
+	since, ok := sess.Values[store.SessionKeyPendingSince].(time.Time)
+	if !ok || time.Since(since) > twoFactorChallengeTTL {
+		return 0, false
+	}
+	return userID, true
+}
+
+// GET /login/2fa
+func (h *Handler) GetTwoFactorLogin(c echo.Context) error {
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+	if _, ok := pendingTwoFactorUser(sess); !ok {
+		return c.Redirect(http.StatusSeeOther, "/login")
+	}
+	return render(c, page.TwoFactorLogin())
+}
+
+// POST /login/2fa
+func (h *Handler) HandleTwoFactorLogin(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleTwoFactorLogin").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	sess, err := session.Get(store.DefaultSessionName, c)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get session")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to process login request"}, "")
+	}
+
+	userID, ok := pendingTwoFactorUser(sess)
+	if !ok {
+		return h.LoginResponse(c, http.StatusUnauthorized, "Session Expired",
+			[]string{"Please sign in again."}, "")
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		return h.LoginResponse(c, http.StatusBadRequest, "Invalid Request",
+			[]string{"Please enter your authentication code"}, "")
+	}
+
+	// A lock taken out during the challenge stops further guesses
+	lockedUntil, err := h.repos.Lockout.LockedUntil(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to check account lock")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+	if lockedUntil != nil {
+		logger.Info().Int("user_id", userID).Msg("two-factor attempt on locked account")
+		clearTwoFactorChallenge(sess)
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			logger.Error().Err(err).Msg("failed to save session")
+		}
+		return h.LoginResponse(c, http.StatusForbidden, "Account Locked",
+			[]string{"Too many failed sign in attempts. Please try again later or reset your password."}, "")
+	}
+
+	tf, err := h.twoFactor(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to get two-factor settings")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+
+	if !h.verifySecondFactor(c, tf, req.Code) {
+		logger.Debug().Int("user_id", userID).Msg("invalid two-factor code")
+		h.recordFailedLogin(ctx, userID, c.RealIP())
+
+		// Too many wrong codes ends the challenge
+		failures, _ := sess.Values[store.SessionKeyPendingFailures].(int)
+		failures++
+		sess.Values[store.SessionKeyPendingFailures] = failures
+		if failures >= twoFactorMaxAttempts {
+			clearTwoFactorChallenge(sess)
+		}
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			logger.Error().Err(err).Msg("failed to save session")
+		}
+		if failures >= twoFactorMaxAttempts {
+			return h.LoginResponse(c, http.StatusUnauthorized, "Login Failed",
+				[]string{"Too many invalid codes. Please sign in again."}, "")
+		}
+		return h.LoginResponse(c, http.StatusUnauthorized, "Login Failed",
+			[]string{"Invalid authentication code"}, "")
+	}
+
+	user, err := h.repos.User.GetByID(ctx, userID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", userID).Msg("failed to get user")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+
+	facility, err := h.repos.Facility.GetByID(ctx, user.FacilityID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get facility")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+	closed, err := h.facilityClosed(ctx, user, facility)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to check facility memberships")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login"}, "")
+	}
+	if closed {
+		return h.LoginResponse(c, http.StatusForbidden, "Facility Archived",
+			[]string{errFacilityArchivedMessage}, "")
+	}
+
+	if err := h.createSession(c, sess, user, facility); err != nil {
+		logger.Error().Err(err).Msg("failed to save session")
+		return h.LoginResponse(c, http.StatusInternalServerError, "System Error",
+			[]string{"Unable to complete login process"}, "")
+	}
+
+	logger.Info().Int("user_id", user.ID).Msg("two-factor login completed")
+
+	return h.LoginResponse(c, http.StatusOK, "", nil, "/app/calendar")
+}
+
+// twoFactor returns a user's enrollment state with the TOTP secret opened
+func (h *Handler) twoFactor(ctx context.Context, userID int) (*entity.TwoFactor, error) {
+	tf, err := h.repos.TwoFactor.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Secret != "" {
+		tf.Secret, err = h.secrets.Open(tf.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("opening totp secret: %w", err)
+		}
+	}
+	return tf, nil
+}
+
+// totpStep returns the time step a TOTP code was generated in, allowing
+// one step of clock drift either way
+func totpStep(code, secret string, now time.Time) (int64, bool) {
+	for _, drift := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(drift*totpPeriod) * time.Second)
+		ok, err := totp.ValidateCustom(code, secret, t, totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && ok {
+			return t.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// useTOTP accepts a TOTP code only once: a code is rejected unless its time
+// step is later than the last one accepted for the user
+func (h *Handler) useTOTP(ctx context.Context, userID int, secret, code string) bool {
+	step, ok := totpStep(code, secret, time.Now())
+	if !ok {
+		return false
+	}
+	if err := h.repos.TwoFactor.UseStep(ctx, userID, step); err != nil {
+		if errors.Is(err, twofactor.ErrCodeReused) {
+			h.logger.Info().Int("user_id", userID).Msg("two-factor code reused")
+		} else {
+			h.logger.Error().Err(err).Int("user_id", userID).Msg("failed to record two-factor code")
+		}
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func (h *Handler) verifySecondFactor(c echo.Context, tf *entity.TwoFactor, code string) bool {
+	if !tf.Enabled || tf.Secret == "" {
+		return false
+	}
+
+	code = strings.TrimSpace(code)
+	if h.useTOTP(c.Request().Context(), tf.UserID, tf.Secret, strings.ReplaceAll(code, " ", "")) {
+		return true
+	}
+
+	codes, err := h.repos.TwoFactor.ListUnusedRecoveryCodes(c.Request().Context(), tf.UserID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("user_id", tf.UserID).Msg("failed to list recovery codes")
+		return false
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	for _, rc := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), []byte(normalized)) == nil {
+			if err := h.repos.TwoFactor.UseRecoveryCode(c.Request().Context(), rc.ID); err != nil {
+				h.logger.Error().Err(err).Int("user_id", tf.UserID).Msg("failed to mark recovery code used")
+				return false
+			}
+			h.logger.Info().Int("user_id", tf.UserID).Msg("recovery code redeemed")
+			return true
+		}
+	}
+
+	return false
+}
+
+// GET /app/profile/2fa
+func (h *Handler) HandleTwoFactorSetup(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleTwoFactorSetup").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	user, err := auth.Provider.GetUser()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get user")
+		return response.System(c)
+	}
+
+	tf, err := h.twoFactor(ctx, user.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to get two-factor settings")
+		return response.System(c)
+	}
+	if tf.Enabled {
+		return response.Error(c, http.StatusConflict, "Already Enabled",
+			[]string{"Two-factor authentication is already enabled for your account."})
+	}
+
+	// A pending secret is shown again rather than replaced, as it may
+	// already be in the user's authenticator app
+	opts := totp.GenerateOpts{
+		Issuer:      twoFactorIssuer,
+		AccountName: user.Email,
+	}
+	if tf.Secret != "" {
+		opts.Secret, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(tf.Secret)
+		if err != nil {
+			logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to decode pending secret")
+			return response.System(c)
+		}
+	}
+
+	key, err := totp.Generate(opts)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate totp key")
+		return response.System(c)
+	}
+
+	if tf.Secret == "" {
+		sealed, err := h.secrets.Seal(key.Secret())
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to seal totp secret")
+			return response.System(c)
+		}
+		err = h.repos.TwoFactor.SetPendingSecret(ctx, user.ID, sealed)
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			return response.Error(c, http.StatusConflict, "Already Enabled",
+				[]string{"Two-factor authentication is already enabled for your account."})
+		}
+		if err != nil {
+			logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to store pending secret")
+			return response.System(c)
+		}
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to render qr code")
+		return response.System(c)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		logger.Error().Err(err).Msg("failed to encode qr code")
+		return response.System(c)
+	}
+	qrCode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	return render(c, page.TwoFactorEnroll(qrCode, key.Secret()))
+}
+
+// POST /app/profile/2fa
+func (h *Handler) HandleTwoFactorEnable(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleTwoFactorEnable").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return response.Validation(c, []string{"Please enter the code from your authenticator app"})
+	}
+
+	tf, err := h.twoFactor(ctx, auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to get two-factor settings")
+		return response.System(c)
+	}
+	if tf.Enabled {
+		return response.Error(c, http.StatusConflict, "Already Enabled",
+			[]string{"Two-factor authentication is already enabled for your account."})
+	}
+	if tf.Secret == "" || !h.useTOTP(ctx, auth.UserID, tf.Secret, strings.TrimSpace(req.Code)) {
+		return response.Validation(c, []string{"The code is invalid or has expired. Please try again."})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate recovery codes")
+		return response.System(c)
+	}
+
+	if err := h.repos.TwoFactor.Enable(ctx, auth.UserID, hashes); err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to enable two-factor")
+		return response.System(c)
+	}
+
+	logger.Info().Int("user_id", auth.UserID).Msg("two-factor authentication enabled")
+
+	return render(c, page.RecoveryCodes(codes))
+}
+
+// POST /app/profile/2fa/recovery-codes
+func (h *Handler) HandleRegenerateRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleRegenerateRecoveryCodes").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	enabled, err := h.repos.TwoFactor.IsEnabled(ctx, auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to check two-factor status")
+		return response.System(c)
+	}
+	if !enabled {
+		return response.Error(c, http.StatusBadRequest, "Not Enabled",
+			[]string{"Enable two-factor authentication first."})
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate recovery codes")
+		return response.System(c)
+	}
+
+	if err := h.repos.TwoFactor.ReplaceRecoveryCodes(ctx, auth.UserID, hashes); err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to replace recovery codes")
+		return response.System(c)
+	}
+
+	logger.Info().Int("user_id", auth.UserID).Msg("recovery codes regenerated")
+
+	return render(c, page.RecoveryCodes(codes))
+}
+
+// DELETE /app/profile/2fa
+func (h *Handler) HandleTwoFactorDisable(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleTwoFactorDisable").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	facility, err := auth.Provider.GetFacility()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get facility")
+		return response.System(c)
+	}
+	if facility.RequireTwoFactor {
+		return response.Error(c, http.StatusForbidden, "Required",
+			[]string{"Your facility requires two-factor authentication."})
+	}
+
+	// Require a current code so a hijacked session cannot remove the second factor
+	tf, err := h.twoFactor(ctx, auth.UserID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to get two-factor settings")
+		return response.System(c)
+	}
+	if !h.verifySecondFactor(c, tf, c.FormValue("code")) {
+		return response.Validation(c, []string{"The code is invalid or has expired. Please try again."})
+	}
+
+	if err := h.repos.TwoFactor.Disable(ctx, auth.UserID); err != nil {
+		logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to disable two-factor")
+		return response.System(c)
+	}
+
+	logger.Info().Int("user_id", auth.UserID).Msg("two-factor authentication disabled")
+
+	return render(c, page.TwoFactorCard(false, false))
+}
+
+// DELETE /app/:facility_code/:user_initials/2fa
+func (h *Handler) HandleResetTwoFactor(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleResetTwoFactor").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	user, err := h.repos.User.GetByInitialsAndFacility(ctx, route.UserInitials, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).
+			Str("initials", route.UserInitials).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to get user")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"User not found"})
+	}
+
+	// Your own second factor is removed from your profile, with a code
+	if user.ID == auth.UserID {
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{"Disable two-factor authentication from your profile instead"})
+	}
+
+	// Nobody may lock out or sign out a user who outranks them
+	target, err := h.managedUser(ctx, user.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+	if !canManageUser(auth, route.FacilityCode, target) {
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{"You can only change users whose permissions you hold yourself"})
+	}
+
+	if err := h.repos.TwoFactor.Disable(ctx, user.ID); err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to reset two-factor")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("user_id", user.ID).
+		Int("reset_by", auth.UserID).
+		Msg("two-factor authentication reset by admin")
+
+	return response.Success(c, "Two-Factor Reset",
+		fmt.Sprintf("%s %s will need to enroll again on their next sign in.", user.FirstName, user.LastName))
+}
+
+// PUT /app/:facility_code/two-factor
+func (h *Handler) HandleUpdateTwoFactorRequirement(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := h.logger.With().
+		Str("handler", "HandleUpdateTwoFactorRequirement").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	facility, err := h.repos.Facility.GetByCode(ctx, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.Error(c, http.StatusNotFound, "Not Found", []string{"Facility not found"})
+	}
+
+	required := c.FormValue("required") == "true"
+	if err := h.repos.Facility.SetRequireTwoFactor(ctx, facility.ID, required); err != nil {
+		logger.Error().Err(err).Int("facility_id", facility.ID).Msg("failed to update two-factor requirement")
+		return response.System(c)
+	}
+
+	logger.Info().
+		Int("facility_id", facility.ID).
+		Bool("require_two_factor", required).
+		Msg("facility two-factor requirement updated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditSettings,
+		EntityID:   strconv.Itoa(facility.ID),
+		Action:     entity.AuditUpdate,
+	}, map[string]bool{"require_two_factor": facility.RequireTwoFactor}, map[string]bool{"require_two_factor": required})
+
+	return render(c, page.TwoFactorRequirementToggle(facility.Code, required))
+}
+
+// generateRecoveryCodes returns plain recovery codes for display along with
+// their bcrypt hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, nil, fmt.Errorf("generating recovery code: %w", err)
+			}
+			b[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("hashing recovery code: %w", err)
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, string(hash))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips formatting so codes can be entered with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,96 @@
+// internal/handler/two_factor_test.go
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/store"
+
+	"github.com/gorilla/sessions"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPStep(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / totpPeriod
+
+	codeAt := func(t *testing.T, at time.Time) string {
+		t.Helper()
+		code, err := totp.GenerateCode(secret, at)
+		if err != nil {
+			t.Fatalf("generating code: %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", now, step, true},
+		{"previous step", now.Add(-totpPeriod * time.Second), step - 1, true},
+		{"next step", now.Add(totpPeriod * time.Second), step + 1, true},
+		{"two steps old", now.Add(-2 * totpPeriod * time.Second), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := totpStep(codeAt(t, tt.at), secret, now)
+			if ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("totpStep() = %d, %v, want %d, %v", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := totpStep("000000x", secret, now); ok {
+		t.Error("totpStep() accepted a malformed code")
+	}
+}
+
+func TestPendingTwoFactorUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[interface{}]interface{}
+		wantID int
+		wantOK bool
+	}{
+		{"no challenge", map[interface{}]interface{}{}, 0, false},
+		{"active challenge", map[interface{}]interface{}{
+			store.SessionKeyPendingUserID: 7,
+			store.SessionKeyPendingSince:  time.Now().Add(-time.Minute),
+		}, 7, true},
+		{"expired challenge", map[interface{}]interface{}{
+			store.SessionKeyPendingUserID: 7,
+			store.SessionKeyPendingSince:  time.Now().Add(-twoFactorChallengeTTL - time.Second),
+		}, 0, false},
+		{"missing start", map[interface{}]interface{}{
+			store.SessionKeyPendingUserID: 7,
+		}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := &sessions.Session{Values: tt.values}
+			id, ok := pendingTwoFactorUser(sess)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("pendingTwoFactorUser() = %d, %v, want %d, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestClearTwoFactorChallenge(t *testing.T) {
+	sess := &sessions.Session{Values: map[interface{}]interface{}{
+		store.SessionKeyPendingUserID:   7,
+		store.SessionKeyPendingSince:    time.Now(),
+		store.SessionKeyPendingFailures: 3,
+	}}
+	clearTwoFactorChallenge(sess)
+	if len(sess.Values) != 0 {
+		t.Errorf("challenge values left in session: %v", sess.Values)
+	}
+}
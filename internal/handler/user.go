@@ -1,15 +1,22 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
+	"strconv"
 	"strings"
 
 	"github.com/DukeRupert/haven/internal/middleware"
 	"github.com/DukeRupert/haven/internal/model/dto"
 	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	userRepo "github.com/DukeRupert/haven/internal/repository/user"
 	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/validation"
 	"github.com/DukeRupert/haven/web/view/alert"
 	"github.com/DukeRupert/haven/web/view/component"
 	"github.com/DukeRupert/haven/web/view/page"
@@ -47,8 +54,53 @@ func (h *Handler) HandleUsers(c echo.Context) error {
 		)
 	}
 
-	// Get users from repository
-	users, err := h.repos.User.GetByFacilityCode(c.Request().Context(), route.FacilityCode)
+	// Get facility settings
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Str("facility_code", route.FacilityCode).
+			Msg("failed to retrieve facility")
+		return echo.NewHTTPError(http.StatusNotFound, "Facility not found")
+	}
+
+	areas, areaID, err := h.areaFilter(c, auth, facility.ID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("facility_id", facility.ID).
+			Msg("failed to retrieve areas")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load controllers. Please try again later.",
+		)
+	}
+
+	quals, qualificationID, err := h.qualificationFilter(c, facility.ID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("facility_id", facility.ID).
+			Msg("failed to retrieve qualifications")
+		return echo.NewHTTPError(
+			http.StatusInternalServerError,
+			"Unable to load controllers. Please try again later.",
+		)
+	}
+
+	// Get the page of users from repository
+	filter := userListParams(c, route.FacilityCode, areaID, qualificationID)
+	users, total, err := h.repos.User.ListByFacility(c.Request().Context(), filter)
+	if err == nil && len(users) == 0 && filter.Page > 1 {
+		// A page past the end has no rows to count, so find the total from
+		// the first page and show the last one instead
+		filter.Page = 1
+		users, total, err = h.repos.User.ListByFacility(c.Request().Context(), filter)
+		if last := params.UserPages(total); err == nil && last > 1 {
+			filter.Page = last
+			users, total, err = h.repos.User.ListByFacility(c.Request().Context(), filter)
+		}
+	}
 	if err != nil {
 		logger.Error().
 			Err(err).
@@ -77,6 +129,12 @@ func (h *Handler) HandleUsers(c echo.Context) error {
 		AuthCtx:     *auth,
 		RouteCtx:    *route,
 		Users:       users,
+
+		RequireTwoFactor: facility.RequireTwoFactor,
+		Areas:            areas,
+		Qualifications:   quals,
+		Filter:           filter,
+		Total:            total,
 	}
 
 	logger.Debug().
@@ -91,6 +149,97 @@ func (h *Handler) HandleUsers(c echo.Context) error {
 	).Render(c.Request().Context(), c.Response().Writer)
 }
 
+// Most users listed by a search across facilities
+const maxUserSearchResults = 50
+
+// GET /app/users/search
+// Finds users at every facility. A search matching one user goes straight
+// to their profile.
+func (h *Handler) HandleSearchUsers(c echo.Context) error {
+	logger := h.logger.With().
+		Str("handler", "HandleSearchUsers").
+		Str("request_id", c.Response().Header().Get(echo.HeaderXRequestID)).
+		Logger()
+
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
+	route, err := middleware.GetRouteContext(c)
+	if err != nil {
+		logger.Error().Msg("missing route context")
+		return response.System(c)
+	}
+
+	query := strings.TrimSpace(c.QueryParam("q"))
+	query = truncateRunes(query, maxUserSearchLength)
+
+	var users []entity.User
+	if query != "" {
+		users, err = h.repos.User.Search(c.Request().Context(), query, maxUserSearchResults)
+		if err != nil {
+			logger.Error().Err(err).Str("query", query).Msg("failed to search users")
+			return echo.NewHTTPError(
+				http.StatusInternalServerError,
+				"Unable to search users. Please try again later.",
+			)
+		}
+	}
+
+	if len(users) == 1 {
+		return c.Redirect(http.StatusFound, fmt.Sprintf("/app/%s/%s", users[0].FacilityCode, users[0].Initials))
+	}
+
+	props := dto.UserSearchPageProps{
+		Title:       "Find a User",
+		Description: "Search users at every facility by name, initials or email.",
+		NavItems:    BuildNav(route, auth, c.Request().URL.Path),
+		AuthCtx:     *auth,
+		RouteCtx:    *route,
+		Query:       query,
+		Users:       users,
+	}
+
+	return render(c, page.UserSearch(props))
+}
+
+// Longest search accepted on the users page
+const maxUserSearchLength = 100
+
+// userListParams reads the search, filters, sort and page chosen on the
+// users page. Values that are not recognized are ignored.
+func userListParams(c echo.Context, facilityCode string, areaID, qualificationID *int) params.ListUsersParams {
+	p := params.ListUsersParams{
+		FacilityCode:    facilityCode,
+		Search:          strings.TrimSpace(c.QueryParam("q")),
+		AreaID:          areaID,
+		QualificationID: qualificationID,
+		Sort:            params.UserSortName,
+		Page:            1,
+	}
+	p.Search = truncateRunes(p.Search, maxUserSearchLength)
+
+	switch role := types.UserRole(c.QueryParam("role")); role {
+	case types.UserRoleAdmin, types.UserRoleUser:
+		p.Role = role
+	}
+	switch status := c.QueryParam("status"); status {
+	case params.RegistrationCompleted, params.RegistrationPending:
+		p.Status = status
+	}
+	switch sort := c.QueryParam("sort"); sort {
+	case params.UserSortInitials, params.UserSortEmail, params.UserSortNewest:
+		p.Sort = sort
+	}
+	if page, err := strconv.Atoi(c.QueryParam("page")); err == nil && page > 1 {
+		p.Page = page
+	}
+
+	return p
+}
+
 // HandleCreateUser processes new user creation requests
 func (h *Handler) HandleCreateUser(c echo.Context) error {
 	logger := h.logger.With().
@@ -114,12 +263,22 @@ func (h *Handler) HandleCreateUser(c echo.Context) error {
 		)
 	}
 
+	auth, err := middleware.GetAuthContext(c)
+	if err != nil {
+		logger.Error().Msg("missing auth context")
+		return response.System(c)
+	}
+
 	// Validate and parse create request
 	createData, err := h.validateCreateUser(c)
-	if err != nil {
+	if err != nil || createData == nil {
 		return err // validateCreateUser handles error responses
 	}
 
+	if !auth.CanGrantAt(createData.FacilityCode, createData.Role.Permissions()) {
+		return response.Error(c, http.StatusForbidden, "Invalid Role", []string{errRoleNotGrantable})
+	}
+
 	// Prepare create params
 	params := params.CreateUserParams{
 		FirstName:  createData.FirstName,
@@ -133,6 +292,14 @@ func (h *Handler) HandleCreateUser(c echo.Context) error {
 
 	// Create user
 	user, err := h.repos.User.Create(c.Request().Context(), params)
+	if errors.Is(err, userRepo.ErrInitialsTaken) {
+		return response.Validation(c, []string{
+			fmt.Sprintf("Someone at %s already uses the initials %s", route.FacilityCode, params.Initials),
+		})
+	}
+	if errors.Is(err, userRepo.ErrEmailExists) {
+		return response.Validation(c, []string{"This email address is already in use"})
+	}
 	if err != nil {
 		logger.Error().
 			Err(err).
@@ -157,6 +324,13 @@ func (h *Handler) HandleCreateUser(c echo.Context) error {
 		Str("role", string(user.Role)).
 		Msg("user created successfully and verification email sent")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &user.FacilityID,
+		EntityType: entity.AuditUser,
+		EntityID:   strconv.Itoa(user.ID),
+		Action:     entity.AuditCreate,
+	}, nil, user)
+
 	// Handle HTMX request
 	if isHtmxRequest(c) {
 		return render(c, ComponentGroup(
@@ -205,6 +379,18 @@ func (h *Handler) HandleUpdateUser(c echo.Context) error {
 		return err
 	}
 
+	// Area supervisors may manage schedules but not edit profiles
+	target, err := h.managedUser(c.Request().Context(), existingUser.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", existingUser.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+	if !canManageUser(auth, route.FacilityCode, target) {
+		return response.Error(c, http.StatusForbidden,
+			"Access Denied",
+			[]string{"You don't have permission to update this user"})
+	}
+
 	// Bind and validate update params
 	var params params.UpdateUserParams
 	if err := c.Bind(&params); err != nil {
@@ -215,8 +401,71 @@ func (h *Handler) HandleUpdateUser(c echo.Context) error {
 	}
 
 	// Validate role changes
-	if err := h.verifyRoleChange(auth, existingUser.Role, params.Role); err != nil {
-		return err
+	if msg := roleChangeError(auth, route.FacilityCode, existingUser.ID, existingUser.Role, params.Role); msg != "" {
+		return response.Error(c, http.StatusForbidden, "Invalid Role", []string{msg})
+	}
+
+	// Initials saved before validation tightened are kept as they are
+	// until someone changes them
+	if strings.EqualFold(strings.TrimSpace(params.Initials), existingUser.Initials) {
+		params.Initials = existingUser.Initials
+	} else {
+		initials, err := validation.ValidateUserInitials(params.Initials)
+		if err != nil {
+			return response.Validation(c, []string{err.Error()})
+		}
+		params.Initials = string(initials)
+	}
+	initialsChanged := params.Initials != existingUser.Initials
+
+	// Members based at another facility keep their home facility and role;
+	// the role chosen here applies only to this facility
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility_code", route.FacilityCode).Msg("failed to get facility")
+		return response.System(c)
+	}
+	memberRole := params.Role
+	visiting := existingUser.FacilityID != facility.ID
+
+	// Home facilities change through a transfer so protected dates move too
+	params.FacilityID = existingUser.FacilityID
+	if visiting {
+		if params.Role != types.UserRoleAdmin && params.Role != types.UserRoleUser {
+			return response.Validation(c, []string{"Please choose a role of admin or user"})
+		}
+		home, err := h.repos.User.GetByID(c.Request().Context(), existingUser.ID)
+		if err != nil {
+			logger.Error().Err(err).Int("user_id", existingUser.ID).Msg("failed to get user")
+			return response.System(c)
+		}
+		params.FacilityID = home.FacilityID
+		params.Role = home.Role
+	}
+
+	// A new email address only takes effect once it has been confirmed
+	newEmail := strings.ToLower(strings.TrimSpace(params.Email))
+	emailChanged := newEmail != "" && !strings.EqualFold(newEmail, existingUser.Email)
+	params.Email = existingUser.Email
+	if emailChanged && auth.IsImpersonating() {
+		return response.Error(c, http.StatusForbidden, "Access Denied",
+			[]string{"Email addresses can't be changed while viewing as another user"})
+	}
+	if emailChanged {
+		if _, err := mail.ParseAddress(newEmail); err != nil {
+			return response.Validation(c, []string{"Please enter a valid email address"})
+		}
+		unique, err := h.repos.User.IsEmailUnique(c.Request().Context(), newEmail, &existingUser.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check email uniqueness")
+			return response.System(c)
+		}
+		if !unique {
+			return response.Validation(c, []string{"This email address is already in use"})
+		}
+		if result, ok := h.allowRequest(c, ratelimit.EmailChangeAccount, strconv.Itoa(existingUser.ID)); !ok {
+			return response.TooManyRequests(c, result.RetryAfter)
+		}
 	}
 
 	// Perform update
@@ -225,24 +474,62 @@ func (h *Handler) HandleUpdateUser(c echo.Context) error {
 		if strings.Contains(err.Error(), "email already exists") {
 			return response.Validation(c, []string{"This email address is already in use"})
 		}
+		if errors.Is(err, userRepo.ErrInitialsTaken) {
+			return response.Validation(c, []string{
+				fmt.Sprintf("The initials %s are already used at a facility where %s %s works", params.Initials, existingUser.FirstName, existingUser.LastName),
+			})
+		}
 		logger.Error().Err(err).Int("user_id", existingUser.ID).Msg("failed to update user")
 		return response.System(c)
 	}
 
+	if visiting {
+		m, err := h.repos.Membership.Add(c.Request().Context(), updatedUser.ID, facility.ID, memberRole)
+		if err != nil {
+			logger.Error().Err(err).Int("user_id", updatedUser.ID).Msg("failed to update membership role")
+			return response.System(c)
+		}
+		updatedUser.Role = m.Role
+	}
+
 	logger.Info().
 		Int("user_id", updatedUser.ID).
 		Str("email", updatedUser.Email).
 		Str("role", string(updatedUser.Role)).
 		Msg("user updated successfully")
 
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditUser,
+		EntityID:   strconv.Itoa(updatedUser.ID),
+		Action:     entity.AuditUpdate,
+	}, existingUser, updatedUser)
+
+	message := fmt.Sprintf("Successfully updated user %s %s", updatedUser.FirstName, updatedUser.LastName)
+	if emailChanged {
+		if err := h.requestEmailChange(c.Request().Context(), updatedUser, newEmail, logger); err != nil {
+			logger.Error().Err(err).Int("user_id", updatedUser.ID).Msg("failed to request email change")
+			return response.Error(c, http.StatusInternalServerError, "Email Not Changed",
+				[]string{"Other changes were saved, but the confirmation email could not be sent. Please try again."})
+		}
+		message = fmt.Sprintf("%s. A confirmation link has been sent to %s; the new email address takes effect once it is confirmed.",
+			message, newEmail)
+	}
+
+	// Links on the page still use the old initials, so reload it at the new address
+	if initialsChanged {
+		c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/app/%s/%s", route.FacilityCode, updatedUser.Initials))
+		return c.NoContent(http.StatusOK)
+	}
+
 	return render(c, ComponentGroup(
-		alert.Success("User Updated",
-			fmt.Sprintf("Successfully updated user %s %s",
-				updatedUser.FirstName, updatedUser.LastName)),
+		alert.Success("User Updated", message),
 		page.UserDetails(*updatedUser, route.FacilityCode, *auth),
 	))
 }
 
+// DELETE /app/:facility_code/:user_initials
+// Deactivates users based at the facility and removes visiting members
 func (h *Handler) HandleDeleteUser(c echo.Context) error {
 	logger := h.logger.With().
 		Str("handler", "HandleDeleteUser").
@@ -280,24 +567,63 @@ func (h *Handler) HandleDeleteUser(c echo.Context) error {
 		return response.System(c)
 	}
 
+	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Str("facility", route.FacilityCode).Msg("failed to fetch facility")
+		return response.System(c)
+	}
+
+	// Members based elsewhere only lose their access to this facility
+	if user.FacilityID != facility.ID {
+		if auth.UserID == user.ID {
+			return response.Error(c, http.StatusForbidden,
+				"Access Denied",
+				[]string{"You can't remove yourself from a facility"})
+		}
+		if err := h.repos.Membership.Remove(c.Request().Context(), user.ID, facility.ID); err != nil {
+			logger.Error().
+				Err(err).
+				Int("user_id", user.ID).
+				Int("facility_id", facility.ID).
+				Msg("failed to remove membership")
+			return response.System(c)
+		}
+
+		logger.Info().
+			Int("user_id", user.ID).
+			Int("facility_id", facility.ID).
+			Msg("facility member removed")
+
+		h.audit(c, entity.AuditEvent{
+			FacilityID: &facility.ID,
+			EntityType: entity.AuditMembership,
+			EntityID:   strconv.Itoa(user.ID),
+			Action:     entity.AuditDelete,
+		}, user, nil)
+
+		return handleDeleteResponse(c, route.FacilityCode)
+	}
+
 	// Check permissions using auth context from PageContext
 	if !canDeleteUser(auth, user) {
 		logger.Warn().
 			Int("target_user_id", user.ID).
 			Int("requesting_user_id", auth.UserID).
 			Str("role", string(auth.Role)).
-			Msg("unauthorized deletion attempt")
+			Msg("unauthorized deactivation attempt")
 		return response.Error(c, http.StatusForbidden,
 			"Access Denied",
-			[]string{"You don't have permission to delete this user"})
+			[]string{"You don't have permission to deactivate this user"})
 	}
 
-	// Delete user
-	if err := h.repos.User.Delete(c.Request().Context(), user.ID); err != nil {
+	// Users based here are deactivated so their history is kept; supers
+	// can purge them afterwards
+	deactivated, err := h.repos.User.SetDeactivated(c.Request().Context(), user.ID, true)
+	if err != nil {
 		logger.Error().
 			Err(err).
 			Int("user_id", user.ID).
-			Msg("failed to delete user")
+			Msg("failed to deactivate user")
 		return response.System(c)
 	}
 
@@ -305,14 +631,21 @@ func (h *Handler) HandleDeleteUser(c echo.Context) error {
 		Int("user_id", user.ID).
 		Str("email", user.Email).
 		Int("facility_id", user.FacilityID).
-		Msg("user deleted successfully")
+		Msg("user deactivated")
+
+	h.audit(c, entity.AuditEvent{
+		FacilityID: &facility.ID,
+		EntityType: entity.AuditUser,
+		EntityID:   strconv.Itoa(user.ID),
+		Action:     entity.AuditDeactivate,
+	}, user, deactivated)
 
 	// Handle HTMX response using facility code from route context
 	return handleDeleteResponse(c, route.FacilityCode)
 }
 
 func handleDeleteResponse(c echo.Context, facilityCode string) error {
-	redirectURL := fmt.Sprintf("/%s/controllers", facilityCode)
+	redirectURL := fmt.Sprintf("/app/%s/users", facilityCode)
 	c.Response().Header().Set("HX-Redirect", redirectURL)
 	return c.NoContent(http.StatusOK)
 }
@@ -349,7 +682,12 @@ func (h *Handler) GetUpdatePasswordForm(c echo.Context) error {
 	}
 
 	// Check if user can update this password
-	if !canUpdatePassword(auth, existingUser.ID) {
+	target, err := h.managedUser(c.Request().Context(), existingUser.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", existingUser.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+	if !canManageUser(auth, route.FacilityCode, target) {
 		logger.Warn().
 			Int("target_user_id", existingUser.ID).
 			Int("requesting_user_id", auth.UserID).
@@ -400,8 +738,14 @@ func (h *Handler) HandleUpdatePassword(c echo.Context) error {
 		return err
 	}
 
+	target, err := h.managedUser(c.Request().Context(), existingUser.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", existingUser.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+
 	// Get and validate form data
-	formData, err := h.validatePasswordUpdate(c, *existingUser, auth)
+	formData, err := h.validatePasswordUpdate(c, *existingUser, target, route.FacilityCode, auth)
 	if err != nil {
 		return err // validatePasswordUpdate handles error responses
 	}
@@ -434,9 +778,22 @@ func (h *Handler) HandleUpdatePassword(c echo.Context) error {
 		)
 	}
 
+	// Sign the user out everywhere, except on the device changing its own
+	// password
+	var revoked int64
+	if formData.UserID == auth.UserID {
+		revoked, err = h.repos.Session.DeleteOthersByUserID(c.Request().Context(), formData.UserID, currentSessionID(c))
+	} else {
+		revoked, err = h.repos.Session.DeleteByUserID(c.Request().Context(), formData.UserID)
+	}
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", formData.UserID).Msg("failed to revoke sessions after password update")
+	}
+
 	logger.Info().
 		Int("user_id", formData.UserID).
 		Str("updater_role", string(auth.Role)).
+		Int64("sessions_revoked", revoked).
 		Msg("password updated successfully")
 
 	return response.Success(c, "Success", "Password has been updated")
@@ -545,6 +902,17 @@ func (h *Handler) GetUpdateUserForm(c echo.Context) error {
 		return response.System(c)
 	}
 
+	target, err := h.managedUser(c.Request().Context(), user.ID, route.FacilityCode)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to load user permissions")
+		return response.System(c)
+	}
+	if !canManageUser(auth, route.FacilityCode, target) {
+		return response.Error(c, http.StatusForbidden,
+			"Access Denied",
+			[]string{"You don't have permission to edit this user"})
+	}
+
 	logger.Debug().
 		Int("user_id", user.ID).
 		Str("email", user.Email).
@@ -553,52 +921,3 @@ func (h *Handler) GetUpdateUserForm(c echo.Context) error {
 
 	return render(c, component.UpdateUserForm(*user, route.FacilityCode, *auth))
 }
-
-// Form validation types and helpers
-type UpdateUserFormData struct {
-	UserID int
-	Auth   *dto.AuthContext
-}
-
-func (h *Handler) validateFormAccess(c echo.Context) (*UpdateUserFormData, error) {
-	logger := h.logger.With().
-		Str("method", "validateFormAccess").
-		Logger()
-
-	// Parse and validate user ID
-	userID, err := getUserID(c)
-	if err != nil {
-		logger.Debug().Err(err).Msg("invalid user ID")
-		return nil, response.System(c)
-	}
-
-	// Get auth context
-	auth, err := middleware.GetAuthContext(c)
-	if err != nil {
-		logger.Error().Err(err).Msg("failed to get auth context")
-		return nil, response.Error(c,
-			http.StatusInternalServerError,
-			"Authentication Error",
-			[]string{"Unable to verify permissions"},
-		)
-	}
-
-	// Check permissions
-	if !canAccessUserForm(auth, userID) {
-		logger.Warn().
-			Int("target_user_id", userID).
-			Int("requesting_user_id", auth.UserID).
-			Str("role", string(auth.Role)).
-			Msg("unauthorized form access attempt")
-		return nil, response.Error(c,
-			http.StatusForbidden,
-			"Access Denied",
-			[]string{"You don't have permission to edit this user"},
-		)
-	}
-
-	return &UpdateUserFormData{
-		UserID: userID,
-		Auth:   auth,
-	}, nil
-}
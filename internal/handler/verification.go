@@ -10,7 +10,9 @@ import (
 
 	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/ratelimit"
 	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/validation"
 	"github.com/rs/zerolog"
 
 	"github.com/DukeRupert/haven/web/view/alert"
@@ -86,6 +88,11 @@ func (h *Handler) HandleResendVerification(c echo.Context) error {
         ).Render(c.Request().Context(), c.Response().Writer)
     }
 
+    // Limit how many emails a single address can trigger
+    if result, ok := h.allowRequest(c, ratelimit.ResendAccount, email); !ok {
+        return response.TooManyRequests(c, result.RetryAfter)
+    }
+
     // Get user by email
     user, err := h.repos.User.GetByEmail(c.Request().Context(), email)
     if err != nil {
@@ -96,6 +103,23 @@ func (h *Handler) HandleResendVerification(c echo.Context) error {
         ).Render(c.Request().Context(), c.Response().Writer)
     }
 
+    // Revoked invitations can only be renewed by an admin
+    revoked, err := h.repos.Invitation.IsRevoked(c.Request().Context(), user.ID)
+    if err != nil {
+        logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to check invitation")
+        return alert.Error(
+            "System Error",
+            []string{"Unable to send verification email. Please try again."},
+        ).Render(c.Request().Context(), c.Response().Writer)
+    }
+    if revoked {
+        logger.Info().Int("user_id", user.ID).Msg("verification requested for revoked invitation")
+        return alert.Success(
+            "Verification Email",
+            "If an account exists with this email, a new verification link will be sent.",
+        ).Render(c.Request().Context(), c.Response().Writer)
+    }
+
     // Check if user already verified
     if user.Password != "" {
         return alert.Error(
@@ -144,6 +168,11 @@ func (h *Handler) InitiateEmailVerification(c echo.Context) error {
 		).Render(c.Request().Context(), c.Response().Writer)
 	}
 
+	// Limit how many emails a single address can trigger
+	if result, ok := h.allowRequest(c, ratelimit.VerifyAccount, req.Email); !ok {
+		return response.TooManyRequests(c, result.RetryAfter)
+	}
+
 	// Check if user exists with this email
 	user, err := h.repos.User.GetByEmail(c.Request().Context(), req.Email)
 	if err != nil {
@@ -161,6 +190,23 @@ func (h *Handler) InitiateEmailVerification(c echo.Context) error {
 		).Render(c.Request().Context(), c.Response().Writer)
 	}
 
+	// Revoked invitations can only be renewed by an admin
+	revoked, err := h.repos.Invitation.IsRevoked(c.Request().Context(), user.ID)
+	if err != nil {
+		logger.Error().Err(err).Int("user_id", user.ID).Msg("Failed to check invitation")
+		return alert.Error(
+			"System Error",
+			[]string{"Unable to process request. Please try again."},
+		).Render(c.Request().Context(), c.Response().Writer)
+	}
+	if revoked {
+		logger.Info().Int("user_id", user.ID).Msg("Verification requested for revoked invitation")
+		return alert.Success(
+			"Verification Email Sent",
+			"If an account exists with this email, you will receive a verification link shortly.",
+		).Render(c.Request().Context(), c.Response().Writer)
+	}
+
 	// Generate verification token
 	token, err := generateSecureToken()
 	if err != nil {
@@ -298,12 +344,9 @@ func (h *Handler) HandleRegistration(c echo.Context) error {
 		))
 	}
 
-	// Add 'K' prefix to facility code
-	fullFacilityCode := "K" + strings.ToUpper(params.FacilityCode)
-
-	// Check if facility exists
-	facility, err := h.repos.Facility.GetByCode(c.Request().Context(), fullFacilityCode)
-	if err != nil || facility == nil {
+	// Check if facility exists, accepting its code or alias
+	facility, err := h.repos.Facility.GetByCodeOrAlias(c.Request().Context(), strings.TrimSpace(params.FacilityCode))
+	if err != nil || facility == nil || facility.IsArchived() {
 		return render(c, ComponentGroup(
 			alert.Error(
 				"Invalid Facility",
@@ -365,15 +408,12 @@ func (h *Handler) HandleRegistration(c echo.Context) error {
 }
 
 func validateRegistrationParams(params *params.RegisterParams) error {
-	// Validate facility code (3 letters)
-	if len(params.FacilityCode) == 0 {
-		return fmt.Errorf("facility code is required")
-	}
-	if len(params.FacilityCode) != 3 {
-		return fmt.Errorf("facility code must be exactly 3 letters")
-	}
-	if !isAlpha(params.FacilityCode) {
-		return fmt.Errorf("facility code must contain only letters")
+	// Validate facility code, or the alias users may know it by
+	if _, err := validation.ValidateFacilityCode(params.FacilityCode); err != nil {
+		alias, aliasErr := validation.ValidateFacilityAlias(params.FacilityCode)
+		if aliasErr != nil || alias == "" {
+			return err
+		}
 	}
 
 	// Validate initials (2 letters)
@@ -472,6 +512,11 @@ func (h *Handler) HandleSetPassword(c echo.Context) error {
 		Str("token", req.Token).
 		Msg("Form data bound successfully")
 
+	// Limit guesses against a single token
+	if result, ok := h.allowRequest(c, ratelimit.SetPasswordAccount, req.Token); !ok {
+		return response.TooManyRequests(c, result.RetryAfter)
+	}
+
 	// Validate passwords match and meet requirements
 	if err := validatePasswords(req.Password, req.ConfirmPassword); err != nil {
 		logger.Debug().
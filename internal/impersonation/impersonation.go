@@ -0,0 +1,23 @@
+// internal/impersonation/impersonation.go
+package impersonation
+
+import "context"
+
+// Banner describes an active impersonation for display in layouts
+type Banner struct {
+	UserName         string // The user being viewed as
+	ImpersonatorName string // The super user doing the viewing
+}
+
+type ctxKey struct{}
+
+// WithBanner returns a copy of ctx carrying the banner for templates
+func WithBanner(ctx context.Context, banner Banner) context.Context {
+	return context.WithValue(ctx, ctxKey{}, banner)
+}
+
+// BannerFrom returns the banner carried by ctx, if impersonating
+func BannerFrom(ctx context.Context) (Banner, bool) {
+	banner, ok := ctx.Value(ctxKey{}).(Banner)
+	return banner, ok
+}
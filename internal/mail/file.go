@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// FileTransport writes each email to a file instead of sending it, for
+// development. Messages can be opened in any mail client.
+type FileTransport struct {
+	dir    string // Empty to write to out
+	out    io.Writer
+	mu     sync.Mutex
+	logger zerolog.Logger
+}
+
+// NewFileTransport writes messages as .eml files in dir, creating it if
+// needed. An empty dir or "-" writes them to stdout.
+func NewFileTransport(dir string, logger zerolog.Logger) (*FileTransport, error) {
+	if dir == "-" {
+		dir = ""
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating mail directory: %w", err)
+		}
+	}
+
+	logger.Debug().Str("dir", dir).Msg("Initializing file transport")
+
+	return &FileTransport{dir: dir, out: os.Stdout, logger: logger}, nil
+}
+
+// Send writes the message to a new file, or to stdout
+func (t *FileTransport) Send(ctx context.Context, email Email) error {
+	now := time.Now()
+	msg, err := buildMessage(email, now)
+	if err != nil {
+		return err
+	}
+
+	if t.dir == "" {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, err := fmt.Fprintf(t.out, "----- mail to %s -----\n%s\n----- end of mail -----\n", email.To, msg); err != nil {
+			return fmt.Errorf("writing message: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.CreateTemp(t.dir, now.Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("creating message file: %w", err)
+	}
+	if _, err := f.Write(msg); err != nil {
+		f.Close()
+		return fmt.Errorf("writing message file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing message file: %w", err)
+	}
+
+	t.logger.Info().
+		Str("to", email.To).
+		Str("subject", email.Subject).
+		Str("file", filepath.Base(f.Name())).
+		Msg("Message written to file")
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -41,7 +42,17 @@ type EmailResponse struct {
 	Message     string    `json:"Message"`
 }
 
+// Send delivers an email through Postmark
+func (c *Client) Send(ctx context.Context, email Email) error {
+	_, err := c.sendEmail(ctx, email)
+	return err
+}
+
 func (c *Client) SendEmail(email Email) (*EmailResponse, error) {
+	return c.sendEmail(context.Background(), email)
+}
+
+func (c *Client) sendEmail(ctx context.Context, email Email) (*EmailResponse, error) {
 	if err := validateEmail(email); err != nil {
 		return nil, fmt.Errorf("validating email: %w", err)
 	}
@@ -50,6 +61,7 @@ func (c *Client) SendEmail(email Email) (*EmailResponse, error) {
 
 	var response EmailResponse
 	err := c.doRequest(requestParams{
+		ctx:       ctx,
 		method:    "POST",
 		path:      "email",
 		payload:   email,
@@ -0,0 +1,209 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMessage renders an email as an RFC 5322 message for transports that
+// deliver raw messages. Bcc recipients are left out of the headers.
+func buildMessage(email Email, now time.Time) ([]byte, error) {
+	if err := validateEmail(email); err != nil {
+		return nil, fmt.Errorf("validating email: %w", err)
+	}
+
+	from, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return nil, fmt.Errorf("parsing From address: %w", err)
+	}
+
+	// Line breaks in a value would start a new header
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	header("From", from.String())
+	header("To", email.To)
+	if email.Cc != "" {
+		header("Cc", email.Cc)
+	}
+	if email.ReplyTo != "" {
+		header("Reply-To", email.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+	for _, h := range email.Headers {
+		header(textproto.CanonicalMIMEHeaderKey(h.Name), mime.QEncoding.Encode("utf-8", h.Value))
+	}
+
+	bodyHeader, body, err := renderBody(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(email.Attachments) == 0 {
+		for _, name := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			header(name, bodyHeader.Get(name))
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", mixed.Boundary()))
+	buf.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, fmt.Errorf("creating body part: %w", err)
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, fmt.Errorf("writing body part: %w", err)
+	}
+
+	for _, a := range email.Attachments {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", a.ContentType)
+		h.Set("Content-Transfer-Encoding", "base64")
+		if a.ContentID != "" {
+			h.Set("Content-ID", "<"+a.ContentID+">")
+			h.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Name}))
+		} else {
+			h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		}
+		part, err := mixed.CreatePart(h)
+		if err != nil {
+			return nil, fmt.Errorf("creating attachment part: %w", err)
+		}
+		// Attachment content is already base64, as Postmark expects
+		if _, err := part.Write([]byte(wrapLines(a.Content, 76))); err != nil {
+			return nil, fmt.Errorf("writing attachment %s: %w", a.Name, err)
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, fmt.Errorf("closing message: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderBody encodes the text and HTML versions of an email, as
+// alternatives when there are both, returning the headers for the body
+func renderBody(email Email) (textproto.MIMEHeader, []byte, error) {
+	h := textproto.MIMEHeader{}
+	var buf bytes.Buffer
+
+	if email.HtmlBody == "" || email.TextBody == "" {
+		contentType, body := "text/plain", email.TextBody
+		if email.HtmlBody != "" {
+			contentType, body = "text/html", email.HtmlBody
+		}
+		h.Set("Content-Type", contentType+"; charset=utf-8")
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, nil, err
+		}
+		return h, buf.Bytes(), nil
+	}
+
+	alt := multipart.NewWriter(&buf)
+	h.Set("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", alt.Boundary()))
+	h.Set("Content-Transfer-Encoding", "7bit")
+
+	// Clients show the last alternative they understand, so HTML goes last
+	for _, p := range []struct{ contentType, body string }{
+		{"text/plain", email.TextBody},
+		{"text/html", email.HtmlBody},
+	} {
+		ph := textproto.MIMEHeader{}
+		ph.Set("Content-Type", p.contentType+"; charset=utf-8")
+		ph.Set("Content-Transfer-Encoding", "quoted-printable")
+		part, err := alt.CreatePart(ph)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating %s part: %w", p.contentType, err)
+		}
+		if err := writeQuotedPrintable(part, p.body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := alt.Close(); err != nil {
+		return nil, nil, fmt.Errorf("closing alternatives: %w", err)
+	}
+	return h, buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("encoding body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("encoding body: %w", err)
+	}
+	return nil
+}
+
+// recipients lists every address an email is delivered to
+func recipients(email Email) ([]string, error) {
+	var addrs []string
+	for _, field := range []string{email.To, email.Cc, email.Bcc} {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		list, err := mail.ParseAddressList(field)
+		if err != nil {
+			return nil, fmt.Errorf("parsing recipients %q: %w", field, err)
+		}
+		for _, a := range list {
+			addrs = append(addrs, a.Address)
+		}
+	}
+	return addrs, nil
+}
+
+// messageID returns a unique Message-ID at the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), domain)
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+// wrapLines breaks base64 content into lines no longer than n
+func wrapLines(s string, n int) string {
+	s = strings.Join(strings.Fields(s), "")
+	if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+		// Not base64; encode it so the part is still valid
+		s = base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	var b strings.Builder
+	for len(s) > n {
+		b.WriteString(s[:n])
+		b.WriteString("\r\n")
+		s = s[n:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
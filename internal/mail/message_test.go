@@ -0,0 +1,105 @@
+// internal/mail/message_test.go
+package mail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBuildMessage(t *testing.T) {
+	email := Email{
+		From:     "support@example.com",
+		To:       "Ada <ada@example.com>",
+		Bcc:      "audit@example.com",
+		Subject:  "Your schedule\r\nBcc: evil@example.com",
+		TextBody: "Hello Ada",
+		HtmlBody: "<p>Hello Ada</p>",
+	}
+
+	raw, err := buildMessage(email, time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildMessage: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("Bcc header = %q, want none", got)
+	}
+	if got := msg.Header.Get("To"); got != email.To {
+		t.Errorf("To header = %q, want %q", got, email.To)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	var types []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		types = append(types, strings.Split(part.Header.Get("Content-Type"), ";")[0])
+		if !strings.Contains(string(body), "Hello Ada") {
+			t.Errorf("part %s body = %q, want greeting", part.Header.Get("Content-Type"), body)
+		}
+	}
+	if strings.Join(types, ",") != "text/plain,text/html" {
+		t.Errorf("parts = %v, want text then html", types)
+	}
+
+	to, err := recipients(email)
+	if err != nil {
+		t.Fatalf("recipients: %v", err)
+	}
+	if strings.Join(to, ",") != "ada@example.com,audit@example.com" {
+		t.Errorf("recipients = %v", to)
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewFileTransport(dir, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewFileTransport: %v", err)
+	}
+
+	err = transport.Send(context.Background(), Email{
+		From:     "support@example.com",
+		To:       "ada@example.com",
+		Subject:  "Welcome",
+		TextBody: "Hello Ada",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %d files, want 1", len(files))
+	}
+	raw, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(raw), "Subject: Welcome") {
+		t.Errorf("message missing subject:\n%s", raw)
+	}
+}
@@ -2,6 +2,7 @@ package mail
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,6 +26,7 @@ type Client struct {
 }
 
 type requestParams struct {
+	ctx       context.Context // Defaults to context.Background
 	method    string
 	path      string
 	payload   interface{}
@@ -102,7 +104,12 @@ func (c *Client) doRequest(params requestParams, dst interface{}) error {
 			Msg("Request payload prepared")
 	}
 
-	req, err := http.NewRequest(params.method, fmt.Sprintf("%s/%s", c.baseURL, params.path), body)
+	ctx := params.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, params.method, fmt.Sprintf("%s/%s", c.baseURL, params.path), body)
 	if err != nil {
 		requestLog.Error().Err(err).Msg("Failed to create request")
 		return fmt.Errorf("creating request: %w", err)
@@ -0,0 +1,177 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// How an SMTP connection is secured
+const (
+	SMTPSecurityStartTLS = "starttls" // Upgrade a plain connection, required
+	SMTPSecurityTLS      = "tls"      // Connect over TLS, usually port 465
+	SMTPSecurityNone     = "none"     // Plain text, for local relays only
+)
+
+// SMTPConfig holds the settings for delivering mail through an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Authentication is skipped when empty
+	Password string
+	Security string // One of starttls, tls or none
+	Timeout  time.Duration
+}
+
+// SMTPTransport delivers mail through an SMTP server
+type SMTPTransport struct {
+	cfg    SMTPConfig
+	logger zerolog.Logger
+}
+
+// NewSMTPTransport creates a transport for the configured server
+func NewSMTPTransport(cfg SMTPConfig, logger zerolog.Logger) (*SMTPTransport, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp transport requires a host")
+	}
+	if cfg.Security == "" {
+		cfg.Security = SMTPSecurityStartTLS
+	}
+	switch cfg.Security {
+	case SMTPSecurityStartTLS, SMTPSecurityTLS, SMTPSecurityNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp security %q", cfg.Security)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.Security == SMTPSecurityTLS {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	logger.Debug().
+		Str("host", cfg.Host).
+		Int("port", cfg.Port).
+		Str("security", cfg.Security).
+		Bool("auth", cfg.Username != "").
+		Msg("Initializing SMTP transport")
+
+	return &SMTPTransport{cfg: cfg, logger: logger}, nil
+}
+
+// Send delivers an email over a new connection to the server
+func (t *SMTPTransport) Send(ctx context.Context, email Email) error {
+	msg, err := buildMessage(email, time.Now())
+	if err != nil {
+		return err
+	}
+	to, err := recipients(email)
+	if err != nil {
+		return err
+	}
+	from, err := recipients(Email{To: email.From})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	client, err := t.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Mail(from[0]); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", addr, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+	if err := client.Quit(); err != nil {
+		t.logger.Warn().Err(err).Msg("smtp QUIT failed after message was accepted")
+	}
+
+	t.logger.Info().
+		Int("recipients", len(to)).
+		Dur("duration", time.Since(start)).
+		Msg("Message delivered to smtp server")
+
+	return nil
+}
+
+// dial connects, secures and authenticates a connection to the server
+func (t *SMTPTransport) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(t.cfg.Host, strconv.Itoa(t.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: t.cfg.Host}
+
+	dialer := &net.Dialer{Timeout: t.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The whole exchange must finish within the timeout or the context
+	deadline := time.Now().Add(t.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if t.cfg.Security == SMTPSecurityTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if t.cfg.Security == SMTPSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+
+	if t.cfg.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost
+		auth := smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	return client, nil
+}
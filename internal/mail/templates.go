@@ -24,24 +24,23 @@ type TemplateData struct {
 
 // Mailer handles email template rendering and sending
 type Mailer struct {
-	client    *Client
+	transport Transport
 	templates *template.Template
 	fromEmail string
 	fromName  string
 }
 
-// NewMailer creates a new Mailer instance with template support
-func NewMailer(client *Client, fromEmail string, fromName string) (*Mailer, error) {
+// NewMailer creates a new Mailer instance with template support that sends
+// through the given transport
+func NewMailer(transport Transport, fromEmail string, fromName string) (*Mailer, error) {
 	// Parse all email templates
 	templates, err := template.ParseFS(templateFS, "templates/*.html", "templates/*.txt")
 	if err != nil {
 		return nil, fmt.Errorf("parsing email templates: %w", err)
 	}
 
-	client.logger.Debug().Str("fromEmail", fromEmail).Str("fromName", fromName)
-
 	return &Mailer{
-		client:    client,
+		transport: transport,
 		templates: templates,
 		fromEmail: fromEmail,
 		fromName:  fromName,
@@ -81,8 +80,7 @@ func (m *Mailer) SendTemplate(ctx context.Context, templateName string, to strin
 	}
 
 	// Send email
-	_, err := m.client.SendEmail(email)
-	if err != nil {
+	if err := m.transport.Send(ctx, email); err != nil {
 		return fmt.Errorf("sending template email: %w", err)
 	}
 
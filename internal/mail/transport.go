@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// Transport delivers a rendered email
+type Transport interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// Names of the transports that can be chosen in config
+const (
+	TransportPostmark = "postmark"
+	TransportSMTP     = "smtp"
+	TransportFile     = "file"
+)
+
+// TransportConfig selects a transport and holds the settings for each
+type TransportConfig struct {
+	Name string // One of postmark, smtp or file
+
+	// Postmark
+	PostmarkServerToken string
+
+	// SMTP
+	SMTP SMTPConfig
+
+	// File: messages are written to this directory, or to stdout when it
+	// is empty or "-"
+	FileDir string
+}
+
+// NewTransport creates the transport named in the config
+func NewTransport(cfg TransportConfig, logger zerolog.Logger) (Transport, error) {
+	switch cfg.Name {
+	case TransportPostmark:
+		if cfg.PostmarkServerToken == "" {
+			return nil, fmt.Errorf("postmark transport requires a server token")
+		}
+		return NewClient(cfg.PostmarkServerToken, logger.With().Str("component", "postmark_client").Logger()), nil
+	case TransportSMTP:
+		return NewSMTPTransport(cfg.SMTP, logger.With().Str("component", "smtp_transport").Logger())
+	case TransportFile:
+		return NewFileTransport(cfg.FileDir, logger.With().Str("component", "file_transport").Logger())
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Name)
+	}
+}
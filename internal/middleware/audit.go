@@ -0,0 +1,77 @@
+// internal/middleware/audit.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MarkAudited notes that the handler has recorded its own audit event, so
+// Audit does not add a generic one
+func MarkAudited(c echo.Context) {
+	c.Set(CtxKeyAudited, true)
+}
+
+// Audit records every successful write that its handler did not audit
+// itself, so no change goes unlogged even without before and after values
+func (m *Middleware) Audit() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+
+			err := next(c)
+
+			status := c.Response().Status
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+			if status >= http.StatusBadRequest {
+				return err
+			}
+			if audited, _ := c.Get(CtxKeyAudited).(bool); audited {
+				return err
+			}
+
+			auth, authErr := GetAuthContext(c)
+			if authErr != nil {
+				return err
+			}
+
+			event := entity.AuditEvent{
+				ActorID:    &auth.UserID,
+				EntityType: entity.AuditRequest,
+				Action:     c.Request().Method,
+				Method:     c.Request().Method,
+				Path:       c.Request().URL.Path,
+				RequestID:  c.Response().Header().Get(echo.HeaderXRequestID),
+			}
+			if auth.ImpersonatorID != 0 {
+				event.ImpersonatorID = &auth.ImpersonatorID
+			}
+
+			// Record even if the client has gone away
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if route, routeErr := GetRouteContext(c); routeErr == nil && route.FacilityCode != "" {
+				if facility, fErr := m.repos.Facility.GetByCode(ctx, route.FacilityCode); fErr == nil {
+					event.FacilityID = &facility.ID
+				}
+			}
+
+			if recordErr := m.repos.Audit.Record(ctx, event); recordErr != nil {
+				m.logger.Error().Err(recordErr).Str("path", event.Path).Msg("failed to record audit event")
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// internal/middleware/csrf.go
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/DukeRupert/haven/internal/csrf"
+	"github.com/DukeRupert/haven/internal/response"
+	"github.com/DukeRupert/haven/internal/store"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// CSRF binds a token to the session and rejects state-changing requests
+// that do not send it back. The token is placed in the request context so
+// layouts can hand it to HTMX.
+func (m *Middleware) CSRF() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := m.logger.With().
+				Str("path", c.Path()).
+				Str("method", c.Request().Method).
+				Logger()
+
+			sess, err := session.Get(store.DefaultSessionName, c)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get session")
+				return response.System(c)
+			}
+
+			token, _ := sess.Values[store.SessionKeyCSRFToken].(string)
+			if token == "" {
+				token, err = csrf.Generate()
+				if err != nil {
+					logger.Error().Err(err).Msg("failed to generate csrf token")
+					return response.System(c)
+				}
+				sess.Values[store.SessionKeyCSRFToken] = token
+				if err := sess.Save(c.Request(), c.Response()); err != nil {
+					logger.Error().Err(err).Msg("failed to save csrf token")
+					return response.System(c)
+				}
+			}
+
+			c.SetRequest(c.Request().WithContext(csrf.WithToken(c.Request().Context(), token)))
+
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+
+			submitted := c.Request().Header.Get(csrf.HeaderName)
+			if submitted == "" {
+				submitted = c.FormValue(csrf.FormField)
+			}
+			if !csrf.Valid(token, submitted) {
+				logger.Warn().
+					Str("ip", c.RealIP()).
+					Bool("missing", submitted == "").
+					Msg("csrf token rejected")
+				return response.Error(c, http.StatusForbidden, "Request Blocked",
+					[]string{"Your session may have expired. Please refresh the page and try again."})
+			}
+
+			return next(c)
+		}
+	}
+}
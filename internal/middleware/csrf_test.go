@@ -0,0 +1,92 @@
+// internal/middleware/csrf_test.go
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DukeRupert/haven/internal/csrf"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+func TestCSRF(t *testing.T) {
+	m := &Middleware{logger: zerolog.Nop()}
+	e := echo.New()
+	e.Use(session.Middleware(sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))))
+
+	var seen string
+	reached := false
+	handler := func(c echo.Context) error {
+		seen = csrf.Token(c.Request().Context())
+		reached = true
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/form", handler, m.CSRF())
+	e.POST("/form", handler, m.CSRF())
+
+	// A first GET issues the token and the session cookie carrying it
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/form", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	token := seen
+	if token == "" {
+		t.Fatal("GET did not put a token in the request context")
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("GET did not save the token in the session")
+	}
+
+	tests := []struct {
+		name   string
+		cookie bool
+		header string
+		form   string
+		want   bool
+	}{
+		{"no session", false, token, "", false},
+		{"missing token", true, "", "", false},
+		{"wrong header", true, "not-the-token", "", false},
+		{"wrong form field", true, "", "not-the-token", false},
+		{"header token", true, token, "", true},
+		{"form token", true, "", token, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.form != "" {
+				form.Set(csrf.FormField, tt.form)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			if tt.header != "" {
+				req.Header.Set(csrf.HeaderName, tt.header)
+			}
+			if tt.cookie {
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+			}
+
+			reached = false
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if reached != tt.want {
+				t.Errorf("POST reached handler = %v, want %v", reached, tt.want)
+			}
+			if blocked := strings.Contains(rec.Body.String(), "Request Blocked"); blocked == tt.want {
+				t.Errorf("POST blocked = %v, want %v", blocked, !tt.want)
+			}
+		})
+	}
+}
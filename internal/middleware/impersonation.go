@@ -0,0 +1,87 @@
+// internal/middleware/impersonation.go
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AuditImpersonation records every write made while a super user is viewing
+// the app as another user, with both identities
+func (m *Middleware) AuditImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth, err := GetAuthContext(c)
+			if err != nil || !auth.IsImpersonating() {
+				return next(c)
+			}
+
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+
+			err = next(c)
+
+			status := c.Response().Status
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+
+			event := entity.ImpersonationEvent{
+				ImpersonatorID: auth.ImpersonatorID,
+				UserID:         auth.UserID,
+				Action:         entity.ImpersonationWrite,
+				Method:         c.Request().Method,
+				Path:           c.Request().URL.Path,
+				Status:         status,
+			}
+
+			m.logger.Info().
+				Int("impersonator_id", event.ImpersonatorID).
+				Int("user_id", event.UserID).
+				Str("method", event.Method).
+				Str("path", event.Path).
+				Int("status", event.Status).
+				Msg("write while impersonating")
+
+			// Record even if the client has gone away
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if recordErr := m.repos.Impersonation.Record(ctx, event); recordErr != nil {
+				m.logger.Error().Err(recordErr).Msg("failed to record impersonation event")
+			}
+
+			return err
+		}
+	}
+}
+
+// BlockImpersonation rejects requests that would change a user's password,
+// email, second factor or sessions while a super user is viewing the app as
+// them. Impersonation is for seeing what the user sees, not taking over
+// their account.
+func (m *Middleware) BlockImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth, err := GetAuthContext(c)
+			if err != nil || !auth.IsImpersonating() {
+				return next(c)
+			}
+
+			m.logger.Warn().
+				Int("impersonator_id", auth.ImpersonatorID).
+				Int("user_id", auth.UserID).
+				Str("method", c.Request().Method).
+				Str("path", c.Request().URL.Path).
+				Msg("credential change blocked while impersonating")
+
+			return echo.NewHTTPError(http.StatusForbidden, "not available while impersonating")
+		}
+	}
+}
@@ -5,15 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/DukeRupert/haven/internal/impersonation"
 	"github.com/DukeRupert/haven/internal/model/dto"
 	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/DukeRupert/haven/internal/ratelimit"
 	"github.com/DukeRupert/haven/internal/repository"
 	"github.com/DukeRupert/haven/internal/repository/facility"
 	"github.com/DukeRupert/haven/internal/store"
+	"github.com/DukeRupert/haven/internal/switcher"
+	"github.com/DukeRupert/haven/internal/validation"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
@@ -39,23 +45,16 @@ const (
 
 // CtxKey define context value keys
 const (
-	CtxKeyAuth  = "auth"
-	CtxKeyRoute = "routeCtx"
-)
-
-// RoleLevel represents the hierarchy level of a role
-type RoleLevel int
-
-const (
-	UserLevel  RoleLevel = 1
-	AdminLevel RoleLevel = 2
-	SuperLevel RoleLevel = 3
+	CtxKeyAuth    = "auth"
+	CtxKeyRoute   = "routeCtx"
+	CtxKeyAudited = "audited"
 )
 
 // Middleware holds all middleware configuration
 type Middleware struct {
-	repos  *repository.Repositories
-	logger zerolog.Logger
+	repos   *repository.Repositories
+	limiter *ratelimit.Limiter
+	logger  zerolog.Logger
 }
 
 // Config holds middleware configuration
@@ -67,8 +66,9 @@ type Config struct {
 // NewMiddleware creates a new middleware instance
 func NewMiddleware(cfg Config) *Middleware {
 	return &Middleware{
-		repos:  cfg.Repos,
-		logger: cfg.Logger.With().Str("component", "middleware").Logger(),
+		repos:   cfg.Repos,
+		limiter: ratelimit.New(cfg.Repos.RateLimit),
+		logger:  cfg.Logger.With().Str("component", "middleware").Logger(),
 	}
 }
 
@@ -88,41 +88,92 @@ func (m *Middleware) Auth() echo.MiddlewareFunc {
 			}
 
 			// Get user and facility data
-			user, facility, err := m.getUserAndFacility(c.Request().Context(), sess, logger)
+			user, home, err := m.getUserAndFacility(c.Request().Context(), sess, logger)
 			if err != nil {
 				return err
 			}
 
+			// Deactivating a user ends their sessions
+			if user.IsDeactivated() {
+				logger.Info().
+					Int("user_id", user.ID).
+					Msg("session rejected for deactivated user")
+				return redirectToLogin(c)
+			}
+
+			memberships, err := m.repos.Membership.ListByUser(c.Request().Context(), user.ID)
+			if err != nil {
+				logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to fetch memberships")
+				return echo.NewHTTPError(http.StatusInternalServerError, "database error")
+			}
+
+			// Archiving a facility ends its users' sessions unless they
+			// work somewhere else
+			facility, role, err := m.activeFacility(c.Request().Context(), sess, user, home, memberships)
+			if err != nil {
+				logger.Error().Err(err).Int("user_id", user.ID).Msg("failed to fetch active facility")
+				return echo.NewHTTPError(http.StatusInternalServerError, "database error")
+			}
+			if facility == nil {
+				logger.Info().
+					Int("user_id", user.ID).
+					Msg("session rejected with no open facility")
+				return redirectToLogin(c)
+			}
+
 			// Update session with fresh data
 			if err := m.updateSession(c, sess, user, facility); err != nil {
 				return err
 			}
 
+			// Record where the session was last used so users can review it
+			if err := m.repos.Session.Touch(c.Request().Context(), sess.ID, c.RealIP(), c.Request().UserAgent()); err != nil {
+				logger.Error().Err(err).Msg("failed to record session activity")
+			}
+
 			// Create the data provider
 			provider := &authDataProvider{
 				repos:      m.repos,
 				user:       user,
 				facility:   facility,
 				userID:     user.ID,
-				facilityID: user.FacilityID,
+				facilityID: facility.ID,
 			}
 
 			// Create and set auth context
 			authContext := &dto.AuthContext{
 				AuthContextData: dto.AuthContextData{
 					UserID:       user.ID,
-					Role:         user.Role,
+					Role:         role,
 					Initials:     user.Initials,
-					FacilityID:   user.FacilityID,
+					FacilityID:   facility.ID,
 					FacilityCode: facility.Code,
+					Memberships:  memberships,
 				},
 				Provider: provider,
 			}
+			setSwitcher(c, memberships, facility.Code)
+
+			// Keep the original identity while a super user views as this user
+			if impersonatorID, ok := sess.Values[store.SessionKeyImpersonatorID].(int); ok && impersonatorID != 0 {
+				impersonator, err := m.repos.User.GetByID(c.Request().Context(), impersonatorID)
+				if err != nil || impersonator.Role != types.UserRoleSuper {
+					logger.Warn().
+						Int("impersonator_id", impersonatorID).
+						Msg("impersonation no longer permitted")
+					return redirectToLogin(c)
+				}
+				authContext.ImpersonatorID = impersonator.ID
+				c.SetRequest(c.Request().WithContext(impersonation.WithBanner(c.Request().Context(), impersonation.Banner{
+					UserName:         user.FirstName + " " + user.LastName,
+					ImpersonatorName: impersonator.FirstName + " " + impersonator.LastName,
+				})))
+			}
 			c.Set("auth", authContext)
 
 			logger.Debug().
 				Int("user_id", user.ID).
-				Str("role", string(user.Role)).
+				Str("role", string(role)).
 				Str("facility_code", facility.Code).
 				Msg("authentication successful")
 
@@ -150,6 +201,23 @@ func (m *Middleware) RouteContext() echo.MiddlewareFunc {
 				facilityCode := c.Param("facility_code")
 				userInitials := c.Param("user_initials")
 
+				// Codes are stored upper case; RequireFacilityAccess rejects
+				// ones that are not valid
+				if code, err := validation.ValidateFacilityCode(facilityCode); err == nil {
+					facilityCode = string(code)
+				}
+
+				// Users may also be addressed by ID, which stays the same
+				// when their initials change
+				if id, err := strconv.Atoi(userInitials); err == nil && facilityCode != "" {
+					if initials, ok := m.initialsByID(c, id, facilityCode); ok {
+						if c.Request().Method == http.MethodGet && c.Request().Header.Get("HX-Request") == "" {
+							return c.Redirect(http.StatusFound, userPath(c.Request().URL, initials))
+						}
+						userInitials = initials
+					}
+				}
+
 				// Store the values even if empty
 				routeCtx.FacilityCode = facilityCode
 				routeCtx.UserInitials = userInitials
@@ -179,13 +247,99 @@ func (m *Middleware) RouteContext() echo.MiddlewareFunc {
 	}
 }
 
-// RequireRole creates middleware that checks if user has sufficient role access
-func (m *Middleware) RequireRole(minimumRole types.UserRole) echo.MiddlewareFunc {
+// initialsByID looks up the current initials of a user at a facility the
+// signed in user can access
+func (m *Middleware) initialsByID(c echo.Context, id int, facilityCode string) (string, bool) {
+	auth, err := GetAuthContext(c)
+	if err != nil {
+		return "", false
+	}
+	if auth.Role != types.UserRoleSuper {
+		if _, ok := auth.Membership(facilityCode); !ok {
+			return "", false
+		}
+	}
+
+	initials, err := m.repos.User.GetInitialsByID(c.Request().Context(), id, facilityCode)
+	if err != nil {
+		return "", false
+	}
+	return initials, true
+}
+
+// userPath replaces the user ID in a /app/:facility_code/:user_id path with
+// the user's initials, keeping the rest of the path and the query
+func userPath(u *url.URL, initials string) string {
+	parts := strings.SplitN(u.Path, "/", 5)
+	parts[3] = initials
+	path := strings.Join(parts, "/")
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// RequireTwoFactor redirects users to enroll in two-factor authentication
+// when their facility requires it. The profile page stays reachable so they
+// can complete enrollment.
+func (m *Middleware) RequireTwoFactor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := m.logger.With().
+				Str("path", c.Path()).
+				Logger()
+
+			auth, err := GetAuthContext(c)
+			if err != nil {
+				return err
+			}
+
+			// Enrollment routes must stay reachable
+			if c.Path() == "/app/profile" || strings.HasPrefix(c.Path(), "/app/profile/2fa") {
+				return next(c)
+			}
+
+			// A super user viewing as someone else cannot enroll for them
+			if auth.IsImpersonating() {
+				return next(c)
+			}
+
+			facility, err := auth.Provider.GetFacility()
+			if err != nil || facility == nil || !facility.RequireTwoFactor {
+				return next(c)
+			}
+
+			enabled, err := m.repos.TwoFactor.IsEnabled(c.Request().Context(), auth.UserID)
+			if err != nil {
+				logger.Error().Err(err).Int("user_id", auth.UserID).Msg("failed to check two-factor status")
+				return echo.NewHTTPError(http.StatusInternalServerError, "database error")
+			}
+			if enabled {
+				return next(c)
+			}
+
+			logger.Debug().
+				Int("user_id", auth.UserID).
+				Str("facility_code", facility.Code).
+				Msg("two-factor enrollment required")
+
+			if c.Request().Header.Get("HX-Request") == "true" {
+				c.Response().Header().Set("HX-Redirect", "/app/profile")
+				return c.NoContent(http.StatusOK)
+			}
+			return c.Redirect(http.StatusSeeOther, "/app/profile")
+		}
+	}
+}
+
+// RequirePermission creates middleware that checks the user holds a
+// permission at the facility they are working at
+func (m *Middleware) RequirePermission(permission types.Permission) echo.MiddlewareFunc {
     return func(next echo.HandlerFunc) echo.HandlerFunc {
         return func(c echo.Context) error {
             logger := m.logger.With().
                 Str("path", c.Path()).
-                Str("required_role", string(minimumRole)).
+                Str("required_permission", string(permission)).
                 Logger()
 
             // Get auth context
@@ -195,18 +349,17 @@ func (m *Middleware) RequireRole(minimumRole types.UserRole) echo.MiddlewareFunc
                 return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
             }
 
-            // Check if user has sufficient role
-            if !HasMinimumRole(auth.Role, minimumRole) {
+            if !auth.Can(permission) {
                 logger.Warn().
                     Str("user_role", string(auth.Role)).
-                    Str("required_role", string(minimumRole)).
-                    Msg("Insufficient role")
+                    Str("facility_code", auth.FacilityCode).
+                    Msg("Missing permission")
                 return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions")
             }
 
             logger.Debug().
                 Str("user_role", string(auth.Role)).
-                Msg("Role check passed")
+                Msg("Permission check passed")
 
             return next(c)
         }
@@ -229,11 +382,19 @@ func (m *Middleware) RequireFacilityAccess() echo.MiddlewareFunc {
             }
 
             // Get route parameter
-            facilityCode := c.Param("facility_code")
-            if facilityCode == "" {
+            if c.Param("facility_code") == "" {
                 logger.Error().Msg("No facility code in route")
                 return echo.NewHTTPError(http.StatusBadRequest, "facility code required")
             }
+            code, err := validation.ValidateFacilityCode(c.Param("facility_code"))
+            if err != nil {
+                logger.Debug().
+                    Err(err).
+                    Str("facility_code", c.Param("facility_code")).
+                    Msg("Invalid facility code in route")
+                return echo.NewHTTPError(http.StatusNotFound, "facility not found")
+            }
+            facilityCode := string(code)
 
             // Super users have access to all facilities
             if auth.Role == types.UserRoleSuper {
@@ -244,8 +405,9 @@ func (m *Middleware) RequireFacilityAccess() echo.MiddlewareFunc {
                 return next(c)
             }
 
-            // For admin and user roles, check if facility code matches
-            if auth.FacilityCode != facilityCode {
+            // For admin and user roles, require a membership at the facility
+            membership, ok := auth.Membership(facilityCode)
+            if !ok || membership.IsArchived() {
                 logger.Warn().
                     Str("user_role", string(auth.Role)).
                     Str("user_facility", auth.FacilityCode).
@@ -254,6 +416,16 @@ func (m *Middleware) RequireFacilityAccess() echo.MiddlewareFunc {
                 return echo.NewHTTPError(http.StatusForbidden, "insufficient facility permissions")
             }
 
+            // Later checks use the role held at this facility
+            auth.Role = membership.Role
+            auth.FacilityID = membership.FacilityID
+            auth.FacilityCode = membership.FacilityCode
+            if p, ok := auth.Provider.(*authDataProvider); ok && p.facilityID != membership.FacilityID {
+                p.facility = nil
+                p.facilityID = membership.FacilityID
+            }
+            setSwitcher(c, auth.Memberships, facilityCode)
+
             logger.Debug().
                 Str("user_role", string(auth.Role)).
                 Str("facility_code", facilityCode).
@@ -264,6 +436,44 @@ func (m *Middleware) RequireFacilityAccess() echo.MiddlewareFunc {
     }
 }
 
+// RequireAreaSupervisor allows users who can edit schedules, and area
+// supervisors for members of their own area, to manage the schedule of the
+// user in the route
+func (m *Middleware) RequireAreaSupervisor() echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(c echo.Context) error {
+            logger := m.logger.With().
+                Str("path", c.Path()).
+                Logger()
+
+            auth, err := GetAuthContext(c)
+            if err != nil {
+                logger.Error().Msg("No auth context found")
+                return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+            }
+
+            if auth.Can(types.PermScheduleEdit) {
+                return next(c)
+            }
+
+            requestedInitials := routeUserInitials(c)
+            if !m.supervisesMember(c, auth, requestedInitials) {
+                logger.Warn().
+                    Str("user_role", string(auth.Role)).
+                    Str("requested_initials", requestedInitials).
+                    Msg("Area supervisor check failed")
+                return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions")
+            }
+
+            logger.Debug().
+                Str("user_initials", auth.Initials).
+                Msg("Area supervisor access granted")
+
+            return next(c)
+        }
+    }
+}
+
 // RequireProfileAccess checks if user has permission to view the requested profile
 func (m *Middleware) RequireProfileAccess() echo.MiddlewareFunc {
     return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -279,20 +489,31 @@ func (m *Middleware) RequireProfileAccess() echo.MiddlewareFunc {
                 return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
             }
 
-            // Get requested user initials from route parameter
-            requestedInitials := c.Param("user_initials")
+            // Get requested user initials, resolved from the user's ID when
+            // the route addresses them by ID
+            requestedInitials := routeUserInitials(c)
             if requestedInitials == "" {
                 logger.Error().Msg("No user initials in route")
                 return echo.NewHTTPError(http.StatusBadRequest, "user initials required")
             }
 
-            // Super and Admin users have access to all profiles within their facility scope
-            // (facility scope is already checked by previous middleware)
-            if auth.Role == types.UserRoleSuper || auth.Role == types.UserRoleAdmin {
+            // Users who manage users or schedules have access to all profiles
+            // within their facility scope (facility scope is already checked
+            // by previous middleware)
+            if auth.Can(types.PermUsersManage) || auth.Can(types.PermScheduleEdit) {
                 logger.Debug().
                     Str("user_role", string(auth.Role)).
                     Str("requested_initials", requestedInitials).
-                    Msg("Access granted based on role")
+                    Msg("Access granted based on permission")
+                return next(c)
+            }
+
+            // Area supervisors have access to the members of their area
+            if auth.Initials != requestedInitials && m.supervisesMember(c, auth, requestedInitials) {
+                logger.Debug().
+                    Str("user_initials", auth.Initials).
+                    Str("requested_initials", requestedInitials).
+                    Msg("Access granted to area supervisor")
                 return next(c)
             }
 
@@ -347,27 +568,6 @@ func (p *authDataProvider) GetFacility() (*entity.Facility, error) {
 	return facility, nil
 }
 
-// HasMinimumRole checks if a role meets or exceeds the minimum required role
-func HasMinimumRole(current, minimum types.UserRole) bool {
-	roleValues := map[types.UserRole]RoleLevel{
-		types.UserRoleUser:  UserLevel,
-		types.UserRoleAdmin: AdminLevel,
-		types.UserRoleSuper: SuperLevel,
-	}
-
-	currentLevel, ok := roleValues[current]
-	if !ok {
-		return false
-	}
-
-	requiredLevel, ok := roleValues[minimum]
-	if !ok {
-		return false
-	}
-
-	return currentLevel >= requiredLevel
-}
-
 // Authenticate verifies user credentials
 func (m *Middleware) Authenticate(ctx context.Context, email, password string) (*entity.User, error) {
 	log := m.logger.With().Str("method", "Authenticate").Logger()
@@ -463,6 +663,79 @@ func (m *Middleware) getUserAndFacility(ctx context.Context, sess *sessions.Sess
 	return user, f, nil
 }
 
+// activeFacility picks the facility a user is working at: the one chosen in
+// the facility switcher, otherwise their home facility, skipping archived
+// facilities. Super users always start at their home facility. The returned
+// role is the one held at that facility; a nil facility means none is open.
+func (m *Middleware) activeFacility(ctx context.Context, sess *sessions.Session, user *entity.User, home *entity.Facility, memberships []entity.FacilityMembership) (*entity.Facility, types.UserRole, error) {
+	if user.Role == types.UserRoleSuper {
+		return home, user.Role, nil
+	}
+
+	var chosen *entity.FacilityMembership
+	selected, _ := sess.Values[SessionKeyFacilityID].(int)
+	for i := range memberships {
+		if memberships[i].IsArchived() {
+			continue
+		}
+		if chosen == nil || memberships[i].FacilityID == selected {
+			chosen = &memberships[i]
+		}
+	}
+	if chosen == nil {
+		return nil, "", nil
+	}
+
+	if home != nil && home.ID == chosen.FacilityID {
+		return home, chosen.Role, nil
+	}
+	f, err := m.repos.Facility.GetByID(ctx, chosen.FacilityID)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, chosen.Role, nil
+}
+
+// routeUserInitials returns the initials of the user in the route. Routes
+// may address users by ID, which RouteContext resolves to their initials.
+func routeUserInitials(c echo.Context) string {
+	if routeCtx, err := GetRouteContext(c); err == nil && routeCtx.UserInitials != "" {
+		return routeCtx.UserInitials
+	}
+	return c.Param("user_initials")
+}
+
+// supervisesMember reports whether the user supervises the area of the member
+// with the given initials at the facility being viewed
+func (m *Middleware) supervisesMember(c echo.Context, auth *dto.AuthContext, initials string) bool {
+	if auth.SupervisedArea() == nil || initials == "" {
+		return false
+	}
+	member, err := m.repos.Membership.GetByInitials(c.Request().Context(), auth.FacilityID, initials)
+	if err != nil || !auth.Supervises(member.AreaID) {
+		return false
+	}
+	auth.SupervisedUserID = member.UserID
+	return true
+}
+
+// setSwitcher offers the user's other open facilities in the navigation
+func setSwitcher(c echo.Context, memberships []entity.FacilityMembership, current string) {
+	var facilities []switcher.Facility
+	for _, m := range memberships {
+		if !m.IsArchived() {
+			facilities = append(facilities, switcher.Facility{Code: m.FacilityCode, Name: m.FacilityName})
+		}
+	}
+	if len(facilities) < 2 {
+		return
+	}
+	c.SetRequest(c.Request().WithContext(switcher.WithSwitcher(c.Request().Context(), switcher.Switcher{
+		Current:    current,
+		Facilities: facilities,
+	})))
+}
+
 func (m *Middleware) updateSession(c echo.Context, sess *sessions.Session, user *entity.User, facility *entity.Facility) error {
 	sess.Values[SessionKeyUserID] = user.ID
 	sess.Values[SessionKeyRole] = user.Role
@@ -0,0 +1,32 @@
+// internal/middleware/middleware_test.go
+package middleware
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestUserPath(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"user page", "/app/KHLN/42", "/app/KHLN/JD"},
+		{"nested page", "/app/KHLN/42/schedule", "/app/KHLN/JD/schedule"},
+		{"deeply nested", "/app/KHLN/42/schedule/7/update", "/app/KHLN/JD/schedule/7/update"},
+		{"with query", "/app/KHLN/42?month=2025-02", "/app/KHLN/JD?month=2025-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("parsing url: %v", err)
+			}
+			if got := userPath(u, "JD"); got != tt.want {
+				t.Errorf("userPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// internal/middleware/ratelimit.go
+package middleware
+
+import (
+	"github.com/DukeRupert/haven/internal/ratelimit"
+	"github.com/DukeRupert/haven/internal/response"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimit rejects requests from a client IP that exceed the policy
+func (m *Middleware) RateLimit(policy ratelimit.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			logger := m.logger.With().
+				Str("path", c.Path()).
+				Str("policy", policy.Name).
+				Logger()
+
+			ip := c.RealIP()
+			result, err := m.limiter.Allow(c.Request().Context(), policy, ip)
+			if err != nil {
+				// Fail open so a database hiccup does not lock everyone out
+				logger.Error().Err(err).Msg("rate limit check failed")
+				return next(c)
+			}
+
+			if !result.Allowed {
+				logger.Warn().
+					Str("ip", ip).
+					Dur("retry_after", result.RetryAfter).
+					Msg("rate limit exceeded")
+				return response.TooManyRequests(c, result.RetryAfter)
+			}
+
+			return next(c)
+		}
+	}
+}
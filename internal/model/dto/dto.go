@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
 	"github.com/DukeRupert/haven/internal/model/types"
 )
 
@@ -21,6 +22,87 @@ type AuthContextData struct {
 	Initials     string
 	FacilityID   int
 	FacilityCode string
+
+	// ImpersonatorID is the super user viewing the app as this user, or 0
+	ImpersonatorID int
+
+	// Memberships are the facilities the user works at and their role at each
+	Memberships []entity.FacilityMembership
+
+	// SupervisedUserID is the user in the route when access to them was
+	// granted because they belong to the area this user supervises
+	SupervisedUserID int
+}
+
+// Membership returns the user's membership at the facility with the given code
+func (a AuthContextData) Membership(facilityCode string) (entity.FacilityMembership, bool) {
+	for _, m := range a.Memberships {
+		if m.FacilityCode == facilityCode {
+			return m, true
+		}
+	}
+	return entity.FacilityMembership{}, false
+}
+
+// Can reports whether the user holds the permission at the facility they are
+// working at. Super users hold every permission.
+func (a AuthContextData) Can(p types.Permission) bool {
+	return a.CanAt(a.FacilityCode, p)
+}
+
+// CanAt reports whether the user holds the permission at the facility with
+// the given code
+func (a AuthContextData) CanAt(facilityCode string, p types.Permission) bool {
+	if a.Role == types.UserRoleSuper {
+		return true
+	}
+	m, ok := a.Membership(facilityCode)
+	return ok && !m.IsArchived() && m.Can(p)
+}
+
+// CanGrant reports whether the user holds every one of the permissions at
+// the facility they are working at
+func (a AuthContextData) CanGrant(perms []types.Permission) bool {
+	return a.CanGrantAt(a.FacilityCode, perms)
+}
+
+// CanGrantAt reports whether the user holds every one of the permissions at
+// the facility with the given code. Users may only hand out permissions they
+// hold themselves.
+func (a AuthContextData) CanGrantAt(facilityCode string, perms []types.Permission) bool {
+	for _, p := range perms {
+		if !a.CanAt(facilityCode, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupervisedArea returns the area the user supervises at the facility they
+// are working at, or nil
+func (a AuthContextData) SupervisedArea() *int {
+	m, ok := a.Membership(a.FacilityCode)
+	if !ok || !m.AreaSupervisor {
+		return nil
+	}
+	return m.AreaID
+}
+
+// Supervises reports whether the user supervises the given area
+func (a AuthContextData) Supervises(areaID *int) bool {
+	supervised := a.SupervisedArea()
+	return supervised != nil && areaID != nil && *supervised == *areaID
+}
+
+// SupervisesUser reports whether access to the user was granted as their
+// area supervisor
+func (a AuthContextData) SupervisesUser(userID int) bool {
+	return a.SupervisedUserID != 0 && a.SupervisedUserID == userID
+}
+
+// IsImpersonating reports whether a super user is viewing as this user
+func (a AuthContextData) IsImpersonating() bool {
+	return a.ImpersonatorID != 0
 }
 
 type AuthDataProvider interface {
@@ -55,8 +137,8 @@ type Route struct {
 	Path          string
 	Name          string
 	Icon          string
-	MinRole       types.UserRole // Minimum role required
-	NeedsFacility bool           // Whether route requires facility context
+	Permission    types.Permission // Permission required, empty for everyone
+	NeedsFacility bool             // Whether route requires facility context
 }
 
 type FacilityPageProps struct {
@@ -66,6 +148,7 @@ type FacilityPageProps struct {
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
 	Facilities []entity.Facility
+	Onboarding []entity.FacilityOnboarding // Setups that have not been finished
 }
 
 type UsersPageProps struct {
@@ -75,6 +158,117 @@ type UsersPageProps struct {
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
 	Users 		[]entity.User
+
+	RequireTwoFactor bool
+
+	// Areas and qualifications that can be filtered by
+	Areas          []entity.Area
+	Qualifications []entity.Qualification
+
+	// Search, filters, sort and page shown, and how many users match
+	Filter params.ListUsersParams
+	Total  int
+}
+
+type UserSearchPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Query       string
+	Users       []entity.User
+}
+
+type AuditPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+
+	// BasePath is the log being shown, of one facility or of them all
+	BasePath     string
+	ShowFacility bool
+
+	// Events on the current page, the filters applied and how many match
+	Events []entity.AuditEvent
+	Filter params.ListAuditParams
+	Total  int
+}
+
+type AreasPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Areas       []entity.Area
+}
+
+type RolesPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Roles       []entity.FacilityRole
+}
+
+type QualificationsPageProps struct {
+	Title          string
+	Description    string
+	NavItems       []NavItem
+	AuthCtx        AuthContext
+	RouteCtx       RouteContext
+	Qualifications []entity.Qualification
+}
+
+type LockoutsPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Lockouts    []entity.AccountLockout
+}
+
+type DeactivatedUsersPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Users       []entity.User
+}
+
+type InvitationsPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Invitations []entity.Invitation
+}
+
+type OnboardingPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Onboarding  entity.FacilityOnboarding
+	Settings    entity.FacilitySettings
+}
+
+type FacilitySettingsPageProps struct {
+	Title       string
+	Description string
+	NavItems    []NavItem
+	AuthCtx     AuthContext
+	RouteCtx    RouteContext
+	Facility    entity.Facility
+	Settings    entity.FacilitySettings
 }
 
 type ProfilePageProps struct {
@@ -84,6 +278,24 @@ type ProfilePageProps struct {
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
 	Details     *UserDetails
+
+	TwoFactorEnabled bool
+
+	// Only populated when viewing your own profile
+	Sessions         []entity.HTTPSession
+	CurrentSessionID string
+
+	// Only populated for admins viewing a member of the facility
+	Areas      []entity.Area
+	Membership *entity.FacilityMembership
+
+	// The facility's qualification catalog and those the member holds
+	Qualifications []entity.Qualification
+	Grants         []entity.UserQualification
+	Today          time.Time
+
+	// The facility's roles, one of which may be assigned to the member
+	Roles []entity.FacilityRole
 }
 
 type CalendarPageProps struct {
@@ -93,10 +305,17 @@ type CalendarPageProps struct {
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
 	Calendar    CalendarProps
+	Settings    entity.FacilitySettings
+	Areas       []entity.Area
+	Qualifications []entity.Qualification
 }
 
 type CalendarProps struct {
 	CurrentMonth   time.Time
+	Today          time.Time    // The facility's current date
+	WeekStart      time.Weekday // First column of the calendar
+	AreaID         *int         // Area the calendar is filtered to, nil for everyone
+	QualificationID *int        // Qualification held on each day shown, nil for everyone
 	ProtectedDates []entity.PD
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
@@ -105,6 +324,8 @@ type CalendarProps struct {
 type CalendarDayProps struct {
 	Date           time.Time
 	CurrentMonth   time.Time
+	Today          time.Time
+	WeekStart      time.Weekday
 	ProtectedDates []entity.PD
 	AuthCtx     AuthContext
 	RouteCtx    RouteContext
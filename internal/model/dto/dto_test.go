@@ -0,0 +1,112 @@
+// internal/model/dto/dto_test.go
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+func TestCanAt(t *testing.T) {
+	archived := time.Now()
+	scheduler := AuthContextData{
+		Role:         types.UserRoleUser,
+		FacilityCode: "KHLN",
+		Memberships: []entity.FacilityMembership{
+			{
+				FacilityCode:      "KHLN",
+				Role:              types.UserRoleUser,
+				FacilityRolePerms: []types.Permission{types.PermScheduleEdit, types.PermPublicationUpdate},
+			},
+			{FacilityCode: "KBZN", Role: types.UserRoleAdmin},
+			{FacilityCode: "KGPI", Role: types.UserRoleAdmin, FacilityArchivedAt: &archived},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		auth         AuthContextData
+		facilityCode string
+		permission   types.Permission
+		expected     bool
+	}{
+		{
+			name:         "granted by facility role",
+			auth:         scheduler,
+			facilityCode: "KHLN",
+			permission:   types.PermPublicationUpdate,
+			expected:     true,
+		},
+		{
+			name:         "not granted by facility role",
+			auth:         scheduler,
+			facilityCode: "KHLN",
+			permission:   types.PermUsersManage,
+			expected:     false,
+		},
+		{
+			name:         "admin at another facility",
+			auth:         scheduler,
+			facilityCode: "KBZN",
+			permission:   types.PermUsersManage,
+			expected:     true,
+		},
+		{
+			name:         "archived facility",
+			auth:         scheduler,
+			facilityCode: "KGPI",
+			permission:   types.PermUsersManage,
+			expected:     false,
+		},
+		{
+			name:         "not a member",
+			auth:         scheduler,
+			facilityCode: "KMSO",
+			permission:   types.PermScheduleEdit,
+			expected:     false,
+		},
+		{
+			name:         "super user without membership",
+			auth:         AuthContextData{Role: types.UserRoleSuper},
+			facilityCode: "KMSO",
+			permission:   types.PermUsersPurge,
+			expected:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.auth.CanAt(tt.facilityCode, tt.permission); got != tt.expected {
+				t.Errorf("CanAt(%s, %s) = %v, want %v", tt.facilityCode, tt.permission, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCanGrantAt(t *testing.T) {
+	auth := AuthContextData{
+		Role: types.UserRoleUser,
+		Memberships: []entity.FacilityMembership{
+			{
+				FacilityCode:      "KHLN",
+				Role:              types.UserRoleUser,
+				FacilityRolePerms: []types.Permission{types.PermUsersManage},
+			},
+		},
+	}
+
+	if !auth.CanGrantAt("KHLN", nil) {
+		t.Error("expected no permissions to be grantable")
+	}
+	if !auth.CanGrantAt("KHLN", types.UserRoleUser.Permissions()) {
+		t.Error("expected the user role to be grantable")
+	}
+	if auth.CanGrantAt("KHLN", types.UserRoleAdmin.Permissions()) {
+		t.Error("expected the admin role not to be grantable by a user manager")
+	}
+	if auth.CanGrantAt("KHLN", []types.Permission{types.PermUsersManage, types.PermRolesManage}) {
+		t.Error("expected permissions the user lacks not to be grantable")
+	}
+}
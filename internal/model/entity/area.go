@@ -0,0 +1,16 @@
+// internal/model/entity/area.go
+package entity
+
+import "time"
+
+// Area is an area of specialization or crew within a facility
+type Area struct {
+	ID         int       `db:"id" json:"id"`
+	FacilityID int       `db:"facility_id" json:"facility_id"`
+	Name       string    `db:"name" json:"name"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+
+	// Number of members assigned to the area
+	MemberCount int `db:"member_count" json:"member_count"`
+}
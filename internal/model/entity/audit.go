@@ -0,0 +1,103 @@
+// internal/model/entity/audit.go
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Kinds of entity recorded in the audit trail
+const (
+	AuditFacility      = "facility"
+	AuditSettings      = "facility_settings"
+	AuditUser          = "user"
+	AuditMembership    = "membership"
+	AuditSchedule      = "schedule"
+	AuditAvailability  = "availability"
+	AuditPublication   = "publication"
+	AuditArea          = "area"
+	AuditQualification = "qualification"
+	AuditGrant         = "qualification_grant"
+	AuditRole          = "role"
+	AuditTransfer      = "transfer"
+	AuditSSOProvider   = "sso_provider"
+	AuditRequest       = "request" // Writes with no more specific entry
+)
+
+// AuditEntityTypes lists the kinds of entity in the order they are offered
+// as filters
+var AuditEntityTypes = []string{
+	AuditAvailability,
+	AuditSchedule,
+	AuditPublication,
+	AuditUser,
+	AuditMembership,
+	AuditRole,
+	AuditArea,
+	AuditQualification,
+	AuditGrant,
+	AuditTransfer,
+	AuditSettings,
+	AuditSSOProvider,
+	AuditFacility,
+	AuditRequest,
+}
+
+// Audit actions
+const (
+	AuditCreate     = "create"
+	AuditUpdate     = "update"
+	AuditDelete     = "delete"
+	AuditDeactivate = "deactivate"
+	AuditReactivate = "reactivate"
+	AuditPublish    = "publish"
+	AuditToggle     = "toggle"
+	AuditGrantAdd   = "grant"
+	AuditRevoke     = "revoke"
+	AuditArchive    = "archive"
+	AuditRestore    = "restore"
+)
+
+// AuditActions lists the actions offered as filters. Writes recorded without
+// a more specific entry use the request method as their action.
+var AuditActions = []string{
+	AuditCreate,
+	AuditUpdate,
+	AuditDelete,
+	AuditDeactivate,
+	AuditReactivate,
+	AuditPublish,
+	AuditToggle,
+	AuditGrantAdd,
+	AuditRevoke,
+	AuditArchive,
+	AuditRestore,
+	"POST",
+	"PUT",
+	"PATCH",
+	"DELETE",
+}
+
+// AuditEvent records a change to the app's state: who made it, to what, and
+// the values before and after
+type AuditEvent struct {
+	ID             int64           `db:"id" json:"id"`
+	ActorID        *int            `db:"actor_id" json:"actor_id,omitempty"`
+	ActorEmail     string          `db:"actor_email" json:"actor_email"`
+	ImpersonatorID *int            `db:"impersonator_id" json:"impersonator_id,omitempty"`
+	FacilityID     *int            `db:"facility_id" json:"facility_id,omitempty"`
+	EntityType     string          `db:"entity_type" json:"entity_type"`
+	EntityID       string          `db:"entity_id" json:"entity_id"`
+	Action         string          `db:"action" json:"action"`
+	Before         json.RawMessage `db:"before" json:"before,omitempty"`
+	After          json.RawMessage `db:"after" json:"after,omitempty"`
+	Method         string          `db:"method" json:"method"`
+	Path           string          `db:"path" json:"path"`
+	RequestID      string          `db:"request_id" json:"request_id"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+
+	// Loaded for display
+	ActorInitials     string `db:"actor_initials" json:"actor_initials,omitempty"`
+	ImpersonatorEmail string `db:"impersonator_email" json:"impersonator_email,omitempty"`
+	FacilityCode      string `db:"facility_code" json:"facility_code,omitempty"`
+}
@@ -8,4 +8,90 @@ type Facility struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Name      string    `json:"name"`
 	Code      string    `json:"code"`
-}
\ No newline at end of file
+	Alias     string    `json:"alias,omitempty"` // Optional display alias
+
+	RequireTwoFactor bool       `json:"require_two_factor"`
+	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
+}
+
+// Label returns the facility's code with its alias, if it has one
+func (f Facility) Label() string {
+	if f.Alias == "" {
+		return f.Code
+	}
+	return f.Code + " (" + f.Alias + ")"
+}
+
+// IsArchived reports whether the facility has been archived
+func (f Facility) IsArchived() bool {
+	return f.ArchivedAt != nil
+}
+
+// PublicationPolicy controls how far ahead a facility's schedule is published
+type PublicationPolicy string
+
+const (
+	// PublicationManual publishes through the date an admin chooses
+	PublicationManual PublicationPolicy = "manual"
+	// PublicationRolling keeps the schedule published PublishWeeksAhead from today
+	PublicationRolling PublicationPolicy = "rolling"
+)
+
+// FacilitySettings holds per-facility scheduling defaults
+type FacilitySettings struct {
+	FacilityID           int          `db:"facility_id" json:"facility_id"`
+	DefaultFirstWeekday  time.Weekday `db:"default_first_weekday" json:"default_first_weekday"`
+	DefaultSecondWeekday time.Weekday `db:"default_second_weekday" json:"default_second_weekday"`
+	PublishWeeksAhead    int          `db:"publish_weeks_ahead" json:"publish_weeks_ahead"`
+	UpdatedAt            time.Time    `db:"updated_at" json:"updated_at"`
+
+	TimeZone                 string            `db:"time_zone" json:"time_zone"`
+	WeekStart                time.Weekday      `db:"week_start" json:"week_start"`
+	PublicationPolicy        PublicationPolicy `db:"publication_policy" json:"publication_policy"`
+	AvailabilityDeadlineDays int               `db:"availability_deadline_days" json:"availability_deadline_days"`
+}
+
+// DefaultFacilitySettings returns the settings used before a facility saves its own
+func DefaultFacilitySettings(facilityID int) FacilitySettings {
+	return FacilitySettings{
+		FacilityID:           facilityID,
+		DefaultFirstWeekday:  time.Saturday,
+		DefaultSecondWeekday: time.Sunday,
+		PublishWeeksAhead:    4,
+		TimeZone:             "UTC",
+		WeekStart:            time.Monday,
+		PublicationPolicy:    PublicationManual,
+	}
+}
+
+// Location returns the facility's time zone, falling back to UTC if the
+// stored zone cannot be loaded
+func (s FacilitySettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the facility's current date. Like DATE columns read from the
+// database, the result is midnight UTC so the two compare directly.
+func (s FacilitySettings) Today(now time.Time) time.Time {
+	local := now.In(s.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RollingPublishedThrough returns the last published date under the rolling policy
+func (s FacilitySettings) RollingPublishedThrough(today time.Time) time.Time {
+	return today.AddDate(0, 0, s.PublishWeeksAhead*7)
+}
+
+// AvailabilityLocked reports whether availability on date can no longer be
+// changed because of the facility's deadline or rolling publication window.
+// Manually published schedules are checked against the stored publication date.
+func (s FacilitySettings) AvailabilityLocked(date, today time.Time) bool {
+	if date.Before(today.AddDate(0, 0, s.AvailabilityDeadlineDays)) {
+		return true
+	}
+	return s.PublicationPolicy == PublicationRolling && !date.After(s.RollingPublishedThrough(today))
+}
@@ -0,0 +1,24 @@
+// internal/model/entity/impersonation.go
+package entity
+
+import "time"
+
+// Impersonation event actions
+const (
+	ImpersonationStart = "start"
+	ImpersonationStop  = "stop"
+	ImpersonationWrite = "write"
+)
+
+// ImpersonationEvent records an action taken while a super user views the
+// app as another user
+type ImpersonationEvent struct {
+	ID             int       `db:"id" json:"id"`
+	ImpersonatorID int       `db:"impersonator_id" json:"impersonator_id"`
+	UserID         int       `db:"user_id" json:"user_id"`
+	Action         string    `db:"action" json:"action"`
+	Method         string    `db:"method" json:"method"`
+	Path           string    `db:"path" json:"path"`
+	Status         int       `db:"status" json:"status"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,29 @@
+// internal/model/entity/invitation.go
+package entity
+
+import "time"
+
+// Invitation is a user who has been created but has not finished registering,
+// along with the most recent verification email sent to them
+type Invitation struct {
+	UserID     int        `db:"user_id" json:"user_id"`
+	FirstName  string     `db:"first_name" json:"first_name"`
+	LastName   string     `db:"last_name" json:"last_name"`
+	Initials   string     `db:"initials" json:"initials"`
+	Email      string     `db:"email" json:"email"`
+	InvitedAt  time.Time  `db:"invited_at" json:"invited_at"`
+	LastSentAt *time.Time `db:"last_sent_at" json:"last_sent_at,omitempty"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Used       bool       `db:"used" json:"used"`
+	RevokedAt  *time.Time `db:"invitation_revoked_at" json:"revoked_at,omitempty"`
+}
+
+// IsActive reports whether the most recent invitation link can still be used
+func (i Invitation) IsActive() bool {
+	return i.RevokedAt == nil && i.ExpiresAt != nil && !i.Used && time.Now().Before(*i.ExpiresAt)
+}
+
+// IsRevoked reports whether an admin revoked the invitation
+func (i Invitation) IsRevoked() bool {
+	return i.RevokedAt != nil
+}
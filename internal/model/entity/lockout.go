@@ -0,0 +1,27 @@
+// internal/model/entity/lockout.go
+package entity
+
+import "time"
+
+// AccountLockout records a temporary lock caused by repeated failed logins
+type AccountLockout struct {
+	ID             int        `db:"id" json:"id"`
+	UserID         int        `db:"user_id" json:"user_id"`
+	FacilityID     int        `db:"facility_id" json:"facility_id"`
+	IPAddress      string     `db:"ip_address" json:"ip_address"`
+	FailedAttempts int        `db:"failed_attempts" json:"failed_attempts"`
+	LockedAt       time.Time  `db:"locked_at" json:"locked_at"`
+	LockedUntil    time.Time  `db:"locked_until" json:"locked_until"`
+	UnlockedAt     *time.Time `db:"unlocked_at" json:"unlocked_at,omitempty"`
+	UnlockedBy     *int       `db:"unlocked_by" json:"unlocked_by,omitempty"`
+
+	// Joined for display
+	FirstName string `db:"first_name" json:"first_name"`
+	LastName  string `db:"last_name" json:"last_name"`
+	Initials  string `db:"initials" json:"initials"`
+}
+
+// IsActive reports whether the lock is still in effect
+func (l AccountLockout) IsActive() bool {
+	return l.UnlockedAt == nil && time.Now().Before(l.LockedUntil)
+}
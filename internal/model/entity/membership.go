@@ -0,0 +1,50 @@
+// internal/model/entity/membership.go
+package entity
+
+import (
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+// FacilityMembership is the role a user holds at one facility
+type FacilityMembership struct {
+	UserID     int            `db:"user_id" json:"user_id"`
+	FacilityID int            `db:"facility_id" json:"facility_id"`
+	Role       types.UserRole `db:"role" json:"role"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+
+	// The area or crew the member works in and whether they supervise it
+	AreaID         *int `db:"area_id" json:"area_id,omitempty"`
+	AreaSupervisor bool `db:"area_supervisor" json:"area_supervisor"`
+
+	// The facility role granting permissions beyond the built-in role
+	FacilityRoleID    *int               `db:"facility_role_id" json:"facility_role_id,omitempty"`
+	FacilityRoleName  string             `db:"facility_role_name" json:"facility_role_name,omitempty"`
+	FacilityRolePerms []types.Permission `db:"facility_role_permissions" json:"facility_role_permissions,omitempty"`
+
+	// Joined for display and access checks
+	FacilityCode       string     `db:"facility_code" json:"facility_code"`
+	FacilityName       string     `db:"facility_name" json:"facility_name"`
+	FacilityArchivedAt *time.Time `db:"facility_archived_at" json:"facility_archived_at,omitempty"`
+	AreaName           string     `db:"area_name" json:"area_name,omitempty"`
+}
+
+// IsArchived reports whether the membership's facility has been archived
+func (m FacilityMembership) IsArchived() bool {
+	return m.FacilityArchivedAt != nil
+}
+
+// Can reports whether the member holds the permission at the facility, from
+// either their built-in role or their facility role
+func (m FacilityMembership) Can(p types.Permission) bool {
+	if m.Role.Can(p) {
+		return true
+	}
+	for _, v := range m.FacilityRolePerms {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+// internal/model/entity/membership_test.go
+package entity
+
+import (
+	"testing"
+
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+func TestFacilityMembershipCan(t *testing.T) {
+	tests := []struct {
+		name       string
+		membership FacilityMembership
+		permission types.Permission
+		expected   bool
+	}{
+		{
+			name:       "admin holds facility permissions",
+			membership: FacilityMembership{Role: types.UserRoleAdmin},
+			permission: types.PermUsersManage,
+			expected:   true,
+		},
+		{
+			name:       "admin lacks system permissions",
+			membership: FacilityMembership{Role: types.UserRoleAdmin},
+			permission: types.PermUsersImpersonate,
+			expected:   false,
+		},
+		{
+			name:       "user without a facility role",
+			membership: FacilityMembership{Role: types.UserRoleUser},
+			permission: types.PermScheduleEdit,
+			expected:   false,
+		},
+		{
+			name: "user granted by facility role",
+			membership: FacilityMembership{
+				Role:              types.UserRoleUser,
+				FacilityRolePerms: []types.Permission{types.PermScheduleEdit, types.PermPublicationUpdate},
+			},
+			permission: types.PermPublicationUpdate,
+			expected:   true,
+		},
+		{
+			name: "facility role does not grant others",
+			membership: FacilityMembership{
+				Role:              types.UserRoleUser,
+				FacilityRolePerms: []types.Permission{types.PermPublicationUpdate},
+			},
+			permission: types.PermUsersManage,
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.membership.Can(tt.permission); got != tt.expected {
+				t.Errorf("Can(%s) = %v, want %v", tt.permission, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// internal/model/entity/onboarding.go
+package entity
+
+import "time"
+
+// OnboardingStep is the next step of the facility setup wizard
+type OnboardingStep string
+
+const (
+	OnboardingStepAdmin    OnboardingStep = "admin"
+	OnboardingStepSettings OnboardingStep = "settings"
+	OnboardingStepImport   OnboardingStep = "import"
+	OnboardingStepComplete OnboardingStep = "complete"
+)
+
+// FacilityOnboarding tracks a facility through the setup wizard so an
+// interrupted setup can be resumed
+type FacilityOnboarding struct {
+	FacilityID  int            `db:"facility_id" json:"facility_id"`
+	Step        OnboardingStep `db:"step" json:"step"`
+	StartedBy   *int           `db:"started_by" json:"started_by,omitempty"`
+	AdminUserID *int           `db:"admin_user_id" json:"admin_user_id,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time     `db:"completed_at" json:"completed_at,omitempty"`
+
+	// Joined for display
+	FacilityCode string `db:"facility_code" json:"facility_code"`
+	FacilityName string `db:"facility_name" json:"facility_name"`
+}
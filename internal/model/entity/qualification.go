@@ -0,0 +1,86 @@
+// internal/model/entity/qualification.go
+package entity
+
+import "time"
+
+// QualificationKind groups the qualifications in a facility's catalog
+type QualificationKind string
+
+const (
+	QualificationPosition QualificationKind = "position" // Certified to work a position
+	QualificationCPC      QualificationKind = "cpc"      // Certified professional controller
+	QualificationOJTI     QualificationKind = "ojti"     // On-the-job training instructor
+	QualificationCIC      QualificationKind = "cic"      // Controller in charge
+)
+
+// QualificationKinds lists the kinds in the order they are offered
+var QualificationKinds = []QualificationKind{
+	QualificationPosition,
+	QualificationCPC,
+	QualificationOJTI,
+	QualificationCIC,
+}
+
+// Valid reports whether the kind is one of the known kinds
+func (k QualificationKind) Valid() bool {
+	for _, v := range QualificationKinds {
+		if k == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the kind as shown to users
+func (k QualificationKind) Label() string {
+	switch k {
+	case QualificationCPC:
+		return "CPC"
+	case QualificationOJTI:
+		return "OJT instructor"
+	case QualificationCIC:
+		return "CIC"
+	default:
+		return "Position"
+	}
+}
+
+// Qualification is a position or certification in a facility's catalog
+type Qualification struct {
+	ID         int               `db:"id" json:"id"`
+	FacilityID int               `db:"facility_id" json:"facility_id"`
+	Name       string            `db:"name" json:"name"`
+	Kind       QualificationKind `db:"kind" json:"kind"`
+	CreatedAt  time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time         `db:"updated_at" json:"updated_at"`
+
+	// Number of users currently holding the qualification
+	HolderCount int `db:"holder_count" json:"holder_count"`
+}
+
+// UserQualification is a qualification granted to a user
+type UserQualification struct {
+	UserID          int               `db:"user_id" json:"user_id"`
+	QualificationID int               `db:"qualification_id" json:"qualification_id"`
+	Name            string            `db:"name" json:"name"`
+	Kind            QualificationKind `db:"kind" json:"kind"`
+	GrantedOn       time.Time         `db:"granted_on" json:"granted_on"`
+	ExpiresOn       *time.Time        `db:"expires_on" json:"expires_on,omitempty"`
+	GrantedBy       *int              `db:"granted_by" json:"granted_by,omitempty"`
+}
+
+// IsExpired reports whether the qualification lapsed before the given day
+func (q UserQualification) IsExpired(today time.Time) bool {
+	return q.ExpiresOn != nil && q.ExpiresOn.Before(today)
+}
+
+// ExpiringQualification is a grant nearing its expiry date along with who
+// holds it, used to warn the facility's admins
+type ExpiringQualification struct {
+	UserQualification
+	FacilityID   int    `db:"facility_id" json:"facility_id"`
+	FacilityCode string `db:"facility_code" json:"facility_code"`
+	FirstName    string `db:"first_name" json:"first_name"`
+	LastName     string `db:"last_name" json:"last_name"`
+	Initials     string `db:"initials" json:"initials"`
+}
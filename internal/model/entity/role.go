@@ -0,0 +1,32 @@
+// internal/model/entity/role.go
+package entity
+
+import (
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+// FacilityRole is a named set of permissions a facility grants members in
+// addition to those of their built-in role
+type FacilityRole struct {
+	ID          int                `db:"id" json:"id"`
+	FacilityID  int                `db:"facility_id" json:"facility_id"`
+	Name        string             `db:"name" json:"name"`
+	Permissions []types.Permission `db:"permissions" json:"permissions"`
+	CreatedAt   time.Time          `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `db:"updated_at" json:"updated_at"`
+
+	// Number of members holding the role
+	MemberCount int `db:"member_count" json:"member_count"`
+}
+
+// Has reports whether the role grants the permission
+func (r FacilityRole) Has(p types.Permission) bool {
+	for _, v := range r.Permissions {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
@@ -11,4 +11,8 @@ type HTTPSession struct {
 	CreatedOn  time.Time  `db:"created_on" json:"created_on"`
 	ModifiedOn *time.Time `db:"modified_on" json:"modified_on,omitempty"`
 	ExpiresOn  *time.Time `db:"expires_on" json:"expires_on,omitempty"`
+	UserID     *int       `db:"user_id" json:"user_id,omitempty"`
+	IPAddress  string     `db:"ip_address" json:"ip_address"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	LastSeenOn *time.Time `db:"last_seen_on" json:"last_seen_on,omitempty"`
 }
@@ -0,0 +1,26 @@
+// internal/model/entity/sso.go
+package entity
+
+import "time"
+
+// OIDCProvider is a facility's OpenID Connect identity provider
+type OIDCProvider struct {
+	ID           int       `db:"id" json:"id"`
+	FacilityID   int       `db:"facility_id" json:"facility_id"`
+	IssuerURL    string    `db:"issuer_url" json:"issuer_url"`
+	ClientID     string    `db:"client_id" json:"client_id"`
+	ClientSecret string    `db:"client_secret" json:"-"` // Sealed with SECRET_KEY
+	Enabled      bool      `db:"enabled" json:"enabled"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UserIdentity links an external identity to a user
+type UserIdentity struct {
+	ID          int        `db:"id" json:"id"`
+	UserID      int        `db:"user_id" json:"user_id"`
+	Issuer      string     `db:"issuer" json:"issuer"`
+	Subject     string     `db:"subject" json:"subject"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
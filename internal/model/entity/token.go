@@ -22,3 +22,23 @@ type VerificationToken struct {
     ExpiresAt time.Time `db:"expires_at"`
     Used      bool      `db:"used"`
 }
+
+// EmailChangeToken is a verification token that moves a user to a new email
+// address once confirmed. CancelToken is sent to the previous address.
+type EmailChangeToken struct {
+	UserID      int       `db:"user_id"`
+	Token       string    `db:"token"`
+	CancelToken string    `db:"cancel_token"`
+	Email       string    `db:"email"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// PasswordResetToken represents a single-use token for resetting a forgotten password
+type PasswordResetToken struct {
+	UserID    int       `db:"user_id"`
+	Token     string    `db:"token"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+	Used      bool      `db:"used"`
+}
@@ -0,0 +1,29 @@
+// internal/model/entity/transfer.go
+package entity
+
+import (
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/types"
+)
+
+// Transfer records a user moving their home facility. The move is scheduled
+// when requested and applied on the effective date; protected dates before
+// that date stay with the old facility.
+type Transfer struct {
+	ID             int            `db:"id" json:"id"`
+	UserID         int            `db:"user_id" json:"user_id"`
+	FromFacilityID int            `db:"from_facility_id" json:"from_facility_id"`
+	ToFacilityID   int            `db:"to_facility_id" json:"to_facility_id"`
+	Role           types.UserRole `db:"role" json:"role"`
+	EffectiveDate  time.Time      `db:"effective_date" json:"effective_date"`
+	TransferredBy  *int           `db:"transferred_by" json:"transferred_by,omitempty"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+	AppliedAt      *time.Time     `db:"applied_at" json:"applied_at,omitempty"`
+	CancelledAt    *time.Time     `db:"cancelled_at" json:"cancelled_at,omitempty"`
+}
+
+// IsPending reports whether the transfer is still waiting for its effective date
+func (t Transfer) IsPending() bool {
+	return t.AppliedAt == nil && t.CancelledAt == nil
+}
@@ -0,0 +1,20 @@
+// internal/model/entity/two_factor.go
+package entity
+
+import "time"
+
+// TwoFactor holds a user's TOTP enrollment state
+type TwoFactor struct {
+	UserID  int    `db:"id" json:"user_id"`
+	Secret  string `db:"totp_secret" json:"-"` // Sealed with SECRET_KEY
+	Enabled bool   `db:"totp_enabled" json:"enabled"`
+}
+
+// RecoveryCode is a hashed single-use two-factor recovery code
+type RecoveryCode struct {
+	ID        int        `db:"id"`
+	UserID    int        `db:"user_id"`
+	CodeHash  string     `db:"code_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
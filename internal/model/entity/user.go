@@ -18,4 +18,17 @@ type User struct {
 	FacilityID            int            `db:"facility_id" json:"facility_id"`
 	Role                  types.UserRole `db:"role" json:"role" validate:"required,oneof=super admin user"`
 	RegistrationCompleted bool           `db:"registration_completed" json:"registration_completed"`
+	DeactivatedAt         *time.Time     `db:"deactivated_at" json:"deactivated_at,omitempty"`
+
+	// Area at the facility being viewed, when loaded for a facility
+	AreaID   *int   `db:"area_id" json:"area_id,omitempty"`
+	AreaName string `db:"area_name" json:"area_name,omitempty"`
+
+	// Code of the home facility, when loaded across facilities
+	FacilityCode string `db:"facility_code" json:"facility_code,omitempty"`
+}
+
+// IsDeactivated reports whether the user has been deactivated
+func (u User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
 }
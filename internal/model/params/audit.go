@@ -0,0 +1,30 @@
+// internal/model/params/audit.go
+package params
+
+import "time"
+
+// Audit events shown on each page of the audit log
+const AuditPerPage = 50
+
+// Most audit events written to one CSV export
+const MaxAuditExport = 10000
+
+// ListAuditParams filters and pages the audit trail. Empty and nil fields
+// match every event.
+type ListAuditParams struct {
+	FacilityID *int
+	Actor      string // Matches the actor's email or initials
+	EntityType string
+	Action     string
+	From       *time.Time // Events on or after this day
+	To         *time.Time // Events on or before this day
+	Page       int        // Starting at 1
+}
+
+// Offset is the number of events on the pages before this one
+func (p ListAuditParams) Offset() int {
+	if p.Page < 1 {
+		return 0
+	}
+	return (p.Page - 1) * AuditPerPage
+}
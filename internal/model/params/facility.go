@@ -2,11 +2,22 @@
 package params
 
 type CreateFacilityParams struct {
-	Name string `json:"name" form:"name"`
-	Code string `json:"code" form:"code"`
+	Name  string `json:"name" form:"name"`
+	Code  string `json:"code" form:"code"`
+	Alias string `json:"alias" form:"alias"`
 }
 
 type UpdateFacilityParams struct {
-	Name string `json:"name" form:"name"`
-	Code string `json:"code" form:"code"`
+	Name  string `json:"name" form:"name"`
+	Code  string `json:"code" form:"code"`
+	Alias string `json:"alias" form:"alias"`
+}
+
+// SaveSSOProviderParams configures a facility's own identity provider. A
+// blank client secret keeps the stored one.
+type SaveSSOProviderParams struct {
+	IssuerURL    string `json:"issuer_url" form:"issuer_url"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Enabled      bool   `json:"enabled" form:"enabled"`
 }
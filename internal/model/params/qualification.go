@@ -0,0 +1,14 @@
+// internal/model/params/qualification.go
+package params
+
+import "time"
+
+// GrantQualificationParams grants or renews a qualification for a user
+type GrantQualificationParams struct {
+	UserID          int
+	FacilityID      int // Facility whose catalog the qualification is from
+	QualificationID int
+	GrantedOn       time.Time
+	ExpiresOn       *time.Time // nil if the qualification does not expire
+	GrantedBy       int
+}
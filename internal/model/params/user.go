@@ -2,6 +2,8 @@
 package params
 
 import (
+	"time"
+
 	"github.com/DukeRupert/haven/internal/model/types"
 )
 
@@ -29,3 +31,58 @@ type UpdatePasswordParams struct {
 	Password string `form:"password" validate:"required,min=8"`
 	Confirm  string `form:"confirm" validate:"required,eqfield=Password"`
 }
+
+// TransferUserParams moves a user's home facility from the effective date
+type TransferUserParams struct {
+	UserID        int
+	ToFacilityID  int
+	Role          types.UserRole
+	EffectiveDate time.Time
+	TransferredBy int
+}
+
+// Orders the users page can be sorted in
+const (
+	UserSortName     = "name"
+	UserSortInitials = "initials"
+	UserSortEmail    = "email"
+	UserSortNewest   = "newest"
+)
+
+// Registration states the users page can be narrowed to
+const (
+	RegistrationCompleted = "registered"
+	RegistrationPending   = "pending"
+)
+
+// Users shown on each page of the users list
+const UsersPerPage = 25
+
+// ListUsersParams searches, filters and pages the users of a facility.
+// Empty and nil fields match everyone.
+type ListUsersParams struct {
+	FacilityCode    string
+	Search          string // Matches name, initials or email
+	Role            types.UserRole
+	Status          string // RegistrationCompleted or RegistrationPending
+	AreaID          *int
+	QualificationID *int
+	Sort            string
+	Page            int // Starting at 1
+}
+
+// Offset is the number of users on the pages before this one
+func (p ListUsersParams) Offset() int {
+	if p.Page < 1 {
+		return 0
+	}
+	return (p.Page - 1) * UsersPerPage
+}
+
+// UserPages returns how many pages it takes to list total users
+func UserPages(total int) int {
+	if total <= 0 {
+		return 1
+	}
+	return (total + UsersPerPage - 1) / UsersPerPage
+}
@@ -0,0 +1,111 @@
+// internal/model/types/permission.go
+package types
+
+// Permission names an action a user may be allowed to take at a facility
+type Permission string
+
+const (
+	PermScheduleEdit         Permission = "schedule.edit"         // Create and change other users' schedules
+	PermPublicationUpdate    Permission = "publication.update"    // Publish the facility's schedule
+	PermUsersManage          Permission = "users.manage"          // Add, edit, deactivate and transfer users
+	PermAvailabilityOverride Permission = "availability.override" // Change other users' availability
+	PermSettingsManage       Permission = "settings.manage"       // Change facility settings and areas
+	PermQualificationsManage Permission = "qualifications.manage" // Maintain the catalog and grant qualifications
+	PermRolesManage          Permission = "roles.manage"          // Define the facility's roles and assign them
+	PermAuditView            Permission = "audit.view"            // Read and export the facility's audit log
+
+	// System permissions are held only by super users
+	PermFacilitiesManage Permission = "facilities.manage" // Create, archive and configure facilities
+	PermUsersPurge       Permission = "users.purge"       // Permanently delete deactivated users
+	PermUsersImpersonate Permission = "users.impersonate" // View the app as another user
+	PermUsersSearch      Permission = "users.search"      // Find users at every facility
+	PermAuditViewAll     Permission = "audit.view_all"    // Read and export the audit log of every facility
+)
+
+// FacilityPermissions are the permissions a facility's roles can grant, in
+// the order they are offered
+var FacilityPermissions = []Permission{
+	PermScheduleEdit,
+	PermPublicationUpdate,
+	PermAvailabilityOverride,
+	PermUsersManage,
+	PermQualificationsManage,
+	PermSettingsManage,
+	PermRolesManage,
+	PermAuditView,
+}
+
+var systemPermissions = []Permission{
+	PermFacilitiesManage,
+	PermUsersPurge,
+	PermUsersImpersonate,
+	PermUsersSearch,
+	PermAuditViewAll,
+}
+
+// Assignable reports whether a facility role may grant the permission
+func (p Permission) Assignable() bool {
+	for _, v := range FacilityPermissions {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the permission as shown to users
+func (p Permission) Label() string {
+	switch p {
+	case PermScheduleEdit:
+		return "Edit schedules"
+	case PermPublicationUpdate:
+		return "Publish the schedule"
+	case PermUsersManage:
+		return "Manage users"
+	case PermAvailabilityOverride:
+		return "Override availability"
+	case PermSettingsManage:
+		return "Manage facility settings and areas"
+	case PermQualificationsManage:
+		return "Manage qualifications"
+	case PermRolesManage:
+		return "Manage roles"
+	case PermAuditView:
+		return "View the audit log"
+	case PermFacilitiesManage:
+		return "Manage facilities"
+	case PermUsersPurge:
+		return "Purge users"
+	case PermUsersImpersonate:
+		return "Impersonate users"
+	case PermUsersSearch:
+		return "Search users at every facility"
+	case PermAuditViewAll:
+		return "View the audit log of every facility"
+	default:
+		return string(p)
+	}
+}
+
+// Permissions returns the permissions granted by a built-in role. Admins hold
+// every facility permission and super users also hold the system ones.
+func (r UserRole) Permissions() []Permission {
+	switch r {
+	case UserRoleSuper:
+		return append(append([]Permission{}, FacilityPermissions...), systemPermissions...)
+	case UserRoleAdmin:
+		return FacilityPermissions
+	default:
+		return nil
+	}
+}
+
+// Can reports whether the built-in role grants the permission
+func (r UserRole) Can(p Permission) bool {
+	for _, v := range r.Permissions() {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
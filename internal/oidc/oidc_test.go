@@ -0,0 +1,218 @@
+// internal/oidc/oidc_test.go
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// mockProvider is a minimal OpenID Connect issuer for tests
+type mockProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	m := &mockProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &key.PublicKey,
+			KeyID:     "test",
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// PKCE: the verifier must hash to the challenge from the auth URL
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.sign(t),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockProvider) sign(t *testing.T) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithHeader("kid", "test"),
+	)
+	if err != nil {
+		t.Fatalf("creating signer: %v", err)
+	}
+
+	payload, _ := json.Marshal(m.claims)
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	raw, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("serializing token: %v", err)
+	}
+	return raw
+}
+
+func TestProviderExchange(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		emailVerified bool
+		nonce         string
+		wrongVerifier bool
+		expectedErr   error
+		expectError   bool
+	}{
+		{
+			name:          "verified email",
+			email:         "controller@example.com",
+			emailVerified: true,
+		},
+		{
+			name:          "unverified email",
+			email:         "controller@example.com",
+			emailVerified: false,
+			expectedErr:   ErrEmailNotVerified,
+		},
+		{
+			name:          "nonce mismatch",
+			email:         "controller@example.com",
+			emailVerified: true,
+			nonce:         "replayed",
+			expectedErr:   ErrNonceMismatch,
+		},
+		{
+			name:          "wrong code verifier",
+			email:         "controller@example.com",
+			emailVerified: true,
+			wrongVerifier: true,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			ctx := context.Background()
+
+			provider, err := NewProvider(ctx, Config{
+				IssuerURL:    m.server.URL,
+				ClientID:     "haven",
+				ClientSecret: "secret",
+				RedirectURL:  "http://localhost/login/oidc/callback",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req, err := NewAuthRequest()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			authURL, err := url.Parse(provider.AuthCodeURL(req))
+			if err != nil {
+				t.Fatalf("parsing auth url: %v", err)
+			}
+			query := authURL.Query()
+			if query.Get("state") != req.State {
+				t.Errorf("expected state %q, got %q", req.State, query.Get("state"))
+			}
+			if query.Get("code_challenge_method") != "S256" {
+				t.Errorf("expected S256 challenge, got %q", query.Get("code_challenge_method"))
+			}
+			m.challenge = query.Get("code_challenge")
+
+			nonce := req.Nonce
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			m.claims = map[string]interface{}{
+				"iss":            m.server.URL,
+				"sub":            "user-123",
+				"aud":            "haven",
+				"exp":            time.Now().Add(time.Hour).Unix(),
+				"iat":            time.Now().Unix(),
+				"nonce":          nonce,
+				"email":          tt.email,
+				"email_verified": tt.emailVerified,
+			}
+
+			if tt.wrongVerifier {
+				req.CodeVerifier = "not-the-verifier"
+			}
+
+			identity, err := provider.Exchange(ctx, "code", req)
+			if tt.expectedErr != nil || tt.expectError {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if identity.Email != tt.email {
+				t.Errorf("expected email %q, got %q", tt.email, identity.Email)
+			}
+			if identity.Subject != "user-123" {
+				t.Errorf("expected subject user-123, got %q", identity.Subject)
+			}
+			if identity.Issuer != m.server.URL {
+				t.Errorf("expected issuer %q, got %q", m.server.URL, identity.Issuer)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// internal/oidc/registry.go
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/secret"
+)
+
+// ProviderStore looks up facility specific providers
+type ProviderStore interface {
+	GetProviderByFacilityID(ctx context.Context, facilityID int) (*entity.OIDCProvider, error)
+}
+
+// Registry resolves the provider for a facility, falling back to the
+// global provider. Discovered providers are cached by configuration.
+type Registry struct {
+	global      *Config
+	store       ProviderStore
+	secrets     *secret.Box
+	redirectURL string
+	notFound    error
+
+	mu        sync.Mutex
+	providers map[Config]*Provider
+}
+
+// NewRegistry creates a registry. global may be nil when there is no
+// global provider. notFound is the error the store returns when a facility
+// has no provider of its own. Facility providers are only used when secrets
+// is set, as their client secrets are stored sealed.
+func NewRegistry(global *Config, store ProviderStore, secrets *secret.Box, notFound error, redirectURL string) *Registry {
+	if global != nil {
+		cfg := *global
+		cfg.RedirectURL = redirectURL
+		global = &cfg
+	}
+	return &Registry{
+		global:      global,
+		store:       store,
+		secrets:     secrets,
+		redirectURL: redirectURL,
+		notFound:    notFound,
+		providers:   make(map[Config]*Provider),
+	}
+}
+
+// ForFacility returns the provider users of the facility sign in with
+func (r *Registry) ForFacility(ctx context.Context, facilityID int) (*Provider, error) {
+	cfg, err := r.configFor(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	p, ok := r.providers[*cfg]
+	r.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	// Discovery happens outside the lock so a slow issuer does not hold up
+	// sign in at other facilities. Concurrent discoveries keep the first.
+	p, err = NewProvider(ctx, *cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.providers[*cfg]; ok {
+		return cached, nil
+	}
+	r.providers[*cfg] = p
+	return p, nil
+}
+
+// configFor picks the facility's own provider before the global one
+func (r *Registry) configFor(ctx context.Context, facilityID int) (*Config, error) {
+	if facilityID != 0 && r.store != nil && r.secrets != nil {
+		p, err := r.store.GetProviderByFacilityID(ctx, facilityID)
+		if err == nil {
+			secret, err := r.secrets.Open(p.ClientSecret)
+			if err != nil {
+				return nil, fmt.Errorf("opening facility provider secret: %w", err)
+			}
+			return &Config{
+				IssuerURL:    p.IssuerURL,
+				ClientID:     p.ClientID,
+				ClientSecret: secret,
+				RedirectURL:  r.redirectURL,
+			}, nil
+		}
+		if !errors.Is(err, r.notFound) {
+			return nil, fmt.Errorf("loading facility provider: %w", err)
+		}
+	}
+
+	if r.global == nil {
+		return nil, ErrNotConfigured
+	}
+	return r.global, nil
+}
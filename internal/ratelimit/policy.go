@@ -0,0 +1,29 @@
+// internal/ratelimit/policy.go
+package ratelimit
+
+import "time"
+
+// Policies for public authentication endpoints. IP policies are applied by
+// middleware; account policies are checked by handlers once the email or
+// token is known.
+var (
+	LoginIP      = Policy{Name: "login:ip", Limit: 20, Window: 15 * time.Minute}
+	LoginAccount = Policy{Name: "login:account", Limit: 10, Window: 15 * time.Minute}
+
+	TwoFactorIP = Policy{Name: "2fa:ip", Limit: 20, Window: 15 * time.Minute}
+
+	VerifyIP      = Policy{Name: "verify:ip", Limit: 10, Window: time.Hour}
+	VerifyAccount = Policy{Name: "verify:account", Limit: 3, Window: time.Hour}
+
+	ResendIP      = Policy{Name: "resend:ip", Limit: 10, Window: time.Hour}
+	ResendAccount = Policy{Name: "resend:account", Limit: 3, Window: time.Hour}
+
+	SetPasswordIP      = Policy{Name: "set-password:ip", Limit: 10, Window: 15 * time.Minute}
+	SetPasswordAccount = Policy{Name: "set-password:account", Limit: 5, Window: 15 * time.Minute}
+
+	ForgotPasswordIP      = Policy{Name: "forgot-password:ip", Limit: 10, Window: time.Hour}
+	ForgotPasswordAccount = Policy{Name: "forgot-password:account", Limit: 3, Window: time.Hour}
+
+	EmailChangeIP      = Policy{Name: "email-change:ip", Limit: 10, Window: time.Hour}
+	EmailChangeAccount = Policy{Name: "email-change:account", Limit: 3, Window: time.Hour}
+)
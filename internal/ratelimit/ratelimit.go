@@ -0,0 +1,76 @@
+// internal/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store records hits against a key within a fixed window
+type Store interface {
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy describes how many requests are allowed per window
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// Result reports the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter checks requests against policies using a shared store
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// New creates a new Limiter
+func New(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow records a hit for the subject under the policy and reports whether
+// the request may proceed
+func (l *Limiter) Allow(ctx context.Context, policy Policy, subject string) (Result, error) {
+	hits, resetAt, err := l.store.Hit(ctx, Key(policy, subject), policy.Window)
+	if err != nil {
+		return Result{}, fmt.Errorf("checking rate limit %s: %w", policy.Name, err)
+	}
+
+	if hits > policy.Limit {
+		retryAfter := resetAt.Sub(l.now())
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return Result{Allowed: false, RetryAfter: retryAfter}, nil
+	}
+
+	return Result{Allowed: true, Remaining: policy.Limit - hits}, nil
+}
+
+// Reset clears the counter for the subject under the policy
+func (l *Limiter) Reset(ctx context.Context, policy Policy, subject string) error {
+	if err := l.store.Reset(ctx, Key(policy, subject)); err != nil {
+		return fmt.Errorf("resetting rate limit %s: %w", policy.Name, err)
+	}
+	return nil
+}
+
+// Key builds the storage key for a policy and subject. Subjects are
+// normalized so that emails differing only in case share a counter.
+func Key(policy Policy, subject string) string {
+	return policy.Name + ":" + strings.ToLower(strings.TrimSpace(subject))
+}
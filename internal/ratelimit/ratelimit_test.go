@@ -0,0 +1,102 @@
+// internal/ratelimit/ratelimit_test.go
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	hits    map[string]int
+	resetAt time.Time
+}
+
+func (s *memoryStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.hits[key]++
+	return s.hits[key], s.resetAt, nil
+}
+
+func (s *memoryStore) Reset(ctx context.Context, key string) error {
+	delete(s.hits, key)
+	return nil
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	policy := Policy{Name: "login:email", Limit: 3, Window: 15 * time.Minute}
+
+	tests := []struct {
+		name              string
+		priorHits         int
+		expectedAllowed   bool
+		expectedRemaining int
+		expectedRetry     time.Duration
+	}{
+		{
+			name:              "first request",
+			priorHits:         0,
+			expectedAllowed:   true,
+			expectedRemaining: 2,
+		},
+		{
+			name:              "last allowed request",
+			priorHits:         2,
+			expectedAllowed:   true,
+			expectedRemaining: 0,
+		},
+		{
+			name:            "over the limit",
+			priorHits:       3,
+			expectedAllowed: false,
+			expectedRetry:   10 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{
+				hits:    map[string]int{Key(policy, "user@example.com"): tt.priorHits},
+				resetAt: now.Add(10 * time.Minute),
+			}
+			limiter := New(store)
+			limiter.now = func() time.Time { return now }
+
+			result, err := limiter.Allow(context.Background(), policy, "user@example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Allowed != tt.expectedAllowed {
+				t.Errorf("expected allowed %v, got %v", tt.expectedAllowed, result.Allowed)
+			}
+			if result.Remaining != tt.expectedRemaining {
+				t.Errorf("expected remaining %d, got %d", tt.expectedRemaining, result.Remaining)
+			}
+			if result.RetryAfter != tt.expectedRetry {
+				t.Errorf("expected retry after %v, got %v", tt.expectedRetry, result.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		expected string
+	}{
+		{"lowercase", "user@example.com", "login:email:user@example.com"},
+		{"mixed case", "User@Example.COM", "login:email:user@example.com"},
+		{"surrounding spaces", "  user@example.com ", "login:email:user@example.com"},
+	}
+
+	policy := Policy{Name: "login:email"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Key(policy, tt.subject); result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
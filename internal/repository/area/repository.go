@@ -0,0 +1,123 @@
+// internal/repository/area/repository.go
+package area
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles the areas and crews within facilities
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new area repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound  = fmt.Errorf("area not found")
+	ErrDuplicate = fmt.Errorf("area name already used at facility")
+)
+
+// ListByFacility returns the facility's areas in name order
+func (r *Repository) ListByFacility(ctx context.Context, facilityID int) ([]entity.Area, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT a.id, a.facility_id, a.name, a.created_at, a.updated_at,
+               COUNT(m.user_id)
+        FROM facility_areas a
+        LEFT JOIN facility_memberships m ON m.area_id = a.id
+        WHERE a.facility_id = $1
+        GROUP BY a.id
+        ORDER BY a.name
+    `, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("listing areas: %w", err)
+	}
+	defer rows.Close()
+
+	var areas []entity.Area
+	for rows.Next() {
+		var a entity.Area
+		if err := rows.Scan(&a.ID, &a.FacilityID, &a.Name, &a.CreatedAt, &a.UpdatedAt, &a.MemberCount); err != nil {
+			return nil, fmt.Errorf("scanning area row: %w", err)
+		}
+		areas = append(areas, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating area rows: %w", err)
+	}
+
+	return areas, nil
+}
+
+// Create adds an area to the facility
+func (r *Repository) Create(ctx context.Context, facilityID int, name string) (*entity.Area, error) {
+	name = strings.TrimSpace(name)
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM facility_areas
+            WHERE facility_id = $1 AND LOWER(name) = LOWER($2)
+        )
+    `, facilityID, name).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("checking area name: %w", err)
+	}
+	if exists {
+		return nil, ErrDuplicate
+	}
+
+	var a entity.Area
+	err = r.pool.QueryRow(ctx, `
+        INSERT INTO facility_areas (facility_id, name)
+        VALUES ($1, $2)
+        RETURNING id, facility_id, name, created_at, updated_at
+    `, facilityID, name).Scan(&a.ID, &a.FacilityID, &a.Name, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating area: %w", err)
+	}
+	return &a, nil
+}
+
+// Get returns an area of the facility
+func (r *Repository) Get(ctx context.Context, facilityID, areaID int) (*entity.Area, error) {
+	var a entity.Area
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, facility_id, name, created_at, updated_at
+        FROM facility_areas
+        WHERE id = $1 AND facility_id = $2
+    `, areaID, facilityID).Scan(&a.ID, &a.FacilityID, &a.Name, &a.CreatedAt, &a.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting area: %w", err)
+	}
+	return &a, nil
+}
+
+// Delete removes an area; its members are left unassigned
+func (r *Repository) Delete(ctx context.Context, facilityID, areaID int) error {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM facility_areas
+        WHERE id = $1 AND facility_id = $2
+    `, areaID, facilityID)
+	if err != nil {
+		return fmt.Errorf("deleting area: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// internal/repository/audit/repository.go
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository stores the audit trail of changes made through the app
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new audit repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Record appends an event to the audit trail. The actor's email is copied so
+// the event still names them if they are later purged.
+func (r *Repository) Record(ctx context.Context, event entity.AuditEvent) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO audit_events (
+            actor_id, actor_email, impersonator_id, facility_id,
+            entity_type, entity_id, action, before, after,
+            method, path, request_id
+        )
+        VALUES (
+            $1, COALESCE((SELECT email FROM users WHERE id = $1), ''), $2, $3,
+            $4, $5, $6, $7, $8,
+            $9, $10, $11
+        )
+    `,
+		event.ActorID, event.ImpersonatorID, event.FacilityID,
+		event.EntityType, event.EntityID, event.Action, jsonOrNil(event.Before), jsonOrNil(event.After),
+		event.Method, event.Path, event.RequestID,
+	)
+	if err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+	return nil
+}
+
+// List returns one page of the events matching the filters, newest first,
+// along with the number of matching events
+func (r *Repository) List(ctx context.Context, p params.ListAuditParams) ([]entity.AuditEvent, int, error) {
+	return r.query(ctx, p, params.AuditPerPage, p.Offset())
+}
+
+// Export returns up to params.MaxAuditExport of the events matching the
+// filters, ignoring the page
+func (r *Repository) Export(ctx context.Context, p params.ListAuditParams) ([]entity.AuditEvent, error) {
+	events, _, err := r.query(ctx, p, params.MaxAuditExport, 0)
+	return events, err
+}
+
+func (r *Repository) query(ctx context.Context, p params.ListAuditParams, limit, offset int) ([]entity.AuditEvent, int, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT 
+            e.id, e.actor_id, e.actor_email, COALESCE(a.initials, ''),
+            e.impersonator_id, COALESCE(i.email, ''),
+            e.facility_id, COALESCE(f.code, ''),
+            e.entity_type, e.entity_id, e.action, e.before, e.after,
+            e.method, e.path, e.request_id, e.created_at,
+            COUNT(*) OVER ()
+        FROM audit_events e
+        LEFT JOIN users a ON a.id = e.actor_id
+        LEFT JOIN users i ON i.id = e.impersonator_id
+        LEFT JOIN facilities f ON f.id = e.facility_id
+        WHERE ($1::int IS NULL OR e.facility_id = $1)
+        AND ($2::text = '' OR STRPOS(LOWER(e.actor_email), LOWER($2)) > 0
+            OR UPPER(a.initials) = UPPER($2))
+        AND ($3::text = '' OR e.entity_type = $3)
+        AND ($4::text = '' OR e.action = $4)
+        AND ($5::timestamptz IS NULL OR e.created_at >= $5)
+        AND ($6::timestamptz IS NULL OR e.created_at < $6::timestamptz + INTERVAL '1 day')
+        ORDER BY e.created_at DESC, e.id DESC
+        LIMIT $7 OFFSET $8
+    `, p.FacilityID, p.Actor, p.EntityType, p.Action, p.From, p.To, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("querying audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []entity.AuditEvent
+	var total int
+	for rows.Next() {
+		var e entity.AuditEvent
+		err := rows.Scan(
+			&e.ID, &e.ActorID, &e.ActorEmail, &e.ActorInitials,
+			&e.ImpersonatorID, &e.ImpersonatorEmail,
+			&e.FacilityID, &e.FacilityCode,
+			&e.EntityType, &e.EntityID, &e.Action, &e.Before, &e.After,
+			&e.Method, &e.Path, &e.RequestID, &e.CreatedAt,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scanning audit event row: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating audit event rows: %w", err)
+	}
+
+	return events, total, nil
+}
+
+// jsonOrNil stores missing values as NULL rather than an empty document
+func jsonOrNil(v []byte) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return string(v)
+}
@@ -26,13 +26,15 @@ func New(pool *pgxpool.Pool) *Repository {
 
 // Custom errors
 var (
-	ErrDuplicateCode = fmt.Errorf("facility code already exists")
+	ErrDuplicateCode  = fmt.Errorf("facility code already exists")
+	ErrDuplicateAlias = fmt.Errorf("facility alias already exists")
 	ErrNotFound      = fmt.Errorf("facility not found")
+	ErrNotEmpty      = fmt.Errorf("facility still has users")
 )
 
 func (r *Repository) List(ctx context.Context) ([]entity.Facility, error) {
 	rows, err := r.pool.Query(ctx, `
-        SELECT id, created_at, name, code
+        SELECT id, created_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
         FROM facilities
         ORDER BY name ASC
     `)
@@ -49,6 +51,9 @@ func (r *Repository) List(ctx context.Context) ([]entity.Facility, error) {
 			&f.CreatedAt,
 			&f.Name,
 			&f.Code,
+			&f.Alias,
+			&f.RequireTwoFactor,
+			&f.ArchivedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning facility row: %w", err)
@@ -66,7 +71,7 @@ func (r *Repository) List(ctx context.Context) ([]entity.Facility, error) {
 func (r *Repository) GetByID(ctx context.Context, id int) (*entity.Facility, error) {
 	var f entity.Facility
 	err := r.pool.QueryRow(ctx, `
-        SELECT id, created_at, updated_at, name, code
+        SELECT id, created_at, updated_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
         FROM facilities
         WHERE id = $1
     `, id).Scan(
@@ -75,6 +80,9 @@ func (r *Repository) GetByID(ctx context.Context, id int) (*entity.Facility, err
 		&f.UpdatedAt,
 		&f.Name,
 		&f.Code,
+		&f.Alias,
+		&f.RequireTwoFactor,
+		&f.ArchivedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -88,7 +96,7 @@ func (r *Repository) GetByID(ctx context.Context, id int) (*entity.Facility, err
 func (r *Repository) GetByCode(ctx context.Context, code string) (*entity.Facility, error) {
 	var f entity.Facility
 	err := r.pool.QueryRow(ctx, `
-        SELECT id, created_at, updated_at, name, code
+        SELECT id, created_at, updated_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
         FROM facilities
         WHERE code = $1
     `, code).Scan(
@@ -97,6 +105,9 @@ func (r *Repository) GetByCode(ctx context.Context, code string) (*entity.Facili
 		&f.UpdatedAt,
 		&f.Name,
 		&f.Code,
+		&f.Alias,
+		&f.RequireTwoFactor,
+		&f.ArchivedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error getting facility by code: %w", err)
@@ -104,6 +115,34 @@ func (r *Repository) GetByCode(ctx context.Context, code string) (*entity.Facili
 	return &f, nil
 }
 
+// GetByCodeOrAlias finds a facility by its code or, failing that, its alias
+func (r *Repository) GetByCodeOrAlias(ctx context.Context, identifier string) (*entity.Facility, error) {
+	var f entity.Facility
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, created_at, updated_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
+        FROM facilities
+        WHERE code = UPPER($1) OR UPPER(alias) = UPPER($1)
+        ORDER BY code = UPPER($1) DESC
+        LIMIT 1
+    `, identifier).Scan(
+		&f.ID,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.Name,
+		&f.Code,
+		&f.Alias,
+		&f.RequireTwoFactor,
+		&f.ArchivedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting facility by code or alias: %w", err)
+	}
+	return &f, nil
+}
+
 func (r *Repository) Create(ctx context.Context, params params.CreateFacilityParams) (*entity.Facility, error) {
 	// Check for unique code first
 	isUnique, err := r.IsCodeUnique(ctx, params.Code, nil)
@@ -113,19 +152,23 @@ func (r *Repository) Create(ctx context.Context, params params.CreateFacilityPar
 	if !isUnique {
 		return nil, ErrDuplicateCode
 	}
+	if err := r.checkAlias(ctx, params.Alias, nil); err != nil {
+		return nil, err
+	}
 
 	var f entity.Facility
 	now := time.Now()
 	err = r.pool.QueryRow(ctx, `
-		INSERT INTO facilities (created_at, updated_at, name, code)  -- Added updated_at
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at, name, code
-	`, now, now, params.Name, params.Code).Scan(
+		INSERT INTO facilities (created_at, updated_at, name, code, alias)  -- Added updated_at
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, created_at, updated_at, name, code, COALESCE(alias, '')
+	`, now, now, params.Name, params.Code, params.Alias).Scan(
 		&f.ID,
 		&f.CreatedAt,
 		&f.UpdatedAt, // Add this field to match RETURNING clause
 		&f.Name,
 		&f.Code,
+		&f.Alias,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating facility: %w", err)
@@ -142,21 +185,27 @@ func (r *Repository) Update(ctx context.Context, id int, params params.UpdateFac
 	if !isUnique {
 		return nil, ErrDuplicateCode
 	}
+	if err := r.checkAlias(ctx, params.Alias, &id); err != nil {
+		return nil, err
+	}
 
 	var f entity.Facility
 	now := time.Now()
 
 	err = r.pool.QueryRow(ctx, `
         UPDATE facilities
-        SET updated_at = $1, name = $2, code = $3
+        SET updated_at = $1, name = $2, code = $3, alias = NULLIF($5, '')
         WHERE id = $4
-        RETURNING id, created_at, updated_at, name, code
-    `, now, params.Name, params.Code, id).Scan(
+        RETURNING id, created_at, updated_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
+    `, now, params.Name, params.Code, id, params.Alias).Scan(
 		&f.ID,
 		&f.CreatedAt,
 		&f.UpdatedAt,
 		&f.Name,
 		&f.Code,
+		&f.Alias,
+		&f.RequireTwoFactor,
+		&f.ArchivedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -168,6 +217,184 @@ func (r *Repository) Update(ctx context.Context, id int, params params.UpdateFac
 	return &f, nil
 }
 
+// SetRequireTwoFactor enables or disables mandatory two-factor authentication for a facility
+func (r *Repository) SetRequireTwoFactor(ctx context.Context, id int, required bool) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE facilities
+        SET require_two_factor = $1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+    `, required, id)
+	if err != nil {
+		return fmt.Errorf("error updating facility two-factor requirement: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetArchived archives or restores a facility. Archived facilities keep their
+// data but are hidden and their users cannot sign in.
+func (r *Repository) SetArchived(ctx context.Context, id int, archived bool) (*entity.Facility, error) {
+	var f entity.Facility
+	err := r.pool.QueryRow(ctx, `
+        UPDATE facilities
+        SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+        RETURNING id, created_at, updated_at, name, code, COALESCE(alias, ''), require_two_factor, archived_at
+    `, archived, id).Scan(
+		&f.ID,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.Name,
+		&f.Code,
+		&f.Alias,
+		&f.RequireTwoFactor,
+		&f.ArchivedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("error archiving facility: %w", err)
+	}
+	return &f, nil
+}
+
+// Delete permanently removes a facility that has no users, along with its
+// publication settings. Facilities with members, protected dates or
+// transfer history, including pending transfers, are kept.
+func (r *Repository) Delete(ctx context.Context, id int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the row so a user cannot be added while we check
+	var inUse bool
+	err = tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM users WHERE facility_id = f.id)
+            OR EXISTS (SELECT 1 FROM facility_memberships WHERE facility_id = f.id)
+            OR EXISTS (SELECT 1 FROM protected_dates WHERE facility_id = f.id)
+            OR EXISTS (
+                SELECT 1 FROM user_transfers
+                WHERE from_facility_id = f.id OR to_facility_id = f.id
+            )
+        FROM facilities f
+        WHERE f.id = $1
+        FOR UPDATE
+    `, id).Scan(&inUse)
+	if err == pgx.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("checking facility users: %w", err)
+	}
+	if inUse {
+		return ErrNotEmpty
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM schedule_publications
+        WHERE facility_id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("deleting facility publications: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM facilities
+        WHERE id = $1
+    `, id)
+	if err != nil {
+		return fmt.Errorf("deleting facility: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetSettings returns the facility's scheduling defaults, falling back to
+// the application defaults when none have been saved
+func (r *Repository) GetSettings(ctx context.Context, facilityID int) (*entity.FacilitySettings, error) {
+	var s entity.FacilitySettings
+	err := r.pool.QueryRow(ctx, `
+        SELECT facility_id, default_first_weekday, default_second_weekday, publish_weeks_ahead, updated_at,
+               time_zone, week_start, publication_policy, availability_deadline_days
+        FROM facility_settings
+        WHERE facility_id = $1
+    `, facilityID).Scan(
+		&s.FacilityID,
+		&s.DefaultFirstWeekday,
+		&s.DefaultSecondWeekday,
+		&s.PublishWeeksAhead,
+		&s.UpdatedAt,
+		&s.TimeZone,
+		&s.WeekStart,
+		&s.PublicationPolicy,
+		&s.AvailabilityDeadlineDays,
+	)
+	if err == pgx.ErrNoRows {
+		defaults := entity.DefaultFacilitySettings(facilityID)
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting facility settings: %w", err)
+	}
+	return &s, nil
+}
+
+// GetSettingsByCode returns the scheduling defaults of the facility with the given code
+func (r *Repository) GetSettingsByCode(ctx context.Context, code string) (*entity.FacilitySettings, error) {
+	f, err := r.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSettings(ctx, f.ID)
+}
+
+// UpdateSettings saves the facility's scheduling defaults
+func (r *Repository) UpdateSettings(ctx context.Context, settings entity.FacilitySettings) (*entity.FacilitySettings, error) {
+	var s entity.FacilitySettings
+	err := r.pool.QueryRow(ctx, `
+        INSERT INTO facility_settings (
+            facility_id, default_first_weekday, default_second_weekday, publish_weeks_ahead,
+            time_zone, week_start, publication_policy, availability_deadline_days
+        )
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (facility_id) DO UPDATE
+        SET default_first_weekday = EXCLUDED.default_first_weekday,
+            default_second_weekday = EXCLUDED.default_second_weekday,
+            publish_weeks_ahead = EXCLUDED.publish_weeks_ahead,
+            time_zone = EXCLUDED.time_zone,
+            week_start = EXCLUDED.week_start,
+            publication_policy = EXCLUDED.publication_policy,
+            availability_deadline_days = EXCLUDED.availability_deadline_days,
+            updated_at = CURRENT_TIMESTAMP
+        RETURNING facility_id, default_first_weekday, default_second_weekday, publish_weeks_ahead, updated_at,
+                  time_zone, week_start, publication_policy, availability_deadline_days
+    `, settings.FacilityID, settings.DefaultFirstWeekday, settings.DefaultSecondWeekday, settings.PublishWeeksAhead,
+		settings.TimeZone, settings.WeekStart, settings.PublicationPolicy, settings.AvailabilityDeadlineDays).Scan(
+		&s.FacilityID,
+		&s.DefaultFirstWeekday,
+		&s.DefaultSecondWeekday,
+		&s.PublishWeeksAhead,
+		&s.UpdatedAt,
+		&s.TimeZone,
+		&s.WeekStart,
+		&s.PublicationPolicy,
+		&s.AvailabilityDeadlineDays,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error updating facility settings: %w", err)
+	}
+	return &s, nil
+}
+
 func (r *Repository) IsCodeUnique(ctx context.Context, code string, excludeID *int) (bool, error) {
 	// Build query with optional ID exclusion
 	// $2::int is used to explicitly cast the nullable ID parameter
@@ -187,3 +414,27 @@ func (r *Repository) IsCodeUnique(ctx context.Context, code string, excludeID *i
 
 	return isUnique, nil
 }
+
+// checkAlias returns ErrDuplicateAlias when another facility already uses
+// the alias, ignoring case
+func (r *Repository) checkAlias(ctx context.Context, alias string, excludeID *int) error {
+	if alias == "" {
+		return nil
+	}
+
+	var taken bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM facilities
+            WHERE UPPER(alias) = UPPER($1)
+            AND ($2::int IS NULL OR id != $2)
+        )
+    `, alias, excludeID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("checking alias uniqueness: %w", err)
+	}
+	if taken {
+		return ErrDuplicateAlias
+	}
+	return nil
+}
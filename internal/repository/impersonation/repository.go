@@ -0,0 +1,34 @@
+// internal/repository/impersonation/repository.go
+package impersonation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository stores the impersonation audit trail
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new impersonation repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Record appends an event to the audit trail
+func (r *Repository) Record(ctx context.Context, event entity.ImpersonationEvent) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO impersonation_events (impersonator_id, user_id, action, method, path, status)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, event.ImpersonatorID, event.UserID, event.Action, event.Method, event.Path, event.Status)
+	if err != nil {
+		return fmt.Errorf("recording impersonation event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// internal/repository/invitation/repository.go
+package invitation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository reports on users who have been invited but not yet registered
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new invitation repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound = fmt.Errorf("invitation not found")
+)
+
+// selectInvitations joins each active unregistered user to their newest
+// registration verification token
+const selectInvitations = `
+        SELECT u.id, u.first_name, u.last_name, u.initials, u.email, u.created_at,
+               vt.created_at, vt.expires_at, COALESCE(vt.used, false), u.invitation_revoked_at
+        FROM users u
+        JOIN facilities f ON f.id = u.facility_id
+        LEFT JOIN LATERAL (
+            SELECT created_at, expires_at, used
+            FROM verification_tokens
+            WHERE user_id = u.id AND purpose = 'registration'
+            ORDER BY created_at DESC
+            LIMIT 1
+        ) vt ON true
+        WHERE f.code = $1 AND NOT u.registration_completed
+        AND u.deactivated_at IS NULL`
+
+// ListByFacility returns the facility's pending invitations, newest first
+func (r *Repository) ListByFacility(ctx context.Context, facilityCode string) ([]entity.Invitation, error) {
+	rows, err := r.pool.Query(ctx, selectInvitations+`
+        ORDER BY u.created_at DESC
+    `, facilityCode)
+	if err != nil {
+		return nil, fmt.Errorf("listing invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []entity.Invitation
+	for rows.Next() {
+		i, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning invitation row: %w", err)
+		}
+		invitations = append(invitations, *i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating invitation rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// Get returns a single pending invitation within a facility
+func (r *Repository) Get(ctx context.Context, facilityCode string, userID int) (*entity.Invitation, error) {
+	row := r.pool.QueryRow(ctx, selectInvitations+`
+        AND u.id = $2
+    `, facilityCode, userID)
+	i, err := scanInvitation(row)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting invitation: %w", err)
+	}
+	return i, nil
+}
+
+// Revoke invalidates every outstanding registration link for an unregistered
+// user and stops them requesting new ones. The user record is kept so the
+// invitation can be resent later.
+func (r *Repository) Revoke(ctx context.Context, userID int) error {
+	return r.resetLinks(ctx, userID, true)
+}
+
+// Renew invalidates every outstanding registration link for an unregistered
+// user ahead of sending a new one, lifting any revocation
+func (r *Repository) Renew(ctx context.Context, userID int) error {
+	return r.resetLinks(ctx, userID, false)
+}
+
+// IsRevoked reports whether the unregistered user's invitation was revoked
+func (r *Repository) IsRevoked(ctx context.Context, userID int) (bool, error) {
+	var revoked bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT invitation_revoked_at IS NOT NULL
+        FROM users
+        WHERE id = $1
+    `, userID).Scan(&revoked)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking invitation revoked: %w", err)
+	}
+	return revoked, nil
+}
+
+func (r *Repository) resetLinks(ctx context.Context, userID int, revoke bool) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+        UPDATE verification_tokens
+        SET used = true
+        WHERE user_id = $1 AND purpose = 'registration' AND NOT used
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("revoking verification tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM registration_tokens
+        WHERE user_id = $1
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("revoking registration token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE users
+        SET invitation_revoked_at = CASE WHEN $2 THEN CURRENT_TIMESTAMP END
+        WHERE id = $1 AND NOT registration_completed
+    `, userID, revoke)
+	if err != nil {
+		return fmt.Errorf("marking invitation revoked: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+func scanInvitation(row pgx.Row) (*entity.Invitation, error) {
+	var i entity.Invitation
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Initials,
+		&i.Email,
+		&i.InvitedAt,
+		&i.LastSentAt,
+		&i.ExpiresAt,
+		&i.Used,
+		&i.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,204 @@
+// internal/repository/lockout/repository.go
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository tracks failed logins and temporary account lockouts
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new lockout repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound = fmt.Errorf("lockout not found")
+)
+
+// LockedUntil returns when the user's lock expires, or nil if the account is not locked
+func (r *Repository) LockedUntil(ctx context.Context, userID int) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := r.pool.QueryRow(ctx, `
+        SELECT locked_until
+        FROM users
+        WHERE id = $1 AND locked_until > NOW()
+    `, userID).Scan(&lockedUntil)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checking account lock: %w", err)
+	}
+	return lockedUntil, nil
+}
+
+// RecordFailure increments the user's failed login count. Once the count
+// reaches threshold the account is locked for duration, a lockout event is
+// recorded and the lock expiry is returned.
+func (r *Repository) RecordFailure(ctx context.Context, userID int, ipAddress string, threshold int, duration time.Duration) (*time.Time, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var attempts, facilityID int
+	err = tx.QueryRow(ctx, `
+        UPDATE users
+        SET failed_login_attempts = failed_login_attempts + 1
+        WHERE id = $1
+        RETURNING failed_login_attempts, facility_id
+    `, userID).Scan(&attempts, &facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("recording failed login: %w", err)
+	}
+
+	var lockedUntil *time.Time
+	if attempts >= threshold {
+		until := time.Now().Add(duration)
+		lockedUntil = &until
+
+		if _, err := tx.Exec(ctx, `
+            UPDATE users
+            SET locked_until = $1, failed_login_attempts = 0
+            WHERE id = $2
+        `, until, userID); err != nil {
+			return nil, fmt.Errorf("locking account: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `
+            INSERT INTO account_lockouts (user_id, facility_id, ip_address, failed_attempts, locked_until)
+            VALUES ($1, $2, $3, $4, $5)
+        `, userID, facilityID, ipAddress, attempts, until); err != nil {
+			return nil, fmt.Errorf("recording lockout: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return lockedUntil, nil
+}
+
+// ResetFailures clears the failed login count after a successful login
+func (r *Repository) ResetFailures(ctx context.Context, userID int) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE users
+        SET failed_login_attempts = 0
+        WHERE id = $1 AND failed_login_attempts > 0
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("resetting failed logins: %w", err)
+	}
+	return nil
+}
+
+// ListByFacility returns recent lockout events for a facility, newest first
+func (r *Repository) ListByFacility(ctx context.Context, facilityCode string, since time.Time) ([]entity.AccountLockout, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT l.id, l.user_id, l.facility_id, l.ip_address, l.failed_attempts,
+               l.locked_at, l.locked_until, l.unlocked_at, l.unlocked_by,
+               u.first_name, u.last_name, u.initials
+        FROM account_lockouts l
+        JOIN users u ON u.id = l.user_id
+        JOIN facilities f ON f.id = l.facility_id
+        WHERE f.code = $1 AND l.locked_at >= $2
+        ORDER BY l.locked_at DESC
+    `, facilityCode, since)
+	if err != nil {
+		return nil, fmt.Errorf("listing lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var lockouts []entity.AccountLockout
+	for rows.Next() {
+		var l entity.AccountLockout
+		if err := rows.Scan(
+			&l.ID,
+			&l.UserID,
+			&l.FacilityID,
+			&l.IPAddress,
+			&l.FailedAttempts,
+			&l.LockedAt,
+			&l.LockedUntil,
+			&l.UnlockedAt,
+			&l.UnlockedBy,
+			&l.FirstName,
+			&l.LastName,
+			&l.Initials,
+		); err != nil {
+			return nil, fmt.Errorf("scanning lockout row: %w", err)
+		}
+		lockouts = append(lockouts, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating lockout rows: %w", err)
+	}
+
+	return lockouts, nil
+}
+
+// Unlock ends an active lockout early
+func (r *Repository) Unlock(ctx context.Context, lockoutID int, facilityCode string, unlockedBy int) (*entity.AccountLockout, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var l entity.AccountLockout
+	err = tx.QueryRow(ctx, `
+        UPDATE account_lockouts l
+        SET unlocked_at = NOW(), unlocked_by = $1
+        FROM facilities f
+        WHERE l.id = $2 AND f.id = l.facility_id AND f.code = $3
+        RETURNING l.id, l.user_id, l.facility_id, l.ip_address, l.failed_attempts,
+                  l.locked_at, l.locked_until, l.unlocked_at, l.unlocked_by
+    `, unlockedBy, lockoutID, facilityCode).Scan(
+		&l.ID,
+		&l.UserID,
+		&l.FacilityID,
+		&l.IPAddress,
+		&l.FailedAttempts,
+		&l.LockedAt,
+		&l.LockedUntil,
+		&l.UnlockedAt,
+		&l.UnlockedBy,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unlocking lockout: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+        UPDATE users
+        SET locked_until = NULL, failed_login_attempts = 0
+        WHERE id = $1
+        RETURNING first_name, last_name, initials
+    `, l.UserID).Scan(&l.FirstName, &l.LastName, &l.Initials)
+	if err != nil {
+		return nil, fmt.Errorf("unlocking account: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return &l, nil
+}
@@ -0,0 +1,264 @@
+// internal/repository/membership/repository.go
+package membership
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles the roles users hold at each facility
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new membership repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound      = fmt.Errorf("facility membership not found")
+	ErrHomeFacility  = fmt.Errorf("cannot remove a user from their home facility")
+	ErrInitialsTaken = fmt.Errorf("initials already used at facility")
+	ErrAreaNotFound  = fmt.Errorf("area not found at facility")
+	ErrRoleNotFound  = fmt.Errorf("role not found at facility")
+)
+
+const selectMembership = `
+        SELECT m.user_id, m.facility_id, m.role, m.created_at,
+               m.area_id, m.area_supervisor,
+               m.facility_role_id, COALESCE(r.name, ''), COALESCE(r.permissions, '{}'),
+               f.code, f.name, f.archived_at, COALESCE(a.name, '')
+        FROM facility_memberships m
+        JOIN facilities f ON f.id = m.facility_id
+        LEFT JOIN facility_areas a ON a.id = m.area_id
+        LEFT JOIN facility_roles r ON r.id = m.facility_role_id`
+
+// ListByUser returns every facility the user is a member of, home facility first
+func (r *Repository) ListByUser(ctx context.Context, userID int) ([]entity.FacilityMembership, error) {
+	rows, err := r.pool.Query(ctx, selectMembership+`
+        JOIN users u ON u.id = m.user_id
+        WHERE m.user_id = $1
+        ORDER BY m.facility_id = u.facility_id DESC, f.code
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing memberships: %w", err)
+	}
+	defer rows.Close()
+
+	var memberships []entity.FacilityMembership
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning membership row: %w", err)
+		}
+		memberships = append(memberships, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating membership rows: %w", err)
+	}
+
+	return memberships, nil
+}
+
+// Add grants the user a role at the facility, or changes the role they hold.
+// Initials identify users within a facility so they must not already be
+// used by another member.
+func (r *Repository) Add(ctx context.Context, userID, facilityID int, role types.UserRole) (*entity.FacilityMembership, error) {
+	var taken bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1
+            FROM users u
+            JOIN users target ON target.id = $1
+            LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = $2
+            WHERE u.id != target.id
+            AND UPPER(u.initials) = UPPER(target.initials)
+            AND (u.facility_id = $2 OR m.user_id IS NOT NULL)
+        )
+    `, userID, facilityID).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("checking initials at facility: %w", err)
+	}
+	if taken {
+		return nil, ErrInitialsTaken
+	}
+
+	_, err = r.pool.Exec(ctx, `
+        INSERT INTO facility_memberships (user_id, facility_id, role)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, facility_id) DO UPDATE
+        SET role = EXCLUDED.role,
+            updated_at = CURRENT_TIMESTAMP
+    `, userID, facilityID, role)
+	if err != nil {
+		return nil, fmt.Errorf("adding membership: %w", err)
+	}
+
+	return r.Get(ctx, userID, facilityID)
+}
+
+// Get returns the user's membership at a facility
+func (r *Repository) Get(ctx context.Context, userID, facilityID int) (*entity.FacilityMembership, error) {
+	row := r.pool.QueryRow(ctx, selectMembership+`
+        WHERE m.user_id = $1 AND m.facility_id = $2
+    `, userID, facilityID)
+	m, err := scanMembership(row)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting membership: %w", err)
+	}
+	return m, nil
+}
+
+// GetByInitials returns the membership of the user with the given initials
+// at a facility
+func (r *Repository) GetByInitials(ctx context.Context, facilityID int, initials string) (*entity.FacilityMembership, error) {
+	row := r.pool.QueryRow(ctx, selectMembership+`
+        JOIN users u ON u.id = m.user_id
+        WHERE m.facility_id = $1 AND u.initials = $2
+    `, facilityID, initials)
+	m, err := scanMembership(row)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting membership by initials: %w", err)
+	}
+	return m, nil
+}
+
+// SetArea assigns the member to one of the facility's areas, or to none when
+// areaID is nil. Only members assigned to an area can supervise it.
+func (r *Repository) SetArea(ctx context.Context, userID, facilityID int, areaID *int, supervisor bool) (*entity.FacilityMembership, error) {
+	if areaID != nil {
+		var exists bool
+		err := r.pool.QueryRow(ctx, `
+            SELECT EXISTS (
+                SELECT 1 FROM facility_areas WHERE id = $1 AND facility_id = $2
+            )
+        `, *areaID, facilityID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("checking area: %w", err)
+		}
+		if !exists {
+			return nil, ErrAreaNotFound
+		}
+	}
+
+	result, err := r.pool.Exec(ctx, `
+        UPDATE facility_memberships
+        SET area_id = $3,
+            area_supervisor = $4,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND facility_id = $2
+    `, userID, facilityID, areaID, supervisor && areaID != nil)
+	if err != nil {
+		return nil, fmt.Errorf("setting membership area: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.Get(ctx, userID, facilityID)
+}
+
+// SetFacilityRole grants the member one of the facility's roles, or removes
+// theirs when roleID is nil
+func (r *Repository) SetFacilityRole(ctx context.Context, userID, facilityID int, roleID *int) (*entity.FacilityMembership, error) {
+	if roleID != nil {
+		var exists bool
+		err := r.pool.QueryRow(ctx, `
+            SELECT EXISTS (
+                SELECT 1 FROM facility_roles WHERE id = $1 AND facility_id = $2
+            )
+        `, *roleID, facilityID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("checking role: %w", err)
+		}
+		if !exists {
+			return nil, ErrRoleNotFound
+		}
+	}
+
+	result, err := r.pool.Exec(ctx, `
+        UPDATE facility_memberships
+        SET facility_role_id = $3,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND facility_id = $2
+    `, userID, facilityID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("setting membership role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrNotFound
+	}
+
+	return r.Get(ctx, userID, facilityID)
+}
+
+// Remove revokes the user's access to a facility other than their home facility
+func (r *Repository) Remove(ctx context.Context, userID, facilityID int) error {
+	var home bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT facility_id = $2 FROM users WHERE id = $1
+    `, userID, facilityID).Scan(&home)
+	if err == pgx.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("checking home facility: %w", err)
+	}
+	if home {
+		return ErrHomeFacility
+	}
+
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM facility_memberships
+        WHERE user_id = $1 AND facility_id = $2
+    `, userID, facilityID)
+	if err != nil {
+		return fmt.Errorf("removing membership: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanMembership(row pgx.Row) (*entity.FacilityMembership, error) {
+	var m entity.FacilityMembership
+	var perms []string
+	err := row.Scan(
+		&m.UserID,
+		&m.FacilityID,
+		&m.Role,
+		&m.CreatedAt,
+		&m.AreaID,
+		&m.AreaSupervisor,
+		&m.FacilityRoleID,
+		&m.FacilityRoleName,
+		&perms,
+		&m.FacilityCode,
+		&m.FacilityName,
+		&m.FacilityArchivedAt,
+		&m.AreaName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms {
+		m.FacilityRolePerms = append(m.FacilityRolePerms, types.Permission(p))
+	}
+	return &m, nil
+}
@@ -0,0 +1,143 @@
+// internal/repository/onboarding/repository.go
+package onboarding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository tracks progress through the facility setup wizard
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new onboarding repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound = fmt.Errorf("facility onboarding not found")
+)
+
+const selectOnboarding = `
+        SELECT o.facility_id, o.step, o.started_by, o.admin_user_id,
+               o.created_at, o.updated_at, o.completed_at,
+               f.code, f.name
+        FROM facility_onboarding o
+        JOIN facilities f ON f.id = o.facility_id`
+
+// Start begins onboarding for a newly created facility
+func (r *Repository) Start(ctx context.Context, facilityID int, startedBy int) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO facility_onboarding (facility_id, step, started_by)
+        VALUES ($1, $2, $3)
+    `, facilityID, entity.OnboardingStepAdmin, startedBy)
+	if err != nil {
+		return fmt.Errorf("starting facility onboarding: %w", err)
+	}
+	return nil
+}
+
+// Get returns the onboarding progress for a facility
+func (r *Repository) Get(ctx context.Context, facilityID int) (*entity.FacilityOnboarding, error) {
+	row := r.pool.QueryRow(ctx, selectOnboarding+`
+        WHERE o.facility_id = $1
+    `, facilityID)
+	o, err := scanOnboarding(row)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting facility onboarding: %w", err)
+	}
+	return o, nil
+}
+
+// ListIncomplete returns every facility whose setup has not been finished
+func (r *Repository) ListIncomplete(ctx context.Context) ([]entity.FacilityOnboarding, error) {
+	rows, err := r.pool.Query(ctx, selectOnboarding+`
+        WHERE o.completed_at IS NULL
+        ORDER BY o.created_at DESC
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("listing facility onboarding: %w", err)
+	}
+	defer rows.Close()
+
+	var list []entity.FacilityOnboarding
+	for rows.Next() {
+		o, err := scanOnboarding(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning onboarding row: %w", err)
+		}
+		list = append(list, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating onboarding rows: %w", err)
+	}
+
+	return list, nil
+}
+
+// SetAdmin records the facility's first admin and moves on to settings
+func (r *Repository) SetAdmin(ctx context.Context, facilityID int, adminUserID int) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE facility_onboarding
+        SET admin_user_id = $1,
+            step = $2,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE facility_id = $3
+    `, adminUserID, entity.OnboardingStepSettings, facilityID)
+	if err != nil {
+		return fmt.Errorf("recording facility admin: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Advance moves onboarding to the given step, completing it at the last step
+func (r *Repository) Advance(ctx context.Context, facilityID int, step entity.OnboardingStep) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE facility_onboarding
+        SET step = $1,
+            completed_at = CASE WHEN $1 = 'complete' THEN CURRENT_TIMESTAMP END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE facility_id = $2
+    `, step, facilityID)
+	if err != nil {
+		return fmt.Errorf("advancing facility onboarding: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanOnboarding(row pgx.Row) (*entity.FacilityOnboarding, error) {
+	var o entity.FacilityOnboarding
+	err := row.Scan(
+		&o.FacilityID,
+		&o.Step,
+		&o.StartedBy,
+		&o.AdminUserID,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&o.CompletedAt,
+		&o.FacilityCode,
+		&o.FacilityName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
@@ -0,0 +1,269 @@
+// internal/repository/qualification/repository.go
+package qualification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles facility qualification catalogs and the grants held
+// by users
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new qualification repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound      = fmt.Errorf("qualification not found")
+	ErrDuplicate     = fmt.Errorf("qualification name already used at facility")
+	ErrGrantNotFound = fmt.Errorf("user does not hold qualification")
+)
+
+// ListByFacility returns the facility's catalog in kind and name order
+func (r *Repository) ListByFacility(ctx context.Context, facilityID int) ([]entity.Qualification, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT q.id, q.facility_id, q.name, q.kind, q.created_at, q.updated_at,
+               COUNT(u.id)
+        FROM qualifications q
+        LEFT JOIN user_qualifications uq ON uq.qualification_id = q.id
+            AND (uq.expires_on IS NULL OR uq.expires_on >= CURRENT_DATE)
+        LEFT JOIN users u ON u.id = uq.user_id AND u.deactivated_at IS NULL
+        WHERE q.facility_id = $1
+        GROUP BY q.id
+        ORDER BY q.kind, q.name
+    `, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("listing qualifications: %w", err)
+	}
+	defer rows.Close()
+
+	var quals []entity.Qualification
+	for rows.Next() {
+		var q entity.Qualification
+		if err := rows.Scan(&q.ID, &q.FacilityID, &q.Name, &q.Kind, &q.CreatedAt, &q.UpdatedAt, &q.HolderCount); err != nil {
+			return nil, fmt.Errorf("scanning qualification row: %w", err)
+		}
+		quals = append(quals, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating qualification rows: %w", err)
+	}
+
+	return quals, nil
+}
+
+// Create adds a qualification to the facility's catalog
+func (r *Repository) Create(ctx context.Context, facilityID int, name string, kind entity.QualificationKind) (*entity.Qualification, error) {
+	name = strings.TrimSpace(name)
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM qualifications
+            WHERE facility_id = $1 AND LOWER(name) = LOWER($2)
+        )
+    `, facilityID, name).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("checking qualification name: %w", err)
+	}
+	if exists {
+		return nil, ErrDuplicate
+	}
+
+	var q entity.Qualification
+	err = r.pool.QueryRow(ctx, `
+        INSERT INTO qualifications (facility_id, name, kind)
+        VALUES ($1, $2, $3)
+        RETURNING id, facility_id, name, kind, created_at, updated_at
+    `, facilityID, name, kind).Scan(&q.ID, &q.FacilityID, &q.Name, &q.Kind, &q.CreatedAt, &q.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating qualification: %w", err)
+	}
+	return &q, nil
+}
+
+// Get returns a qualification from the facility's catalog
+func (r *Repository) Get(ctx context.Context, facilityID, qualificationID int) (*entity.Qualification, error) {
+	var q entity.Qualification
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, facility_id, name, kind, created_at, updated_at
+        FROM qualifications
+        WHERE id = $1 AND facility_id = $2
+    `, qualificationID, facilityID).Scan(&q.ID, &q.FacilityID, &q.Name, &q.Kind, &q.CreatedAt, &q.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting qualification: %w", err)
+	}
+	return &q, nil
+}
+
+// Delete removes a qualification from the catalog along with its grants
+func (r *Repository) Delete(ctx context.Context, facilityID, qualificationID int) error {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM qualifications
+        WHERE id = $1 AND facility_id = $2
+    `, qualificationID, facilityID)
+	if err != nil {
+		return fmt.Errorf("deleting qualification: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListByUser returns the qualifications from the facility's catalog held by
+// the user, including expired ones
+func (r *Repository) ListByUser(ctx context.Context, userID, facilityID int) ([]entity.UserQualification, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT uq.user_id, uq.qualification_id, q.name, q.kind,
+               uq.granted_on, uq.expires_on, uq.granted_by
+        FROM user_qualifications uq
+        JOIN qualifications q ON q.id = uq.qualification_id
+        WHERE uq.user_id = $1 AND q.facility_id = $2
+        ORDER BY q.kind, q.name
+    `, userID, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("listing user qualifications: %w", err)
+	}
+	defer rows.Close()
+
+	var grants []entity.UserQualification
+	for rows.Next() {
+		var g entity.UserQualification
+		if err := rows.Scan(&g.UserID, &g.QualificationID, &g.Name, &g.Kind, &g.GrantedOn, &g.ExpiresOn, &g.GrantedBy); err != nil {
+			return nil, fmt.Errorf("scanning user qualification row: %w", err)
+		}
+		grants = append(grants, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating user qualification rows: %w", err)
+	}
+
+	return grants, nil
+}
+
+// Grant gives the user a qualification from the facility's catalog, or
+// renews it if they already hold it. Renewing clears any expiry warning.
+func (r *Repository) Grant(ctx context.Context, p params.GrantQualificationParams) (*entity.UserQualification, error) {
+	var g entity.UserQualification
+	err := r.pool.QueryRow(ctx, `
+        WITH granted AS (
+            INSERT INTO user_qualifications (user_id, qualification_id, granted_on, expires_on, granted_by)
+            SELECT $1, q.id, $4, $5, $6
+            FROM qualifications q
+            WHERE q.id = $2 AND q.facility_id = $3
+            ON CONFLICT (user_id, qualification_id) DO UPDATE
+            SET granted_on = EXCLUDED.granted_on,
+                expires_on = EXCLUDED.expires_on,
+                granted_by = EXCLUDED.granted_by,
+                expiry_warned_at = NULL
+            RETURNING user_id, qualification_id, granted_on, expires_on, granted_by
+        )
+        SELECT g.user_id, g.qualification_id, q.name, q.kind,
+               g.granted_on, g.expires_on, g.granted_by
+        FROM granted g
+        JOIN qualifications q ON q.id = g.qualification_id
+    `, p.UserID, p.QualificationID, p.FacilityID, p.GrantedOn, p.ExpiresOn, p.GrantedBy).Scan(
+		&g.UserID, &g.QualificationID, &g.Name, &g.Kind,
+		&g.GrantedOn, &g.ExpiresOn, &g.GrantedBy,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("granting qualification: %w", err)
+	}
+	return &g, nil
+}
+
+// Revoke removes a qualification from the user
+func (r *Repository) Revoke(ctx context.Context, userID, facilityID, qualificationID int) error {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM user_qualifications uq
+        USING qualifications q
+        WHERE q.id = uq.qualification_id
+        AND uq.user_id = $1 AND q.facility_id = $2 AND uq.qualification_id = $3
+    `, userID, facilityID, qualificationID)
+	if err != nil {
+		return fmt.Errorf("revoking qualification: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrGrantNotFound
+	}
+	return nil
+}
+
+// ListExpiring returns the grants of active facility members expiring
+// within the window that admins have not yet been warned about
+func (r *Repository) ListExpiring(ctx context.Context, within time.Duration) ([]entity.ExpiringQualification, error) {
+	days := int(within.Hours() / 24)
+	rows, err := r.pool.Query(ctx, `
+        SELECT uq.user_id, uq.qualification_id, q.name, q.kind,
+               uq.granted_on, uq.expires_on, uq.granted_by,
+               f.id, f.code, u.first_name, u.last_name, u.initials
+        FROM user_qualifications uq
+        JOIN qualifications q ON q.id = uq.qualification_id
+        JOIN facilities f ON f.id = q.facility_id
+        JOIN users u ON u.id = uq.user_id
+        LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = f.id
+        WHERE uq.expiry_warned_at IS NULL
+        AND uq.expires_on BETWEEN CURRENT_DATE AND CURRENT_DATE + $1::int
+        AND u.deactivated_at IS NULL
+        AND f.archived_at IS NULL
+        AND (u.facility_id = f.id OR m.user_id IS NOT NULL)
+        ORDER BY f.id, uq.expires_on, u.initials
+    `, days)
+	if err != nil {
+		return nil, fmt.Errorf("listing expiring qualifications: %w", err)
+	}
+	defer rows.Close()
+
+	var expiring []entity.ExpiringQualification
+	for rows.Next() {
+		var e entity.ExpiringQualification
+		err := rows.Scan(
+			&e.UserID, &e.QualificationID, &e.Name, &e.Kind,
+			&e.GrantedOn, &e.ExpiresOn, &e.GrantedBy,
+			&e.FacilityID, &e.FacilityCode, &e.FirstName, &e.LastName, &e.Initials,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning expiring qualification row: %w", err)
+		}
+		expiring = append(expiring, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating expiring qualification rows: %w", err)
+	}
+
+	return expiring, nil
+}
+
+// MarkWarned records that admins were warned about an expiring grant
+func (r *Repository) MarkWarned(ctx context.Context, userID, qualificationID int) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE user_qualifications
+        SET expiry_warned_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND qualification_id = $2
+    `, userID, qualificationID)
+	if err != nil {
+		return fmt.Errorf("marking qualification warned: %w", err)
+	}
+	return nil
+}
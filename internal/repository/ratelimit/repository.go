@@ -0,0 +1,73 @@
+// internal/repository/ratelimit/repository.go
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository persists rate limit counters so limits apply across instances
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new rate limit repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Hit records a request against a key and returns the number of hits in the
+// current window along with when the window resets. An expired window is
+// restarted atomically.
+func (r *Repository) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	var hits int
+	var expiresAt time.Time
+	err := r.pool.QueryRow(ctx, `
+        INSERT INTO rate_limit_buckets (key, hits, window_start, expires_at)
+        VALUES ($1, 1, NOW(), NOW() + $2::int * INTERVAL '1 second')
+        ON CONFLICT (key) DO UPDATE SET
+            hits = CASE
+                WHEN rate_limit_buckets.expires_at <= NOW() THEN 1
+                ELSE rate_limit_buckets.hits + 1
+            END,
+            window_start = CASE
+                WHEN rate_limit_buckets.expires_at <= NOW() THEN NOW()
+                ELSE rate_limit_buckets.window_start
+            END,
+            expires_at = CASE
+                WHEN rate_limit_buckets.expires_at <= NOW() THEN NOW() + $2::int * INTERVAL '1 second'
+                ELSE rate_limit_buckets.expires_at
+            END
+        RETURNING hits, expires_at
+    `, key, int(window.Seconds())).Scan(&hits, &expiresAt)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("recording rate limit hit: %w", err)
+	}
+	return hits, expiresAt, nil
+}
+
+// Reset clears the counter for a key
+func (r *Repository) Reset(ctx context.Context, key string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("resetting rate limit: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes counters whose window has ended
+func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM rate_limit_buckets
+        WHERE expires_at < CURRENT_TIMESTAMP
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired rate limits: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -2,10 +2,23 @@
 package repository
 
 import (
+	"github.com/DukeRupert/haven/internal/repository/area"
+	"github.com/DukeRupert/haven/internal/repository/audit"
 	"github.com/DukeRupert/haven/internal/repository/facility"
+	"github.com/DukeRupert/haven/internal/repository/impersonation"
+	"github.com/DukeRupert/haven/internal/repository/invitation"
+	"github.com/DukeRupert/haven/internal/repository/lockout"
+	"github.com/DukeRupert/haven/internal/repository/membership"
+	"github.com/DukeRupert/haven/internal/repository/onboarding"
+	"github.com/DukeRupert/haven/internal/repository/qualification"
+	"github.com/DukeRupert/haven/internal/repository/ratelimit"
+	"github.com/DukeRupert/haven/internal/repository/role"
 	"github.com/DukeRupert/haven/internal/repository/schedule"
 	"github.com/DukeRupert/haven/internal/repository/session"
+	"github.com/DukeRupert/haven/internal/repository/sso"
 	"github.com/DukeRupert/haven/internal/repository/token"
+	"github.com/DukeRupert/haven/internal/repository/transfer"
+	"github.com/DukeRupert/haven/internal/repository/twofactor"
 	"github.com/DukeRupert/haven/internal/repository/user"
 	"github.com/DukeRupert/haven/internal/repository/publication"
 )
@@ -17,6 +30,19 @@ type Repositories struct {
 	Token    *token.Repository
 	Session  *session.Repository
 	Publication *publication.Repository
+	TwoFactor   *twofactor.Repository
+	RateLimit   *ratelimit.Repository
+	Lockout     *lockout.Repository
+	SSO         *sso.Repository
+	Impersonation *impersonation.Repository
+	Invitation    *invitation.Repository
+	Onboarding    *onboarding.Repository
+	Membership    *membership.Repository
+	Area          *area.Repository
+	Transfer      *transfer.Repository
+	Qualification *qualification.Repository
+	Role          *role.Repository
+	Audit         *audit.Repository
 }
 
 func NewRepositories(db *DB) *Repositories {
@@ -26,6 +52,19 @@ func NewRepositories(db *DB) *Repositories {
 	tokenRepo := token.New(db.pool)
 	sessionRepo := session.New(db.pool)
 	publicationRepo := publication.New(db.pool)
+	twoFactorRepo := twofactor.New(db.pool)
+	rateLimitRepo := ratelimit.New(db.pool)
+	lockoutRepo := lockout.New(db.pool)
+	ssoRepo := sso.New(db.pool)
+	impersonationRepo := impersonation.New(db.pool)
+	invitationRepo := invitation.New(db.pool)
+	onboardingRepo := onboarding.New(db.pool)
+	membershipRepo := membership.New(db.pool)
+	areaRepo := area.New(db.pool)
+	transferRepo := transfer.New(db.pool)
+	qualificationRepo := qualification.New(db.pool)
+	roleRepo := role.New(db.pool)
+	auditRepo := audit.New(db.pool)
 
 	// User repository depends on facility and schedule
 	userRepo := user.New(
@@ -41,5 +80,18 @@ func NewRepositories(db *DB) *Repositories {
 		Token:    tokenRepo,
 		Session:  sessionRepo,
 		Publication: publicationRepo,
+		TwoFactor:   twoFactorRepo,
+		RateLimit:   rateLimitRepo,
+		Lockout:     lockoutRepo,
+		SSO:         ssoRepo,
+		Impersonation: impersonationRepo,
+		Invitation:    invitationRepo,
+		Onboarding:    onboardingRepo,
+		Membership:    membershipRepo,
+		Area:          areaRepo,
+		Transfer:      transferRepo,
+		Qualification: qualificationRepo,
+		Role:          roleRepo,
+		Audit:         auditRepo,
 	}
 }
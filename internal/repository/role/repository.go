@@ -0,0 +1,166 @@
+// internal/repository/role/repository.go
+package role
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles the roles facilities define for their members
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new role repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound  = fmt.Errorf("role not found")
+	ErrDuplicate = fmt.Errorf("role name already used at facility")
+)
+
+// ListByFacility returns the facility's roles in name order
+func (r *Repository) ListByFacility(ctx context.Context, facilityID int) ([]entity.FacilityRole, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT r.id, r.facility_id, r.name, r.permissions, r.created_at, r.updated_at,
+               COUNT(m.user_id)
+        FROM facility_roles r
+        LEFT JOIN facility_memberships m ON m.facility_role_id = r.id
+        WHERE r.facility_id = $1
+        GROUP BY r.id
+        ORDER BY r.name
+    `, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []entity.FacilityRole
+	for rows.Next() {
+		var fr entity.FacilityRole
+		var perms []string
+		if err := rows.Scan(&fr.ID, &fr.FacilityID, &fr.Name, &perms, &fr.CreatedAt, &fr.UpdatedAt, &fr.MemberCount); err != nil {
+			return nil, fmt.Errorf("scanning role row: %w", err)
+		}
+		fr.Permissions = toPermissions(perms)
+		roles = append(roles, fr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating role rows: %w", err)
+	}
+
+	return roles, nil
+}
+
+// Create adds a role to the facility
+func (r *Repository) Create(ctx context.Context, facilityID int, name string, perms []types.Permission) (*entity.FacilityRole, error) {
+	name = strings.TrimSpace(name)
+	if err := r.checkName(ctx, facilityID, 0, name); err != nil {
+		return nil, err
+	}
+
+	row := r.pool.QueryRow(ctx, `
+        INSERT INTO facility_roles (facility_id, name, permissions)
+        VALUES ($1, $2, $3)
+        RETURNING id, facility_id, name, permissions, created_at, updated_at
+    `, facilityID, name, fromPermissions(perms))
+	fr, err := scanRole(row)
+	if err != nil {
+		return nil, fmt.Errorf("creating role: %w", err)
+	}
+	return fr, nil
+}
+
+// Update renames a role and replaces its permissions
+func (r *Repository) Update(ctx context.Context, facilityID, roleID int, name string, perms []types.Permission) (*entity.FacilityRole, error) {
+	name = strings.TrimSpace(name)
+	if err := r.checkName(ctx, facilityID, roleID, name); err != nil {
+		return nil, err
+	}
+
+	row := r.pool.QueryRow(ctx, `
+        UPDATE facility_roles
+        SET name = $3,
+            permissions = $4
+        WHERE id = $1 AND facility_id = $2
+        RETURNING id, facility_id, name, permissions, created_at, updated_at
+    `, roleID, facilityID, name, fromPermissions(perms))
+	fr, err := scanRole(row)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating role: %w", err)
+	}
+	return fr, nil
+}
+
+// Delete removes a role; its members keep only their built-in role
+func (r *Repository) Delete(ctx context.Context, facilityID, roleID int) error {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM facility_roles
+        WHERE id = $1 AND facility_id = $2
+    `, roleID, facilityID)
+	if err != nil {
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkName returns ErrDuplicate if another of the facility's roles uses the name
+func (r *Repository) checkName(ctx context.Context, facilityID, roleID int, name string) error {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM facility_roles
+            WHERE facility_id = $1 AND id != $2 AND LOWER(name) = LOWER($3)
+        )
+    `, facilityID, roleID, name).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("checking role name: %w", err)
+	}
+	if exists {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func scanRole(row pgx.Row) (*entity.FacilityRole, error) {
+	var fr entity.FacilityRole
+	var perms []string
+	if err := row.Scan(&fr.ID, &fr.FacilityID, &fr.Name, &perms, &fr.CreatedAt, &fr.UpdatedAt); err != nil {
+		return nil, err
+	}
+	fr.Permissions = toPermissions(perms)
+	return &fr, nil
+}
+
+func toPermissions(names []string) []types.Permission {
+	perms := make([]types.Permission, 0, len(names))
+	for _, n := range names {
+		perms = append(perms, types.Permission(n))
+	}
+	return perms
+}
+
+func fromPermissions(perms []types.Permission) []string {
+	names := make([]string, 0, len(perms))
+	for _, p := range perms {
+		names = append(names, string(p))
+	}
+	return names
+}
@@ -103,7 +103,7 @@ func (r *Repository) Update(ctx context.Context, scheduleID int, params params.U
             second_weekday = $3,
             start_date = $4
         FROM users u
-        WHERE s.id = $5 AND s.user_id = u.id
+        WHERE s.id = $5 AND s.user_id = u.id AND s.end_date IS NULL
         RETURNING 
             s.id, s.created_at, s.updated_at, s.user_id,
             u.facility_id,
@@ -182,6 +182,7 @@ func (r *Repository) GetByID(ctx context.Context, id int) (*entity.Schedule, err
 	return &schedule, nil
 }
 
+// GetByUserID returns the user's open schedule
 func (r *Repository) GetByUserID(ctx context.Context, userID int) (*entity.Schedule, error) {
 	var schedule entity.Schedule
 	err := r.pool.QueryRow(ctx, `
@@ -196,7 +197,7 @@ func (r *Repository) GetByUserID(ctx context.Context, userID int) (*entity.Sched
             s.start_date
         FROM schedules s
         JOIN users u ON s.user_id = u.id
-        WHERE s.user_id = $1
+        WHERE s.user_id = $1 AND s.end_date IS NULL
     `, userID).Scan(
 		&schedule.ID,
 		&schedule.CreatedAt,
@@ -259,7 +260,7 @@ func (r *Repository) GetProtectedDateByID(ctx context.Context, id int) (entity.P
    return date, nil
 }
 
-func (r *Repository) GetProtectedDatesByFacilityCode(ctx context.Context, facilityCode string) ([]entity.PD, error) {
+func (r *Repository) GetProtectedDatesByFacilityCode(ctx context.Context, facilityCode string, areaID, qualificationID *int) ([]entity.PD, error) {
 	rows, err := r.pool.Query(ctx, `
         SELECT 
             pd.id, pd.created_at, pd.updated_at,
@@ -270,9 +271,17 @@ func (r *Repository) GetProtectedDatesByFacilityCode(ctx context.Context, facili
         FROM protected_dates pd
         JOIN facilities f ON pd.facility_id = f.id
         JOIN users u ON pd.user_id = u.id
+        LEFT JOIN facility_memberships m ON m.user_id = pd.user_id AND m.facility_id = pd.facility_id
         WHERE f.code = $1
+        AND ($2::int IS NULL OR m.area_id = $2)
+        AND ($3::int IS NULL OR EXISTS (
+            SELECT 1 FROM user_qualifications uq
+            WHERE uq.user_id = pd.user_id
+            AND uq.qualification_id = $3
+            AND (uq.expires_on IS NULL OR uq.expires_on >= pd.date)
+        ))
         ORDER BY pd.date ASC, pd.user_id
-    `, facilityCode)
+    `, facilityCode, areaID, qualificationID)
 	if err != nil {
 		return nil, fmt.Errorf("getting protected dates: %w", err)
 	}
@@ -358,7 +367,7 @@ func (r *Repository) ToggleProtectedDateAvailability(ctx context.Context, dateID
 func (r *Repository) hasSchedule(ctx context.Context, userID int) (bool, error) {
 	var exists bool
 	err := r.pool.QueryRow(ctx, `
-        SELECT EXISTS(SELECT 1 FROM schedules WHERE user_id = $1)
+        SELECT EXISTS(SELECT 1 FROM schedules WHERE user_id = $1 AND end_date IS NULL)
     `, userID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("checking schedule existence: %w", err)
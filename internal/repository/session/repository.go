@@ -17,6 +17,7 @@ type CreateParams struct {
 	Key       string
 	Data      []byte
 	ExpiresOn time.Time
+	UserID    *int
 	IsNew     bool
 }
 
@@ -46,6 +47,10 @@ func (r *Repository) CreateSessionsTable() error {
 			created_on TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
 			modified_on TIMESTAMPTZ,
 			expires_on TIMESTAMPTZ,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			ip_address TEXT,
+			user_agent TEXT,
+			last_seen_on TIMESTAMPTZ,
 			CONSTRAINT http_sessions_key_key UNIQUE (key)
 		);
 		CREATE INDEX IF NOT EXISTS http_sessions_expiry_idx ON http_sessions (expires_on);
@@ -89,18 +94,18 @@ func (r *Repository) Save(ctx context.Context, params CreateParams) error {
 
 	if params.IsNew {
 		query = `
-            INSERT INTO http_sessions (key, data, created_on, modified_on, expires_on)
-            VALUES ($1, $2, $3, $4, $5)
+            INSERT INTO http_sessions (key, data, created_on, modified_on, expires_on, user_id)
+            VALUES ($1, $2, $3, $4, $5, $6)
         `
-		args = []interface{}{params.Key, params.Data, now, now, params.ExpiresOn}
+		args = []interface{}{params.Key, params.Data, now, now, params.ExpiresOn, params.UserID}
 		log.Debug().Msg("inserting new session")
 	} else {
 		query = `
             UPDATE http_sessions 
-            SET data = $1, modified_on = $2, expires_on = $3 
-            WHERE key = $4
+            SET data = $1, modified_on = $2, expires_on = $3, user_id = $4
+            WHERE key = $5
         `
-		args = []interface{}{params.Data, now, params.ExpiresOn, params.Key}
+		args = []interface{}{params.Data, now, params.ExpiresOn, params.UserID, params.Key}
 		log.Debug().Msg("updating existing session")
 	}
 
@@ -146,6 +151,152 @@ func (r *Repository) Load(ctx context.Context, key string) (*entity.HTTPSession,
 	return &sess, nil
 }
 
+// DeleteByUserID removes every session belonging to a user, signing them out
+// on all devices
+func (r *Repository) DeleteByUserID(ctx context.Context, userID int) (int64, error) {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM http_sessions
+        WHERE user_id = $1
+    `, userID)
+	if err != nil {
+		return 0, fmt.Errorf("deleting sessions for user: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteOthersByUserID removes every session belonging to a user except the
+// one with the given key, signing them out on their other devices
+func (r *Repository) DeleteOthersByUserID(ctx context.Context, userID int, keepKey string) (int64, error) {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM http_sessions
+        WHERE user_id = $1 AND key <> $2
+    `, userID, keepKey)
+	if err != nil {
+		return 0, fmt.Errorf("deleting other sessions for user: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// ListUnlinked returns unexpired sessions not linked to a user, such as those
+// saved before sessions recorded their user
+func (r *Repository) ListUnlinked(ctx context.Context) ([]entity.HTTPSession, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, key, data
+        FROM http_sessions
+        WHERE user_id IS NULL AND (expires_on IS NULL OR expires_on > NOW())
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("listing unlinked sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []entity.HTTPSession
+	for rows.Next() {
+		var sess entity.HTTPSession
+		if err := rows.Scan(&sess.ID, &sess.Key, &sess.Data); err != nil {
+			return nil, fmt.Errorf("scanning session row: %w", err)
+		}
+		list = append(list, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating session rows: %w", err)
+	}
+
+	return list, nil
+}
+
+// Link records the user a session belongs to if it has none yet
+func (r *Repository) Link(ctx context.Context, key string, userID int) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE http_sessions
+        SET user_id = $1
+        WHERE key = $2 AND user_id IS NULL
+    `, userID, key)
+	if err != nil {
+		return fmt.Errorf("linking session: %w", err)
+	}
+	return nil
+}
+
+// Touch records the client and time of the latest authenticated request
+func (r *Repository) Touch(ctx context.Context, key, ipAddress, userAgent string) error {
+	_, err := r.pool.Exec(ctx, `
+        UPDATE http_sessions
+        SET ip_address = $1, user_agent = $2, last_seen_on = NOW()
+        WHERE key = $3
+    `, ipAddress, userAgent, key)
+	if err != nil {
+		return fmt.Errorf("touching session: %w", err)
+	}
+	return nil
+}
+
+// ListByUserID returns a user's unexpired sessions, most recently used first
+func (r *Repository) ListByUserID(ctx context.Context, userID int) ([]entity.HTTPSession, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, key, created_on, modified_on, expires_on, user_id,
+               COALESCE(ip_address, ''), COALESCE(user_agent, ''), last_seen_on
+        FROM http_sessions
+        WHERE user_id = $1 AND (expires_on IS NULL OR expires_on > NOW())
+        ORDER BY COALESCE(last_seen_on, created_on) DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions for user: %w", err)
+	}
+	defer rows.Close()
+
+	var list []entity.HTTPSession
+	for rows.Next() {
+		var sess entity.HTTPSession
+		if err := rows.Scan(
+			&sess.ID,
+			&sess.Key,
+			&sess.CreatedOn,
+			&sess.ModifiedOn,
+			&sess.ExpiresOn,
+			&sess.UserID,
+			&sess.IPAddress,
+			&sess.UserAgent,
+			&sess.LastSeenOn,
+		); err != nil {
+			return nil, fmt.Errorf("scanning session row: %w", err)
+		}
+		list = append(list, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating session rows: %w", err)
+	}
+
+	return list, nil
+}
+
+// DeleteForUser removes a single session, provided it belongs to the user
+func (r *Repository) DeleteForUser(ctx context.Context, id int64, userID int) error {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM http_sessions
+        WHERE id = $1 AND user_id = $2
+    `, id, userID)
+	if err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes sessions past their expiry
+func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM http_sessions
+        WHERE expires_on < CURRENT_TIMESTAMP
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired sessions: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
 func (r *Repository) destroy(ctx context.Context, session *sessions.Session) error {
 	logger := zerolog.Ctx(ctx).With().
 		Str("method", "destroy").
@@ -0,0 +1,196 @@
+// internal/repository/sso/repository.go
+package sso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository stores single sign-on providers and linked identities
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new single sign-on repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound = fmt.Errorf("not found")
+)
+
+// selectProvider reads a provider row; client_secret is returned sealed
+const selectProvider = `
+        SELECT id, facility_id, issuer_url, client_id, client_secret, enabled, created_at, updated_at
+        FROM oidc_providers`
+
+// GetProviderByFacilityID returns the facility's enabled provider. Providers
+// whose secret has not been sealed yet are ignored.
+func (r *Repository) GetProviderByFacilityID(ctx context.Context, facilityID int) (*entity.OIDCProvider, error) {
+	p, err := scanProvider(r.pool.QueryRow(ctx, selectProvider+`
+        WHERE facility_id = $1 AND enabled = true AND secret_sealed
+    `, facilityID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting oidc provider: %w", err)
+	}
+	return p, nil
+}
+
+// GetProvider returns the facility's provider whether or not it is enabled
+func (r *Repository) GetProvider(ctx context.Context, facilityID int) (*entity.OIDCProvider, error) {
+	p, err := scanProvider(r.pool.QueryRow(ctx, selectProvider+`
+        WHERE facility_id = $1
+    `, facilityID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting oidc provider: %w", err)
+	}
+	return p, nil
+}
+
+// SaveProvider creates or replaces the facility's provider. ClientSecret must
+// already be sealed; an empty secret keeps the stored one.
+func (r *Repository) SaveProvider(ctx context.Context, p entity.OIDCProvider) (*entity.OIDCProvider, error) {
+	saved, err := scanProvider(r.pool.QueryRow(ctx, `
+        INSERT INTO oidc_providers (facility_id, issuer_url, client_id, client_secret, enabled, secret_sealed)
+        VALUES ($1, $2, $3, $4, $5, true)
+        ON CONFLICT (facility_id) DO UPDATE SET
+            issuer_url = EXCLUDED.issuer_url,
+            client_id = EXCLUDED.client_id,
+            client_secret = CASE WHEN EXCLUDED.client_secret = ''
+                THEN oidc_providers.client_secret ELSE EXCLUDED.client_secret END,
+            secret_sealed = CASE WHEN EXCLUDED.client_secret = ''
+                THEN oidc_providers.secret_sealed ELSE true END,
+            enabled = EXCLUDED.enabled,
+            updated_at = CURRENT_TIMESTAMP
+        RETURNING id, facility_id, issuer_url, client_id, client_secret, enabled, created_at, updated_at
+    `, p.FacilityID, p.IssuerURL, p.ClientID, p.ClientSecret, p.Enabled))
+	if err != nil {
+		return nil, fmt.Errorf("saving oidc provider: %w", err)
+	}
+	return saved, nil
+}
+
+// DeleteProvider removes the facility's provider so its users fall back to
+// the global provider
+func (r *Repository) DeleteProvider(ctx context.Context, facilityID int) error {
+	tag, err := r.pool.Exec(ctx, `
+        DELETE FROM oidc_providers
+        WHERE facility_id = $1
+    `, facilityID)
+	if err != nil {
+		return fmt.Errorf("deleting oidc provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SealSecrets encrypts client secrets stored in plain text before sealing
+// was introduced, returning how many were sealed
+func (r *Repository) SealSecrets(ctx context.Context, seal func(string) (string, error)) (int, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, client_secret
+        FROM oidc_providers
+        WHERE NOT secret_sealed
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("listing unsealed oidc secrets: %w", err)
+	}
+	type unsealed struct {
+		id     int
+		secret string
+	}
+	var pending []unsealed
+	for rows.Next() {
+		var u unsealed
+		if err := rows.Scan(&u.id, &u.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning unsealed oidc secret: %w", err)
+		}
+		pending = append(pending, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterating unsealed oidc secrets: %w", err)
+	}
+
+	for _, u := range pending {
+		sealed, err := seal(u.secret)
+		if err != nil {
+			return 0, fmt.Errorf("sealing oidc secret: %w", err)
+		}
+		_, err = r.pool.Exec(ctx, `
+            UPDATE oidc_providers
+            SET client_secret = $2, secret_sealed = true
+            WHERE id = $1 AND NOT secret_sealed
+        `, u.id, sealed)
+		if err != nil {
+			return 0, fmt.Errorf("storing sealed oidc secret: %w", err)
+		}
+	}
+	return len(pending), nil
+}
+
+// GetUserIDByIdentity returns the user linked to an external identity
+func (r *Repository) GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	var userID int
+	err := r.pool.QueryRow(ctx, `
+        SELECT user_id
+        FROM user_identities
+        WHERE issuer = $1 AND subject = $2
+    `, issuer, subject).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting user identity: %w", err)
+	}
+	return userID, nil
+}
+
+// LinkIdentity records a sign in with an external identity, linking it to
+// the user the first time it is seen
+func (r *Repository) LinkIdentity(ctx context.Context, userID int, issuer, subject string) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO user_identities (user_id, issuer, subject, last_login_at)
+        VALUES ($1, $2, $3, NOW())
+        ON CONFLICT (issuer, subject) DO UPDATE SET last_login_at = NOW()
+    `, userID, issuer, subject)
+	if err != nil {
+		return fmt.Errorf("linking user identity: %w", err)
+	}
+	return nil
+}
+
+func scanProvider(row pgx.Row) (*entity.OIDCProvider, error) {
+	var p entity.OIDCProvider
+	err := row.Scan(
+		&p.ID,
+		&p.FacilityID,
+		&p.IssuerURL,
+		&p.ClientID,
+		&p.ClientSecret,
+		&p.Enabled,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -65,7 +65,7 @@ func (r *Repository) GetVerificationToken(ctx context.Context, token string) (*e
 	err := r.pool.QueryRow(ctx, `
         SELECT user_id, token, email, created_at, expires_at, used
         FROM verification_tokens
-        WHERE token = $1
+        WHERE token = $1 AND purpose = 'registration'
     `, token).Scan(&vt.UserID, &vt.Token, &vt.Email, &vt.CreatedAt, &vt.ExpiresAt, &vt.Used)
 
 	if err == pgx.ErrNoRows {
@@ -91,7 +91,7 @@ func (r *Repository) MarkAsUsed(ctx context.Context, token string) error {
 	result, err := r.pool.Exec(ctx, `
         UPDATE verification_tokens
         SET used = true
-        WHERE token = $1 AND NOT used
+        WHERE token = $1 AND NOT used AND purpose = 'registration'
     `, token)
 	if err != nil {
 		return fmt.Errorf("marking token as used: %w", err)
@@ -103,6 +103,162 @@ func (r *Repository) MarkAsUsed(ctx context.Context, token string) error {
 	return nil
 }
 
+// StoreEmailChange saves a pending email change. Any earlier change the user
+// has not confirmed is superseded.
+func (r *Repository) StoreEmailChange(ctx context.Context, ect *entity.EmailChangeToken) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+        UPDATE verification_tokens
+        SET used = true
+        WHERE user_id = $1 AND purpose = 'email_change' AND NOT used
+    `, ect.UserID)
+	if err != nil {
+		return fmt.Errorf("superseding email change tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO verification_tokens (user_id, token, cancel_token, email, expires_at, purpose)
+        VALUES ($1, $2, $3, $4, $5, 'email_change')
+    `, ect.UserID, ect.Token, ect.CancelToken, ect.Email, ect.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("storing email change token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ConsumeEmailChange marks an email change token as used and returns the
+// change it confirms
+func (r *Repository) ConsumeEmailChange(ctx context.Context, token string) (*entity.EmailChangeToken, error) {
+	ect := &entity.EmailChangeToken{}
+	err := r.pool.QueryRow(ctx, `
+        UPDATE verification_tokens
+        SET used = true
+        WHERE token = $1
+        AND purpose = 'email_change'
+        AND NOT used
+        AND expires_at > NOW()
+        RETURNING user_id, token, cancel_token, email, created_at, expires_at
+    `, token).Scan(&ect.UserID, &ect.Token, &ect.CancelToken, &ect.Email, &ect.CreatedAt, &ect.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("consuming email change token: %w", err)
+	}
+	return ect, nil
+}
+
+// CancelEmailChange voids a pending email change using the token sent to the
+// previous address and returns the affected user ID
+func (r *Repository) CancelEmailChange(ctx context.Context, cancelToken string) (int, error) {
+	var userID int
+	err := r.pool.QueryRow(ctx, `
+        UPDATE verification_tokens
+        SET used = true
+        WHERE cancel_token = $1
+        AND purpose = 'email_change'
+        AND NOT used
+        RETURNING user_id
+    `, cancelToken).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cancelling email change: %w", err)
+	}
+	return userID, nil
+}
+
+// StorePasswordReset saves a new password reset token
+func (r *Repository) StorePasswordReset(ctx context.Context, prt *entity.PasswordResetToken) error {
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO password_reset_tokens (user_id, token, expires_at)
+        VALUES ($1, $2, $3)
+    `, prt.UserID, prt.Token, prt.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("storing password reset token: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetToken retrieves a password reset token if it is unused and not expired
+func (r *Repository) GetPasswordResetToken(ctx context.Context, token string) (*entity.PasswordResetToken, error) {
+	prt := &entity.PasswordResetToken{}
+	err := r.pool.QueryRow(ctx, `
+        SELECT user_id, token, created_at, expires_at, used
+        FROM password_reset_tokens
+        WHERE token = $1
+    `, token).Scan(&prt.UserID, &prt.Token, &prt.CreatedAt, &prt.ExpiresAt, &prt.Used)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting password reset token: %w", err)
+	}
+
+	if prt.Used {
+		return nil, ErrTokenUsed
+	}
+
+	if time.Now().After(prt.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return prt, nil
+}
+
+// ConsumePasswordReset marks a password reset token as used and returns the
+// associated user ID. Any other outstanding reset tokens for the user are
+// invalidated at the same time.
+func (r *Repository) ConsumePasswordReset(ctx context.Context, token string) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+        UPDATE password_reset_tokens
+        SET used = true
+        WHERE token = $1
+        AND NOT used
+        AND expires_at > NOW()
+        RETURNING user_id
+    `, token).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrInvalidToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("consuming password reset token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE password_reset_tokens
+        SET used = true
+        WHERE user_id = $1 AND NOT used
+    `, userID)
+	if err != nil {
+		return 0, fmt.Errorf("invalidating password reset tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return userID, nil
+}
+
 // Verify checks if a registration token is valid and not expired, returns associated user ID
 func (r *Repository) Verify(ctx context.Context, token string) (int, error) {
 	var userID int
@@ -137,7 +293,7 @@ func (r *Repository) Delete(ctx context.Context, token string) error {
 	return nil
 }
 
-// DeleteExpired removes all expired tokens from both tables
+// DeleteExpired removes all expired tokens from every token table
 func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
@@ -163,9 +319,18 @@ func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
 		return 0, fmt.Errorf("deleting expired verification tokens: %w", err)
 	}
 
+	// Delete from password_reset_tokens
+	resetResult, err := tx.Exec(ctx, `
+        DELETE FROM password_reset_tokens
+        WHERE expires_at < CURRENT_TIMESTAMP OR used = true
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired password reset tokens: %w", err)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return 0, fmt.Errorf("committing transaction: %w", err)
 	}
 
-	return regResult.RowsAffected() + verResult.RowsAffected(), nil
+	return regResult.RowsAffected() + verResult.RowsAffected() + resetResult.RowsAffected(), nil
 }
@@ -0,0 +1,367 @@
+// internal/repository/transfer/repository.go
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles moving users between facilities
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new transfer repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrUserNotFound  = fmt.Errorf("user not found")
+	ErrNotFound      = fmt.Errorf("pending transfer not found")
+	ErrSameFacility  = fmt.Errorf("user is already based at facility")
+	ErrInitialsTaken = fmt.Errorf("initials already used at facility")
+	ErrPending       = fmt.Errorf("user already has a pending transfer")
+	ErrCancelled     = fmt.Errorf("user is no longer based at the facility they were transferring from")
+)
+
+// Protected days fall on every third occurrence of a schedule's weekdays, so
+// the pattern repeats every three weeks
+const protectedCycleDays = 21
+
+const transferColumns = `
+    id, user_id, from_facility_id, to_facility_id, COALESCE(role::text, ''),
+    effective_date, transferred_by, created_at, applied_at, cancelled_at`
+
+// Schedule records a transfer of the user's home facility to take effect on
+// the effective date. Nothing changes for the user until it is applied.
+func (r *Repository) Schedule(ctx context.Context, p params.TransferUserParams) (*entity.Transfer, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var fromFacilityID int
+	err = tx.QueryRow(ctx, `
+        SELECT facility_id FROM users WHERE id = $1 FOR UPDATE
+    `, p.UserID).Scan(&fromFacilityID)
+	if err == pgx.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting user facility: %w", err)
+	}
+	if fromFacilityID == p.ToFacilityID {
+		return nil, ErrSameFacility
+	}
+
+	if err := checkInitials(ctx, tx, p.UserID, p.ToFacilityID); err != nil {
+		return nil, err
+	}
+
+	t, err := scanTransfer(tx.QueryRow(ctx, `
+        INSERT INTO user_transfers (user_id, from_facility_id, to_facility_id, role, effective_date, transferred_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING`+transferColumns,
+		p.UserID, fromFacilityID, p.ToFacilityID, p.Role, p.EffectiveDate, p.TransferredBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrPending
+		}
+		return nil, fmt.Errorf("recording transfer: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return t, nil
+}
+
+// GetPending returns the user's transfer waiting for its effective date
+func (r *Repository) GetPending(ctx context.Context, userID int) (*entity.Transfer, error) {
+	t, err := scanTransfer(r.pool.QueryRow(ctx, `
+        SELECT`+transferColumns+`
+        FROM user_transfers
+        WHERE user_id = $1 AND applied_at IS NULL AND cancelled_at IS NULL
+    `, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting pending transfer: %w", err)
+	}
+	return t, nil
+}
+
+// ListDue returns pending transfers whose effective date has arrived in the
+// time zone of the facility the user is leaving
+func (r *Repository) ListDue(ctx context.Context) ([]entity.Transfer, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT`+transferColumns+`
+        FROM user_transfers t
+        LEFT JOIN facility_settings s ON s.facility_id = t.from_facility_id
+        WHERE t.applied_at IS NULL AND t.cancelled_at IS NULL
+        AND t.effective_date <= (NOW() AT TIME ZONE COALESCE(s.time_zone, 'UTC'))::date
+        ORDER BY t.effective_date, t.id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("listing due transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []entity.Transfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning transfer: %w", err)
+		}
+		transfers = append(transfers, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// Apply moves the user's home facility for a pending transfer. Their open
+// schedule is closed on the effective date, keeping earlier protected dates
+// at the old facility, and a schedule with the same weekdays and three week
+// cycle continues at the new facility, carrying over availability already
+// given. Their role at the old facility is replaced by the transfer's role.
+// A transfer whose user has since left the old facility is cancelled and
+// ErrCancelled returned.
+func (r *Repository) Apply(ctx context.Context, transferID int) (*entity.Transfer, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := scanTransfer(tx.QueryRow(ctx, `
+        SELECT`+transferColumns+`
+        FROM user_transfers
+        WHERE id = $1 AND applied_at IS NULL AND cancelled_at IS NULL
+        FOR UPDATE
+    `, transferID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting transfer: %w", err)
+	}
+
+	var (
+		facilityID  int
+		deactivated bool
+	)
+	err = tx.QueryRow(ctx, `
+        SELECT facility_id, deactivated_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE
+    `, t.UserID).Scan(&facilityID, &deactivated)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("getting user facility: %w", err)
+	}
+	if err == pgx.ErrNoRows || deactivated || facilityID != t.FromFacilityID {
+		if _, err := tx.Exec(ctx, `
+            UPDATE user_transfers SET cancelled_at = CURRENT_TIMESTAMP WHERE id = $1
+        `, t.ID); err != nil {
+			return nil, fmt.Errorf("cancelling transfer: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("committing transaction: %w", err)
+		}
+		return nil, ErrCancelled
+	}
+
+	// Someone may have taken the initials since the transfer was scheduled
+	if err := checkInitials(ctx, tx, t.UserID, t.ToFacilityID); err != nil {
+		return nil, err
+	}
+
+	// Close the open schedule; the trigger leaves closed schedules alone so
+	// its dates stay put until moved below
+	var (
+		scheduleID                  int
+		firstWeekday, secondWeekday int
+		startDate                   time.Time
+	)
+	err = tx.QueryRow(ctx, `
+        UPDATE schedules
+        SET end_date = $2,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE user_id = $1 AND end_date IS NULL
+        RETURNING id, first_weekday, second_weekday, start_date
+    `, t.UserID, t.EffectiveDate).Scan(&scheduleID, &firstWeekday, &secondWeekday, &startDate)
+	hasSchedule := err == nil
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("closing schedule: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE users
+        SET facility_id = $2,
+            role = $3,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+    `, t.UserID, t.ToFacilityID, t.Role)
+	if err != nil {
+		return nil, fmt.Errorf("moving user: %w", err)
+	}
+
+	// The old home membership goes with the old facility
+	_, err = tx.Exec(ctx, `
+        DELETE FROM facility_memberships
+        WHERE user_id = $1 AND facility_id = $2
+    `, t.UserID, t.FromFacilityID)
+	if err != nil {
+		return nil, fmt.Errorf("removing old home membership: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+        INSERT INTO facility_memberships (user_id, facility_id, role)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, facility_id) DO UPDATE
+        SET role = EXCLUDED.role,
+            updated_at = CURRENT_TIMESTAMP
+    `, t.UserID, t.ToFacilityID, t.Role)
+	if err != nil {
+		return nil, fmt.Errorf("adding new home membership: %w", err)
+	}
+
+	if hasSchedule {
+		if err := continueSchedule(ctx, tx, t, scheduleID, firstWeekday, secondWeekday, startDate); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+        UPDATE user_transfers
+        SET applied_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+        RETURNING applied_at
+    `, t.ID).Scan(&t.AppliedAt)
+	if err != nil {
+		return nil, fmt.Errorf("marking transfer applied: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return t, nil
+}
+
+// continueSchedule starts a schedule at the new facility that keeps the old
+// one's three week cycle, then replaces the old schedule's protected dates
+// from the effective date with it
+func continueSchedule(ctx context.Context, tx pgx.Tx, t *entity.Transfer, oldScheduleID, firstWeekday, secondWeekday int, oldStart time.Time) error {
+	start := continuedStart(oldStart, t.EffectiveDate)
+
+	// The trigger generates the new schedule's dates at the new facility
+	var newScheduleID int
+	err := tx.QueryRow(ctx, `
+        INSERT INTO schedules (user_id, first_weekday, second_weekday, start_date)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, t.UserID, firstWeekday, secondWeekday, start).Scan(&newScheduleID)
+	if err != nil {
+		return fmt.Errorf("starting schedule at new facility: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM protected_dates
+        WHERE schedule_id = $1 AND date < $2
+    `, newScheduleID, t.EffectiveDate)
+	if err != nil {
+		return fmt.Errorf("removing dates before transfer: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        UPDATE protected_dates n
+        SET available = o.available
+        FROM protected_dates o
+        WHERE n.schedule_id = $1
+        AND o.schedule_id = $2
+        AND o.date = n.date
+    `, newScheduleID, oldScheduleID)
+	if err != nil {
+		return fmt.Errorf("carrying over availability: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        DELETE FROM protected_dates
+        WHERE schedule_id = $1 AND date >= $2
+    `, oldScheduleID, t.EffectiveDate)
+	if err != nil {
+		return fmt.Errorf("removing protected dates after transfer: %w", err)
+	}
+	return nil
+}
+
+// continuedStart returns the start of the last three week cycle of a
+// schedule begun on oldStart that starts on or before the effective date.
+// Starting a whole number of cycles after the old schedule keeps every
+// protected day where it was.
+func continuedStart(oldStart, effective time.Time) time.Time {
+	days := int(effective.Sub(oldStart).Hours() / 24)
+	if days <= 0 {
+		return oldStart
+	}
+	return oldStart.AddDate(0, 0, days/protectedCycleDays*protectedCycleDays)
+}
+
+// checkInitials returns ErrInitialsTaken if anyone at the facility already
+// uses the user's initials
+func checkInitials(ctx context.Context, tx pgx.Tx, userID, facilityID int) error {
+	var taken bool
+	err := tx.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1
+            FROM users u
+            JOIN users target ON target.id = $1
+            LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = $2
+            WHERE u.id != target.id
+            AND UPPER(u.initials) = UPPER(target.initials)
+            AND (u.facility_id = $2 OR m.user_id IS NOT NULL)
+        )
+    `, userID, facilityID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("checking initials at facility: %w", err)
+	}
+	if taken {
+		return ErrInitialsTaken
+	}
+	return nil
+}
+
+func scanTransfer(row pgx.Row) (*entity.Transfer, error) {
+	var t entity.Transfer
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.FromFacilityID,
+		&t.ToFacilityID,
+		&t.Role,
+		&t.EffectiveDate,
+		&t.TransferredBy,
+		&t.CreatedAt,
+		&t.AppliedAt,
+		&t.CancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
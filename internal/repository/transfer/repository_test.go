@@ -0,0 +1,39 @@
+// internal/repository/transfer/repository_test.go
+package transfer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContinuedStart(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	oldStart := date(2025, 1, 6)
+
+	tests := []struct {
+		name      string
+		effective time.Time
+		want      time.Time
+	}{
+		{"before the old schedule", date(2025, 1, 1), oldStart},
+		{"on the old start", oldStart, oldStart},
+		{"within the first cycle", date(2025, 1, 20), oldStart},
+		{"on a cycle boundary", date(2025, 1, 27), date(2025, 1, 27)},
+		{"within a later cycle", date(2025, 3, 1), date(2025, 2, 17)},
+		{"a year on", date(2026, 1, 6), date(2025, 12, 29)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := continuedStart(oldStart, tt.effective)
+			if !got.Equal(tt.want) {
+				t.Errorf("continuedStart() = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+			if days := int(got.Sub(oldStart).Hours() / 24); days%protectedCycleDays != 0 {
+				t.Errorf("continuedStart() is %d days after the old start, not a whole number of cycles", days)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// internal/repository/twofactor/repository.go
+package twofactor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repository handles two-factor enrollment and recovery code operations
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// New creates a new two-factor repository
+func New(pool *pgxpool.Pool) *Repository {
+	return &Repository{
+		pool: pool,
+	}
+}
+
+// Common errors
+var (
+	ErrNotFound       = fmt.Errorf("user not found")
+	ErrAlreadyEnabled = fmt.Errorf("two-factor already enabled")
+	ErrCodeReused     = fmt.Errorf("two-factor code already used")
+)
+
+// Get returns the two-factor enrollment state for a user. The secret is
+// returned sealed, and is empty until a plain text secret has been sealed.
+func (r *Repository) Get(ctx context.Context, userID int) (*entity.TwoFactor, error) {
+	var tf entity.TwoFactor
+	var secret *string
+	err := r.pool.QueryRow(ctx, `
+        SELECT id, CASE WHEN totp_secret_sealed THEN totp_secret END, totp_enabled
+        FROM users
+        WHERE id = $1
+    `, userID).Scan(&tf.UserID, &secret, &tf.Enabled)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting two-factor settings: %w", err)
+	}
+	if secret != nil {
+		tf.Secret = *secret
+	}
+	return &tf, nil
+}
+
+// IsEnabled reports whether a user has completed two-factor enrollment
+func (r *Repository) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	var enabled bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT totp_enabled FROM users WHERE id = $1
+    `, userID).Scan(&enabled)
+	if err == pgx.ErrNoRows {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking two-factor status: %w", err)
+	}
+	return enabled, nil
+}
+
+// SetPendingSecret stores a sealed secret for an enrollment that has not
+// been confirmed yet
+func (r *Repository) SetPendingSecret(ctx context.Context, userID int, secret string) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE users
+        SET totp_secret = $1, totp_secret_sealed = true, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2 AND NOT totp_enabled
+    `, secret, userID)
+	if err != nil {
+		return fmt.Errorf("storing pending two-factor secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		enabled, err := r.IsEnabled(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			return ErrAlreadyEnabled
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UseStep records the time step of an accepted TOTP code, returning
+// ErrCodeReused unless it is later than the last one accepted
+func (r *Repository) UseStep(ctx context.Context, userID int, step int64) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE users
+        SET totp_last_step = $2
+        WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+    `, userID, step)
+	if err != nil {
+		return fmt.Errorf("recording two-factor code: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCodeReused
+	}
+	return nil
+}
+
+// SealSecrets encrypts TOTP secrets stored in plain text before sealing
+// was introduced, returning how many were sealed
+func (r *Repository) SealSecrets(ctx context.Context, seal func(string) (string, error)) (int, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, totp_secret
+        FROM users
+        WHERE totp_secret IS NOT NULL AND NOT totp_secret_sealed
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("listing unsealed totp secrets: %w", err)
+	}
+	type unsealed struct {
+		id     int
+		secret string
+	}
+	var pending []unsealed
+	for rows.Next() {
+		var u unsealed
+		if err := rows.Scan(&u.id, &u.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning unsealed totp secret: %w", err)
+		}
+		pending = append(pending, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterating unsealed totp secrets: %w", err)
+	}
+
+	for _, u := range pending {
+		sealed, err := seal(u.secret)
+		if err != nil {
+			return 0, fmt.Errorf("sealing totp secret: %w", err)
+		}
+		_, err = r.pool.Exec(ctx, `
+            UPDATE users
+            SET totp_secret = $2, totp_secret_sealed = true
+            WHERE id = $1 AND NOT totp_secret_sealed
+        `, u.id, sealed)
+		if err != nil {
+			return 0, fmt.Errorf("storing sealed totp secret: %w", err)
+		}
+	}
+	return len(pending), nil
+}
+
+// Enable confirms enrollment and replaces the user's recovery codes
+func (r *Repository) Enable(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+        UPDATE users
+        SET totp_enabled = true, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND totp_secret IS NOT NULL AND totp_secret_sealed
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("enabling two-factor: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes discards any existing recovery codes and stores new ones
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Disable removes the user's secret and recovery codes
+func (r *Repository) Disable(ctx context.Context, userID int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+        UPDATE users
+        SET totp_secret = NULL, totp_secret_sealed = false, totp_enabled = false, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("disabling two-factor: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("deleting recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ListUnusedRecoveryCodes returns the recovery codes that have not been redeemed
+func (r *Repository) ListUnusedRecoveryCodes(ctx context.Context, userID int) ([]entity.RecoveryCode, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, user_id, code_hash, created_at, used_at
+        FROM user_recovery_codes
+        WHERE user_id = $1 AND used_at IS NULL
+        ORDER BY id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []entity.RecoveryCode
+	for rows.Next() {
+		var rc entity.RecoveryCode
+		if err := rows.Scan(&rc.ID, &rc.UserID, &rc.CodeHash, &rc.CreatedAt, &rc.UsedAt); err != nil {
+			return nil, fmt.Errorf("scanning recovery code: %w", err)
+		}
+		codes = append(codes, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode marks a recovery code as redeemed
+func (r *Repository) UseRecoveryCode(ctx context.Context, id int) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE user_recovery_codes
+        SET used_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND used_at IS NULL
+    `, id)
+	if err != nil {
+		return fmt.Errorf("using recovery code: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("deleting recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, `
+            INSERT INTO user_recovery_codes (user_id, code_hash)
+            VALUES ($1, $2)
+        `, userID, hash); err != nil {
+			return fmt.Errorf("storing recovery code: %w", err)
+		}
+	}
+	return nil
+}
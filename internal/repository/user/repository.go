@@ -3,15 +3,19 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/DukeRupert/haven/internal/model/dto"
 	"github.com/DukeRupert/haven/internal/model/entity"
 	"github.com/DukeRupert/haven/internal/model/params"
+	"github.com/DukeRupert/haven/internal/model/types"
 	"github.com/DukeRupert/haven/internal/repository/facility"
 	"github.com/DukeRupert/haven/internal/repository/schedule"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
@@ -34,22 +38,33 @@ func New(pool *pgxpool.Pool, facility *facility.Repository, schedule *schedule.R
 
 // Custom errors
 var (
-	ErrNotFound    = fmt.Errorf("user not found")
-	ErrEmailExists = fmt.Errorf("email already exists")
+	ErrNotFound      = fmt.Errorf("user not found")
+	ErrEmailExists   = fmt.Errorf("email already exists")
+	ErrInitialsTaken = fmt.Errorf("initials already used at facility")
 )
 
+// Enforces unique initials within a facility, for visiting members too
+const initialsConstraint = "users_facility_initials_key"
+
+// isInitialsViolation reports whether the database rejected a change
+// because the initials are already used at one of the user's facilities
+func isInitialsViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == initialsConstraint
+}
+
 func (r *Repository) GetByID(ctx context.Context, id int) (*entity.User, error) {
 	var user entity.User
 	err := r.pool.QueryRow(ctx, `
         SELECT 
             id, created_at, updated_at, first_name, last_name, 
-            initials, email, facility_id, role
+            initials, email, facility_id, role, deactivated_at
         FROM users
         WHERE id = $1
     `, id).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt,
 		&user.FirstName, &user.LastName, &user.Initials,
-		&user.Email, &user.FacilityID, &user.Role,
+		&user.Email, &user.FacilityID, &user.Role, &user.DeactivatedAt,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, ErrNotFound
@@ -60,28 +75,114 @@ func (r *Repository) GetByID(ctx context.Context, id int) (*entity.User, error)
 	return &user, nil
 }
 
-func (r *Repository) GetByFacilityCode(ctx context.Context, facilityCode string) ([]entity.User, error) {
-	rows, err := r.pool.Query(ctx, `
+// Columns the users list can be ordered by, keyed by params.UserSort*
+var userSortColumns = map[string]string{
+	params.UserSortName:     "u.last_name, u.first_name, u.id",
+	params.UserSortInitials: "u.initials, u.id",
+	params.UserSortEmail:    "u.email, u.id",
+	params.UserSortNewest:   "u.created_at DESC, u.id DESC",
+}
+
+// ListByFacility returns a page of the active users based at or members of a
+// facility with the role each holds there, along with how many users match
+// in total. They can be searched, narrowed to a role, registration state,
+// area or holders of a current qualification, and sorted.
+func (r *Repository) ListByFacility(ctx context.Context, p params.ListUsersParams) ([]entity.User, int, error) {
+	orderBy, ok := userSortColumns[p.Sort]
+	if !ok {
+		orderBy = userSortColumns[params.UserSortName]
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
         SELECT 
             u.id, u.created_at, u.updated_at, u.first_name, u.last_name,
-            u.initials, u.email, u.facility_id, u.role
+            u.initials, u.email, u.facility_id, COALESCE(m.role, u.role),
+            u.registration_completed, m.area_id, COALESCE(a.name, ''),
+            COUNT(*) OVER ()
         FROM users u
-        JOIN facilities f ON u.facility_id = f.id
-        WHERE f.code = $1
-        ORDER BY u.last_name, u.first_name ASC
-    `, facilityCode)
+        JOIN facilities f ON f.code = $1
+        LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = f.id
+        LEFT JOIN facility_areas a ON a.id = m.area_id
+        WHERE (u.facility_id = f.id OR m.user_id IS NOT NULL)
+        AND u.deactivated_at IS NULL
+        AND ($2::int IS NULL OR m.area_id = $2)
+        AND ($3::int IS NULL OR EXISTS (
+            SELECT 1 FROM user_qualifications uq
+            WHERE uq.user_id = u.id
+            AND uq.qualification_id = $3
+            AND (uq.expires_on IS NULL OR uq.expires_on >= CURRENT_DATE)
+        ))
+        AND ($4::text = '' OR (
+            u.first_name || ' ' || u.last_name ILIKE $4
+            OR u.initials ILIKE $4
+            OR u.email ILIKE $4
+        ))
+        AND ($5::text = '' OR COALESCE(m.role, u.role)::text = $5)
+        AND ($6::text = '' OR u.registration_completed = ($6 = '%s'))
+        ORDER BY %s
+        LIMIT $7 OFFSET $8
+    `, params.RegistrationCompleted, orderBy),
+		p.FacilityCode, p.AreaID, p.QualificationID, containsPattern(p.Search),
+		string(p.Role), p.Status, params.UsersPerPage, p.Offset(),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("querying users by facility code: %w", err)
+		return nil, 0, fmt.Errorf("querying users by facility code: %w", err)
 	}
 	defer rows.Close()
 
 	var users []entity.User
+	var total int
 	for rows.Next() {
 		var user entity.User
 		err := rows.Scan(
 			&user.ID, &user.CreatedAt, &user.UpdatedAt,
 			&user.FirstName, &user.LastName, &user.Initials,
 			&user.Email, &user.FacilityID, &user.Role,
+			&user.RegistrationCompleted, &user.AreaID, &user.AreaName,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scanning user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterating user rows: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// Search finds users at every facility whose name, initials or email
+// contain the query, along with the code of their home facility
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]entity.User, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT 
+            u.id, u.created_at, u.updated_at, u.first_name, u.last_name,
+            u.initials, u.email, u.facility_id, f.code, u.role,
+            u.registration_completed, u.deactivated_at
+        FROM users u
+        JOIN facilities f ON f.id = u.facility_id
+        WHERE u.first_name || ' ' || u.last_name ILIKE $1
+        OR u.initials ILIKE $1
+        OR u.email ILIKE $1
+        ORDER BY u.initials ILIKE $2 DESC, u.last_name, u.first_name, f.code
+        LIMIT $3
+    `, containsPattern(query), escapeLike(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("searching users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID, &user.CreatedAt, &user.UpdatedAt,
+			&user.FirstName, &user.LastName, &user.Initials,
+			&user.Email, &user.FacilityID, &user.FacilityCode, &user.Role,
+			&user.RegistrationCompleted, &user.DeactivatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning user row: %w", err)
@@ -96,6 +197,21 @@ func (r *Repository) GetByFacilityCode(ctx context.Context, facilityCode string)
 	return users, nil
 }
 
+// containsPattern returns an ILIKE pattern matching text containing the
+// search, or an empty string when there is nothing to search for
+func containsPattern(search string) string {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return ""
+	}
+	return "%" + escapeLike(search) + "%"
+}
+
+// escapeLike stops LIKE treating characters in the search as wildcards
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.TrimSpace(s))
+}
+
 func (r *Repository) Create(ctx context.Context, params params.CreateUserParams) (*entity.User, error) {
 	log.Debug().
 		Str("first_name", params.FirstName).
@@ -115,9 +231,23 @@ func (r *Repository) Create(ctx context.Context, params params.CreateUserParams)
 		return nil, ErrEmailExists
 	}
 
+	available, err := r.IsInitialsAvailable(ctx, params.FacilityID, params.Initials, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		return nil, ErrInitialsTaken
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var user entity.User
 	now := time.Now()
-	err = r.pool.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
         INSERT INTO users (
             created_at, updated_at, first_name, last_name, 
             initials, email, password, facility_id, role
@@ -135,13 +265,45 @@ func (r *Repository) Create(ctx context.Context, params params.CreateUserParams)
 		&user.FirstName, &user.LastName, &user.Initials,
 		&user.Email, &user.FacilityID, &user.Role,
 	)
+	if isInitialsViolation(err) {
+		return nil, ErrInitialsTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("creating user: %w", err)
 	}
 
+	if err := syncHomeMembership(ctx, tx, &user); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	return &user, nil
 }
 
+// syncHomeMembership keeps the user's membership at their home facility in
+// step with their role. Super users have access everywhere and need none.
+func syncHomeMembership(ctx context.Context, tx pgx.Tx, user *entity.User) error {
+	if user.Role == types.UserRoleSuper {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+        INSERT INTO facility_memberships (user_id, facility_id, role)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, facility_id) DO UPDATE
+        SET role = EXCLUDED.role,
+            updated_at = CURRENT_TIMESTAMP
+    `, user.ID, user.FacilityID, user.Role)
+	if isInitialsViolation(err) {
+		return ErrInitialsTaken
+	}
+	if err != nil {
+		return fmt.Errorf("updating home facility membership: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) Update(ctx context.Context, userID int, params params.UpdateUserParams) (*entity.User, error) {
 	var count int
 	err := r.pool.QueryRow(ctx, `
@@ -156,10 +318,16 @@ func (r *Repository) Update(ctx context.Context, userID int, params params.Updat
 		return nil, fmt.Errorf("email already exists: %s", params.Email)
 	}
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var user entity.User
 	now := time.Now()
 
-	err = r.pool.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
         UPDATE users 
         SET updated_at = $1,
             first_name = $2,
@@ -198,13 +366,150 @@ func (r *Repository) Update(ctx context.Context, userID int, params params.Updat
 		&user.FacilityID,
 		&user.Role,
 	)
+	if isInitialsViolation(err) {
+		return nil, ErrInitialsTaken
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error updating user: %w", err)
 	}
 
+	if err := syncHomeMembership(ctx, tx, &user); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ListAdmins returns the active users holding the admin role at a facility
+func (r *Repository) ListAdmins(ctx context.Context, facilityID int) ([]entity.User, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT 
+            u.id, u.created_at, u.updated_at, u.first_name, u.last_name,
+            u.initials, u.email, u.facility_id, m.role
+        FROM users u
+        JOIN facility_memberships m ON m.user_id = u.id
+        WHERE m.facility_id = $1
+        AND m.role = 'admin'
+        AND u.deactivated_at IS NULL
+        ORDER BY u.last_name, u.first_name ASC
+    `, facilityID)
+	if err != nil {
+		return nil, fmt.Errorf("querying facility admins: %w", err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID, &user.CreatedAt, &user.UpdatedAt,
+			&user.FirstName, &user.LastName, &user.Initials,
+			&user.Email, &user.FacilityID, &user.Role,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating user rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// ListDeactivated returns the deactivated users based at a facility, most
+// recently deactivated first
+func (r *Repository) ListDeactivated(ctx context.Context, facilityCode string) ([]entity.User, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT 
+            u.id, u.created_at, u.updated_at, u.first_name, u.last_name,
+            u.initials, u.email, u.facility_id, u.role, u.deactivated_at
+        FROM users u
+        JOIN facilities f ON f.id = u.facility_id
+        WHERE f.code = $1
+        AND u.deactivated_at IS NOT NULL
+        ORDER BY u.deactivated_at DESC
+    `, facilityCode)
+	if err != nil {
+		return nil, fmt.Errorf("querying deactivated users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []entity.User
+	for rows.Next() {
+		var user entity.User
+		err := rows.Scan(
+			&user.ID, &user.CreatedAt, &user.UpdatedAt,
+			&user.FirstName, &user.LastName, &user.Initials,
+			&user.Email, &user.FacilityID, &user.Role, &user.DeactivatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating user rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetDeactivated deactivates or reactivates a user. Deactivated users cannot
+// sign in and are hidden from facility lists. Their past protected dates are
+// kept for reporting while upcoming ones are removed from the calendar.
+func (r *Repository) SetDeactivated(ctx context.Context, userID int, deactivated bool) (*entity.User, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var user entity.User
+	err = tx.QueryRow(ctx, `
+        UPDATE users
+        SET deactivated_at = CASE WHEN $1 THEN COALESCE(deactivated_at, NOW()) END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+        RETURNING 
+            id, created_at, updated_at, first_name, last_name,
+            initials, email, facility_id, role, deactivated_at
+    `, deactivated, userID).Scan(
+		&user.ID, &user.CreatedAt, &user.UpdatedAt,
+		&user.FirstName, &user.LastName, &user.Initials,
+		&user.Email, &user.FacilityID, &user.Role, &user.DeactivatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating user deactivation: %w", err)
+	}
+
+	if deactivated {
+		_, err = tx.Exec(ctx, `
+            DELETE FROM protected_dates
+            WHERE user_id = $1 AND date >= CURRENT_DATE
+        `, userID)
+		if err != nil {
+			return nil, fmt.Errorf("removing upcoming protected dates: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	return &user, nil
 }
 
+// Delete permanently removes a user along with their schedule and history
 func (r *Repository) Delete(ctx context.Context, userID int) error {
 	result, err := r.pool.Exec(ctx, `
         DELETE FROM users 
@@ -241,6 +546,7 @@ func (r *Repository) VerifyCredentials(ctx context.Context, facilityID int, init
         WHERE facility_id = $1 
         AND UPPER(initials) = UPPER($2)
         AND LOWER(email) = LOWER($3)
+        AND deactivated_at IS NULL
     `, facilityID, initials, email).Scan(
 		&user.ID,
 		&user.CreatedAt,
@@ -291,6 +597,32 @@ func (r *Repository) UpdatePassword(ctx context.Context, userID int, hashedPassw
 	return nil
 }
 
+// UpdateEmail moves a user to a new email address
+func (r *Repository) UpdateEmail(ctx context.Context, userID int, email string) error {
+	unique, err := r.IsEmailUnique(ctx, email, &userID)
+	if err != nil {
+		return err
+	}
+	if !unique {
+		return ErrEmailExists
+	}
+
+	result, err := r.pool.Exec(ctx, `
+        UPDATE users
+        SET
+            email = $1,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+    `, email, userID)
+	if err != nil {
+		return fmt.Errorf("error updating user email: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // GetDetails retrieves full user details including facility and schedule
 func (r *Repository) GetDetails(ctx context.Context, initials string, facility string) (*dto.UserDetails, error) {
 	// Get user first
@@ -349,22 +681,27 @@ func (r *Repository) GetDetails(ctx context.Context, initials string, facility s
 	}, nil
 }
 
-// GetByInitialsAndFacility finds a user by their initials and facility code
+// GetByInitialsAndFacility finds a user based at or a member of the facility.
+// The role returned is the one they hold at that facility.
 func (r *Repository) GetByInitialsAndFacility(ctx context.Context, initials string, facilityCode string) (*entity.User, error) {
     var user entity.User
     err := r.pool.QueryRow(ctx, `
         SELECT 
             u.id, u.created_at, u.updated_at, u.first_name, u.last_name, 
-            u.initials, u.email, u.facility_id, u.role, u.registration_completed,
-            u.password
+            u.initials, u.email, u.facility_id, COALESCE(m.role, u.role), u.registration_completed,
+            u.password, m.area_id, COALESCE(a.name, ''), u.deactivated_at
         FROM users u
-        JOIN facilities f ON u.facility_id = f.id
-        WHERE u.initials = $1 AND f.code = $2
+        JOIN facilities f ON f.code = $2
+        LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = f.id
+        LEFT JOIN facility_areas a ON a.id = m.area_id
+        WHERE u.initials = $1
+        AND (u.facility_id = f.id OR m.user_id IS NOT NULL)
     `, initials, facilityCode).Scan(
         &user.ID, &user.CreatedAt, &user.UpdatedAt,
         &user.FirstName, &user.LastName, &user.Initials,
         &user.Email, &user.FacilityID, &user.Role,
         &user.RegistrationCompleted, &user.Password,
+        &user.AreaID, &user.AreaName, &user.DeactivatedAt,
     )
     if err == pgx.ErrNoRows {
         return nil, ErrNotFound
@@ -382,13 +719,14 @@ func (r *Repository) GetByEmail(ctx context.Context, email string) (*entity.User
 	err := r.pool.QueryRow(ctx, `
         SELECT 
             id, created_at, updated_at, first_name, last_name,
-            initials, email, password, facility_id, role
+            initials, email, password, facility_id, role, deactivated_at
         FROM users
         WHERE email = $1
     `, email).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt,
 		&user.FirstName, &user.LastName, &user.Initials,
 		&user.Email, &user.Password, &user.FacilityID, &user.Role,
+		&user.DeactivatedAt,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, ErrNotFound
@@ -415,3 +753,44 @@ func (r *Repository) IsEmailUnique(ctx context.Context, email string, excludeID
 	}
 	return isUnique, nil
 }
+
+// IsInitialsAvailable checks that no one else based at or a member of the
+// facility uses the initials, excluding the specified user ID
+func (r *Repository) IsInitialsAvailable(ctx context.Context, facilityID int, initials string, excludeID *int) (bool, error) {
+	var available bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT NOT EXISTS (
+            SELECT 1
+            FROM users u
+            LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = $1
+            WHERE UPPER(u.initials) = UPPER($2)
+            AND (u.facility_id = $1 OR m.user_id IS NOT NULL)
+            AND ($3::int IS NULL OR u.id != $3)
+        )
+    `, facilityID, initials, excludeID).Scan(&available)
+	if err != nil {
+		return false, fmt.Errorf("checking initials availability: %w", err)
+	}
+	return available, nil
+}
+
+// GetInitialsByID returns the current initials of a user based at or a
+// member of the facility. Links by ID keep working when initials change.
+func (r *Repository) GetInitialsByID(ctx context.Context, id int, facilityCode string) (string, error) {
+	var initials string
+	err := r.pool.QueryRow(ctx, `
+        SELECT u.initials
+        FROM users u
+        JOIN facilities f ON f.code = $2
+        LEFT JOIN facility_memberships m ON m.user_id = u.id AND m.facility_id = f.id
+        WHERE u.id = $1
+        AND (u.facility_id = f.id OR m.user_id IS NOT NULL)
+    `, id, facilityCode).Scan(&initials)
+	if err == pgx.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting initials by id: %w", err)
+	}
+	return initials, nil
+}
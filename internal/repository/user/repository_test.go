@@ -0,0 +1,33 @@
+// internal/repository/user/repository_test.go
+package user
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsInitialsViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"other error", errors.New("connection reset"), false},
+		{"initials index or trigger", &pgconn.PgError{Code: "23505", ConstraintName: initialsConstraint}, true},
+		{"wrapped", fmt.Errorf("updating user: %w", &pgconn.PgError{Code: "23505", ConstraintName: initialsConstraint}), true},
+		{"email index", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, false},
+		{"other violation", &pgconn.PgError{Code: "23503", ConstraintName: initialsConstraint}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInitialsViolation(tt.err); got != tt.want {
+				t.Errorf("isInitialsViolation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
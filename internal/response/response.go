@@ -2,7 +2,12 @@
 package response
 
 import (
+    "fmt"
+    "math"
     "net/http"
+    "strconv"
+    "time"
+
     "github.com/DukeRupert/haven/web/view/alert"
     "github.com/labstack/echo/v4"
 )
@@ -47,6 +52,24 @@ func Validation(c echo.Context, messages []string) error {
     )
 }
 
+// TooManyRequests returns a rate limit error response with a Retry-After header
+func TooManyRequests(c echo.Context, retryAfter time.Duration) error {
+    seconds := int(math.Ceil(retryAfter.Seconds()))
+    minutes := int(math.Ceil(retryAfter.Minutes()))
+    c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+
+    message := "Too many attempts. Please try again in a minute."
+    if minutes > 1 {
+        message = fmt.Sprintf("Too many attempts. Please try again in %d minutes.", minutes)
+    }
+
+    return Error(c,
+        http.StatusTooManyRequests,
+        "Too Many Requests",
+        []string{message},
+    )
+}
+
 // Success returns a success response
 func Success(c echo.Context, heading string, message string) error {
     c.Response().Status = http.StatusOK
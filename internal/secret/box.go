@@ -0,0 +1,57 @@
+// internal/secret/box.go
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is returned when a sealed secret cannot be opened
+var ErrInvalid = errors.New("invalid sealed secret")
+
+// Box seals secrets such as facility client secrets and TOTP secrets before
+// they are stored, so the database never holds them in the clear
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox creates a box from a 16, 24 or 32 byte AES key
+func NewBox(key string) (*Box, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating gcm: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts a secret with a random nonce, returning it base64 encoded
+func (b *Box) Seal(secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret returned by Seal
+func (b *Box) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrInvalid
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,38 @@
+// internal/secret/box_test.go
+package secret
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBox(t *testing.T) {
+	box, err := NewBox("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("creating box: %v", err)
+	}
+
+	sealed, err := box.Seal("client-secret")
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+	if sealed == "client-secret" {
+		t.Fatal("sealed secret is stored in the clear")
+	}
+
+	opened, err := box.Open(sealed)
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	if opened != "client-secret" {
+		t.Errorf("opened %q, want %q", opened, "client-secret")
+	}
+
+	other, _ := NewBox("fedcba9876543210fedcba9876543210")
+	if _, err := other.Open(sealed); !errors.Is(err, ErrInvalid) {
+		t.Errorf("opening with another key: got %v, want ErrInvalid", err)
+	}
+	if _, err := box.Open("client-secret"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("opening plain text: got %v, want ErrInvalid", err)
+	}
+}
@@ -3,6 +3,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,8 +19,22 @@ type PgxStore struct {
 	Codecs   []securecookie.Codec
 	Options  *sessions.Options
 	logger   zerolog.Logger
+
+	// IdleTimeout ends sessions with no activity; each save slides the expiry.
+	// MaxLifetime ends sessions a fixed time after they were created.
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
 }
 
+// Default session lifetimes
+const (
+	DefaultIdleTimeout = 12 * time.Hour
+	DefaultMaxLifetime = 7 * 24 * time.Hour
+)
+
+// ErrSessionExpired is returned when loading a session past its expiry
+var ErrSessionExpired = errors.New("session expired")
+
 // SessionKeys defines constants for session value keys
 const (
 	DefaultSessionName     = "session"
@@ -29,19 +44,38 @@ const (
 	SessionKeyFacilityID   = "facility_id"
 	SessionKeyFacilityCode = "facility_code"
 	SessionKeyLastAccess   = "last_access"
+	SessionKeyCreatedAt    = "created_at"
+	SessionKeyCSRFToken    = "csrf_token"
+
+	// Set while a super user views the app as another user
+	SessionKeyImpersonatorID = "impersonator_id"
+
+	// Set between password verification and the second factor
+	SessionKeyPendingUserID   = "pending_user_id"
+	SessionKeyPendingSince    = "pending_since"
+	SessionKeyPendingFailures = "pending_failures"
+
+	// Set between redirecting to the identity provider and its callback
+	SessionKeyOIDCState      = "oidc_state"
+	SessionKeyOIDCNonce      = "oidc_nonce"
+	SessionKeyOIDCVerifier   = "oidc_verifier"
+	SessionKeyOIDCFacilityID = "oidc_facility_id"
 )
 
-func NewPgxStore(repo *session.Repository, keyPairs ...[]byte) (*PgxStore, error) {
+func NewPgxStore(repo *session.Repository, logger zerolog.Logger, keyPairs ...[]byte) (*PgxStore, error) {
 	store := &PgxStore{
 		sessions: repo,
 		Codecs:   securecookie.CodecsFromPairs(keyPairs...),
+		logger:   logger.With().Str("component", "session_store").Logger(),
 		Options: &sessions.Options{
 			Path:     "/",
-			MaxAge:   86400 * 7,
+			MaxAge:   int(DefaultIdleTimeout.Seconds()),
 			HttpOnly: true,
 			Secure:   true,
 			SameSite: http.SameSiteLaxMode,
 		},
+		IdleTimeout: DefaultIdleTimeout,
+		MaxLifetime: DefaultMaxLifetime,
 	}
 
 	// Create table if needed
@@ -85,12 +119,17 @@ func (s *PgxStore) Get(r *http.Request, name string) (*sessions.Session, error)
 		return session, nil
 	}
 
-	// Decode session ID from cookie
+	// Decode session ID from cookie. A cookie signed with a previous key is
+	// re-signed with the current key the next time the session is saved.
 	var id string
-	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+	rotated, err := s.decodeMulti(name, cookie.Value, &id)
+	if err != nil {
 		// Invalid cookie, return new session
 		return session, nil
 	}
+	if rotated {
+		s.logger.Debug().Msg("session cookie signed with previous key")
+	}
 
 	// Set session ID
 	session.ID = id
@@ -98,8 +137,8 @@ func (s *PgxStore) Get(r *http.Request, name string) (*sessions.Session, error)
 
 	// Load session data
 	if err := s.Load(r.Context(), session); err != nil {
-		// Failed to load session, return new one
-		return sessions.NewSession(s, name), nil
+		// Failed or expired session, return new one
+		return s.New(r, name)
 	}
 
 	return session, nil
@@ -144,12 +183,19 @@ func (s *PgxStore) Save(r *http.Request, w http.ResponseWriter, session *session
 
 // save is your internal method that handles the actual saving
 func (s *PgxStore) save(ctx context.Context, sess *sessions.Session) error {
+	// Sessions created before the absolute lifetime was tracked start it
+	// now. Set before encoding so the value is persisted.
+	now := time.Now()
+	if _, ok := sess.Values[SessionKeyCreatedAt].(time.Time); !ok {
+		sess.Values[SessionKeyCreatedAt] = now
+	}
+
 	encoded, err := s.encodeSession(sess)
 	if err != nil {
 		return err
 	}
 
-	expiresOn := time.Now().Add(time.Second * time.Duration(sess.Options.MaxAge))
+	expiresOn := s.expiresOn(sess.Values, now)
 
 	params := session.CreateParams{
 		Key:       sess.ID,
@@ -158,20 +204,81 @@ func (s *PgxStore) save(ctx context.Context, sess *sessions.Session) error {
 		IsNew:     sess.IsNew,
 	}
 
+	// Link authenticated sessions to their user so they can be revoked
+	params.UserID = sessionOwner(sess.Values)
+
 	return s.sessions.Save(ctx, params)
 }
 
-// decodeSession handles the session-specific decoding
-func (s *PgxStore) decodeSession(name string, data []byte) (map[interface{}]interface{}, error) {
+// sessionOwner returns the user a session belongs to, if authenticated. An
+// impersonation session still belongs to the super user.
+func sessionOwner(values map[interface{}]interface{}) *int {
+	if impersonatorID, ok := values[SessionKeyImpersonatorID].(int); ok && impersonatorID != 0 {
+		return &impersonatorID
+	}
+	if userID, ok := values[SessionKeyUserID].(int); ok {
+		return &userID
+	}
+	return nil
+}
+
+// LinkSessions records the user of authenticated sessions saved before
+// sessions were linked to their user, so signing a user out everywhere
+// reaches them too. It returns how many sessions were linked.
+func (s *PgxStore) LinkSessions(ctx context.Context) (int, error) {
+	unlinked, err := s.sessions.ListUnlinked(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, sess := range unlinked {
+		values, _, err := s.decodeSession(DefaultSessionName, sess.Data)
+		if err != nil {
+			continue
+		}
+		userID := sessionOwner(values)
+		if userID == nil {
+			continue
+		}
+		if err := s.sessions.Link(ctx, sess.Key, *userID); err != nil {
+			return linked, err
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// decodeSession handles the session-specific decoding. It reports whether
+// the data was encoded with a previous key and should be re-encoded.
+func (s *PgxStore) decodeSession(name string, data []byte) (map[interface{}]interface{}, bool, error) {
 	var values map[interface{}]interface{}
-	err := securecookie.DecodeMulti(name, string(data), &values, s.Codecs...)
+	rotated, err := s.decodeMulti(name, string(data), &values)
 	if err != nil {
 		s.logger.Error().
 			Err(err).
 			Msg("failed to decode session data")
-		return nil, fmt.Errorf("decoding session values: %w", err)
+		return nil, false, fmt.Errorf("decoding session values: %w", err)
 	}
-	return values, nil
+	return values, rotated, nil
+}
+
+// decodeMulti decodes with each codec in turn, like securecookie.DecodeMulti,
+// and reports whether a codec other than the current one succeeded
+func (s *PgxStore) decodeMulti(name, value string, dst interface{}) (bool, error) {
+	if len(s.Codecs) == 0 {
+		return false, securecookie.DecodeMulti(name, value, dst)
+	}
+
+	var errs securecookie.MultiError
+	for i, codec := range s.Codecs {
+		err := codec.Decode(name, value, dst)
+		if err == nil {
+			return i > 0, nil
+		}
+		errs = append(errs, err)
+	}
+	return false, errs
 }
 
 // Load handles the high-level session loading logic
@@ -181,14 +288,37 @@ func (s *PgxStore) Load(ctx context.Context, session *sessions.Session) error {
 		return fmt.Errorf("loading session: %w", err)
 	}
 
-	values, err := s.decodeSession(session.Name(), sess.Data)
+	// Reject rows the database still holds past their expiry
+	now := time.Now()
+	if sess.ExpiresOn != nil && !sess.ExpiresOn.After(now) {
+		return ErrSessionExpired
+	}
+
+	values, rotated, err := s.decodeSession(session.Name(), sess.Data)
 	if err != nil {
 		return err
 	}
 
+	// Enforce the configured timeouts even if they were shortened since the row was saved
+	if !s.expiresOn(values, now).After(now) {
+		s.logger.Debug().
+			Str("session_id", session.ID).
+			Msg("session past idle timeout or max lifetime")
+		return ErrSessionExpired
+	}
+
 	session.Values = values
 	session.IsNew = false
 
+	// Re-encode data stored under a previous key so the key can be retired
+	if rotated {
+		if err := s.save(ctx, session); err != nil {
+			s.logger.Error().Err(err).Str("session_id", session.ID).Msg("failed to re-encode session with current key")
+		} else {
+			s.logger.Info().Str("session_id", session.ID).Msg("session re-encoded with current key")
+		}
+	}
+
 	s.logger.Debug().
 		Str("session_id", session.ID).
 		Msg("session loaded and decoded successfully")
@@ -196,6 +326,38 @@ func (s *PgxStore) Load(ctx context.Context, session *sessions.Session) error {
 	return nil
 }
 
+// SetLifetime configures the idle timeout and absolute lifetime. The cookie
+// lives as long as the idle timeout and is renewed on each save.
+func (s *PgxStore) SetLifetime(idle, max time.Duration) {
+	if idle <= 0 {
+		idle = DefaultIdleTimeout
+	}
+	if max <= 0 {
+		max = DefaultMaxLifetime
+	}
+	s.IdleTimeout = idle
+	s.MaxLifetime = max
+	s.MaxAge(int(idle.Seconds()))
+}
+
+// expiresOn returns when a session with the given values expires: the idle
+// timeout after its last access, capped by its absolute lifetime
+func (s *PgxStore) expiresOn(values map[interface{}]interface{}, now time.Time) time.Time {
+	lastAccess, ok := values[SessionKeyLastAccess].(time.Time)
+	if !ok || lastAccess.After(now) {
+		lastAccess = now
+	}
+	expires := lastAccess.Add(s.IdleTimeout)
+
+	if createdAt, ok := values[SessionKeyCreatedAt].(time.Time); ok {
+		if limit := createdAt.Add(s.MaxLifetime); limit.Before(expires) {
+			expires = limit
+		}
+	}
+
+	return expires
+}
+
 // MaxAge sets the maximum age for the store and the underlying cookie
 func (s *PgxStore) MaxAge(age int) {
 	s.Options.MaxAge = age
@@ -0,0 +1,130 @@
+// internal/store/pgx_store_test.go
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/rs/zerolog"
+)
+
+func TestExpiresOn(t *testing.T) {
+	s := &PgxStore{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour}
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		values map[interface{}]interface{}
+		want   time.Time
+	}{
+		{"no activity recorded", map[interface{}]interface{}{}, now.Add(time.Hour)},
+		{"recent access", map[interface{}]interface{}{
+			SessionKeyLastAccess: now.Add(-10 * time.Minute),
+		}, now.Add(50 * time.Minute)},
+		{"idle past timeout", map[interface{}]interface{}{
+			SessionKeyLastAccess: now.Add(-2 * time.Hour),
+		}, now.Add(-time.Hour)},
+		{"access in the future", map[interface{}]interface{}{
+			SessionKeyLastAccess: now.Add(time.Hour),
+		}, now.Add(time.Hour)},
+		{"capped by max lifetime", map[interface{}]interface{}{
+			SessionKeyLastAccess: now,
+			SessionKeyCreatedAt:  now.Add(-23*time.Hour - 30*time.Minute),
+		}, now.Add(30 * time.Minute)},
+		{"within max lifetime", map[interface{}]interface{}{
+			SessionKeyLastAccess: now,
+			SessionKeyCreatedAt:  now.Add(-time.Hour),
+		}, now.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.expiresOn(tt.values, now); !got.Equal(tt.want) {
+				t.Errorf("expiresOn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMulti(t *testing.T) {
+	current := []byte("current-auth-key-0123456789abcdef")
+	previous := []byte("previous-auth-key-0123456789abcde")
+	unknown := []byte("unknown-auth-key-0123456789abcdef")
+
+	encode := func(t *testing.T, key []byte) string {
+		t.Helper()
+		value, err := securecookie.EncodeMulti(DefaultSessionName, "session-id", securecookie.CodecsFromPairs(key)...)
+		if err != nil {
+			t.Fatalf("encoding: %v", err)
+		}
+		return value
+	}
+
+	s := &PgxStore{
+		Codecs: securecookie.CodecsFromPairs(current, nil, previous, nil),
+		logger: zerolog.Nop(),
+	}
+
+	tests := []struct {
+		name        string
+		key         []byte
+		wantRotated bool
+		wantErr     bool
+	}{
+		{"current key", current, false, false},
+		{"previous key", previous, true, false},
+		{"unknown key", unknown, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id string
+			rotated, err := s.decodeMulti(DefaultSessionName, encode(t, tt.key), &id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeMulti() error = %v, want error %v", err, tt.wantErr)
+			}
+			if rotated != tt.wantRotated {
+				t.Errorf("decodeMulti() rotated = %v, want %v", rotated, tt.wantRotated)
+			}
+			if !tt.wantErr && id != "session-id" {
+				t.Errorf("decodeMulti() decoded %q, want %q", id, "session-id")
+			}
+		})
+	}
+}
+
+func TestSessionOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[interface{}]interface{}
+		want   int
+	}{
+		{"anonymous", map[interface{}]interface{}{}, 0},
+		{"pending second factor", map[interface{}]interface{}{SessionKeyPendingUserID: 5}, 0},
+		{"signed in", map[interface{}]interface{}{SessionKeyUserID: 5}, 5},
+		{"impersonating", map[interface{}]interface{}{
+			SessionKeyUserID:         5,
+			SessionKeyImpersonatorID: 1,
+		}, 1},
+		{"impersonation ended", map[interface{}]interface{}{
+			SessionKeyUserID:         5,
+			SessionKeyImpersonatorID: 0,
+		}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sessionOwner(tt.values)
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("sessionOwner() = %d, want nil", *got)
+				}
+				return
+			}
+			if got == nil || *got != tt.want {
+				t.Errorf("sessionOwner() = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
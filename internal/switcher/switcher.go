@@ -0,0 +1,30 @@
+// internal/switcher/switcher.go
+package switcher
+
+import "context"
+
+// Facility is one choice in the navigation's facility switcher
+type Facility struct {
+	Code string
+	Name string
+}
+
+// Switcher lists the facilities a user can move between
+type Switcher struct {
+	Current    string // Code of the facility being viewed
+	Facilities []Facility
+}
+
+type ctxKey struct{}
+
+// WithSwitcher returns a copy of ctx carrying the switcher for templates
+func WithSwitcher(ctx context.Context, s Switcher) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// From returns the switcher carried by ctx, if the user belongs to more
+// than one facility
+func From(ctx context.Context) (Switcher, bool) {
+	s, ok := ctx.Value(ctxKey{}).(Switcher)
+	return s, ok
+}
@@ -9,6 +9,10 @@ var (
 	ErrInvalidLength   = errors.New("facility code must be 3 or 4 characters")
 	ErrNotAlphanumeric = errors.New("facility code must contain only letters and numbers")
 
+	// Facility alias-related errors
+	ErrAliasTooLong = errors.New("facility alias too long")
+	ErrInvalidAlias = errors.New("facility alias must contain only letters, numbers, spaces and hyphens")
+
 	// Facility Name-related errors
 	ErrEmptyName           = errors.New("facility name is required")
 	ErrNameTooLong         = errors.New("facility name too long")
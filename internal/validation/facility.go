@@ -7,7 +7,12 @@ import (
 	"strings"
 )
 
-const MaxNameLength = 250
+const (
+	MaxNameLength  = 250
+	MinCodeLength  = 3
+	MaxCodeLength  = 4
+	MaxAliasLength = 24
+)
 
 // FacilityCode represents a validated facility code
 type FacilityCode string
@@ -15,18 +20,22 @@ type FacilityCode string
 // FacilityName represents a validated facility name
 type FacilityName string
 
-// ValidateFacilityCode validates and normalizes a facility code
+// FacilityAlias represents a validated facility display alias
+type FacilityAlias string
+
+// ValidateFacilityCode validates and normalizes a facility code. Codes are
+// stored as entered, upper case, so ICAO identifiers outside the contiguous
+// US (PANC, PHNL, TJSJ) and FAA identifiers for TRACONs and ARTCCs (NCT,
+// A80, ZSE) are all accepted.
 // Returns the normalized code and any validation error
 func ValidateFacilityCode(code string) (FacilityCode, error) {
-	if code == "" {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	if normalized == "" {
 		return "", ErrEmptyCode
 	}
 
-	// Normalize to lowercase
-	normalized := strings.ToLower(code)
-
 	// Validate length
-	if len(normalized) != 3 && len(normalized) != 4 {
+	if len(normalized) < MinCodeLength || len(normalized) > MaxCodeLength {
 		return "", ErrInvalidLength
 	}
 
@@ -35,12 +44,26 @@ func ValidateFacilityCode(code string) (FacilityCode, error) {
 		return "", ErrNotAlphanumeric
 	}
 
-	// Prepend K to 3-character codes
-	if len(normalized) == 3 {
-		normalized = "k" + normalized
+	return FacilityCode(normalized), nil
+}
+
+// ValidateFacilityAlias validates an optional display alias for a facility,
+// such as "SEA" for KSEA or "NorCal" for NCT. An empty alias is valid.
+func ValidateFacilityAlias(alias string) (FacilityAlias, error) {
+	normalized := strings.TrimSpace(alias)
+	if normalized == "" {
+		return "", nil
 	}
 
-	return FacilityCode(normalized), nil
+	if len(normalized) > MaxAliasLength {
+		return "", fmt.Errorf("%w: maximum length is %d characters", ErrAliasTooLong, MaxAliasLength)
+	}
+
+	if !regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9 -]*$`).MatchString(normalized) {
+		return "", ErrInvalidAlias
+	}
+
+	return FacilityAlias(normalized), nil
 }
 
 // ValidateFacilityName validates a facility name
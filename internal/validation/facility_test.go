@@ -125,49 +125,67 @@ func TestValidateFacilityCode(t *testing.T) {
 		{
 			name:          "valid 3-char lowercase",
 			input:         "abc",
-			expected:      "kabc",
+			expected:      "ABC",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 3-char mixed case",
 			input:         "aBc",
-			expected:      "kabc",
+			expected:      "ABC",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 3-char uppercase",
 			input:         "ABC",
-			expected:      "kabc",
+			expected:      "ABC",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 3-char with numbers",
-			input:         "a12",
-			expected:      "ka12",
+			input:         "a80",
+			expected:      "A80",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 4-char lowercase",
 			input:         "abcd",
-			expected:      "abcd",
+			expected:      "ABCD",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 4-char uppercase",
 			input:         "ABCD",
-			expected:      "abcd",
+			expected:      "ABCD",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 4-char mixed case with numbers",
 			input:         "Ab2D",
-			expected:      "ab2d",
+			expected:      "AB2D",
 			expectedError: nil,
 		},
 		{
 			name:          "valid 4-char starting with k",
 			input:         "KABC",
-			expected:      "kabc",
+			expected:      "KABC",
+			expectedError: nil,
+		},
+		{
+			name:          "alaska ICAO code",
+			input:         "PANC",
+			expected:      "PANC",
+			expectedError: nil,
+		},
+		{
+			name:          "ARTCC code is not prefixed",
+			input:         "zse",
+			expected:      "ZSE",
+			expectedError: nil,
+		},
+		{
+			name:          "surrounding spaces",
+			input:         " phnl ",
+			expected:      "PHNL",
 			expectedError: nil,
 		},
 	}
@@ -199,6 +217,37 @@ func TestValidateFacilityCode(t *testing.T) {
 	}
 }
 
+func TestValidateFacilityAlias(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      FacilityAlias
+		expectedError error
+	}{
+		{"empty alias", "", "", nil},
+		{"only spaces", "   ", "", nil},
+		{"short alias", "SEA", "SEA", nil},
+		{"keeps case and spaces", " NorCal TRACON ", "NorCal TRACON", nil},
+		{"hyphenated", "Anchorage-Center", "Anchorage-Center", nil},
+		{"too long", strings.Repeat("A", MaxAliasLength+1), "", ErrAliasTooLong},
+		{"leading hyphen", "-SEA", "", ErrInvalidAlias},
+		{"special characters", "SEA!", "", ErrInvalidAlias},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateFacilityAlias(tt.input)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestIsAlphanumeric(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1,4 +1,4 @@
-// internal/worker/token_cleaner.go
+// internal/worker/cleaner.go
 package worker
 
 import (
@@ -9,37 +9,53 @@ import (
 	"github.com/rs/zerolog"
 )
 
-type TokenRepository interface {
+// ExpiringRepository is implemented by repositories holding rows that expire
+type ExpiringRepository interface {
     DeleteExpired(ctx context.Context) (int64, error)
 }
 
-// TokenCleaner handles periodic cleanup of expired registration tokens
-type TokenCleaner struct {
-    tokens   TokenRepository
+// TokenRepository is kept for callers of NewTokenCleaner
+type TokenRepository = ExpiringRepository
+
+// Cleaner handles periodic cleanup of expired rows
+type Cleaner struct {
+    name     string
+    repo     ExpiringRepository
     logger   zerolog.Logger
     interval time.Duration
     done     chan struct{}
 }
 
-// NewTokenCleaner creates a new TokenCleaner instance
-func NewTokenCleaner(tokens TokenRepository, logger zerolog.Logger, interval time.Duration) *TokenCleaner {
+// TokenCleaner handles periodic cleanup of expired registration tokens
+type TokenCleaner = Cleaner
+
+// NewCleaner creates a new Cleaner instance. The name identifies the
+// cleaner in logs, e.g. "token" or "rate_limit".
+func NewCleaner(name string, repo ExpiringRepository, logger zerolog.Logger, interval time.Duration) *Cleaner {
     if interval < time.Minute {
         interval = 15 * time.Minute
     }
 
-    return &TokenCleaner{
-        tokens:   tokens,
-        logger:   logger.With().Str("component", "token_cleaner").Logger(),
+    return &Cleaner{
+        name:     name,
+        repo:     repo,
+        logger:   logger.With().Str("component", name+"_cleaner").Logger(),
         interval: interval,
         done:     make(chan struct{}),
     }
 }
 
+// NewTokenCleaner creates a new TokenCleaner instance
+func NewTokenCleaner(tokens TokenRepository, logger zerolog.Logger, interval time.Duration) *TokenCleaner {
+    return NewCleaner("token", tokens, logger, interval)
+}
+
 // Start begins the periodic cleanup process
-func (tc *TokenCleaner) Start() {
+func (tc *Cleaner) Start() {
     tc.logger.Info().
         Dur("interval", tc.interval).
-        Msg("Starting token cleanup worker")
+        Str("cleaner", tc.name).
+        Msg("Starting cleanup worker")
 
     go func() {
         ticker := time.NewTicker(tc.interval)
@@ -57,7 +73,7 @@ func (tc *TokenCleaner) Start() {
                     tc.logger.Error().Err(err).Msg("Periodic cleanup failed")
                 }
             case <-tc.done:
-                tc.logger.Info().Msg("Token cleanup worker stopped")
+                tc.logger.Info().Msg("Cleanup worker stopped")
                 return
             }
         }
@@ -65,26 +81,26 @@ func (tc *TokenCleaner) Start() {
 }
 
 // Stop gracefully stops the cleanup process
-func (tc *TokenCleaner) Stop() {
-    tc.logger.Info().Msg("Stopping token cleanup worker")
+func (tc *Cleaner) Stop() {
+    tc.logger.Info().Msg("Stopping cleanup worker")
     close(tc.done)
 }
 
 // cleanup performs a single cleanup operation
-func (tc *TokenCleaner) cleanup() error {
+func (tc *Cleaner) cleanup() error {
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
     startTime := time.Now()
-    count, err := tc.tokens.DeleteExpired(ctx)
+    count, err := tc.repo.DeleteExpired(ctx)
     if err != nil {
-        return fmt.Errorf("deleting expired tokens: %w", err)
+        return fmt.Errorf("deleting expired %s rows: %w", tc.name, err)
     }
 
     tc.logger.Info().
         Int64("deleted_count", count).
         Dur("duration", time.Since(startTime)).
-        Msg("Completed token cleanup")
+        Msg("Completed cleanup")
 
     return nil
 }
\ No newline at end of file
@@ -0,0 +1,184 @@
+// internal/worker/qualification.go
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/rs/zerolog"
+)
+
+// QualificationRepository lists grants nearing expiry and records warnings
+type QualificationRepository interface {
+	ListExpiring(ctx context.Context, within time.Duration) ([]entity.ExpiringQualification, error)
+	MarkWarned(ctx context.Context, userID, qualificationID int) error
+}
+
+// AdminRepository lists the admins of a facility
+type AdminRepository interface {
+	ListAdmins(ctx context.Context, facilityID int) ([]entity.User, error)
+}
+
+// TemplateMailer sends templated email
+type TemplateMailer interface {
+	SendTemplate(ctx context.Context, templateName string, to string, data interface{}) error
+}
+
+// ExpiryNotifier periodically warns facility admins about qualifications
+// expiring within a window. Each grant is warned about once until renewed.
+type ExpiryNotifier struct {
+	quals    QualificationRepository
+	admins   AdminRepository
+	mailer   TemplateMailer
+	logger   zerolog.Logger
+	window   time.Duration
+	interval time.Duration
+	done     chan struct{}
+}
+
+// NewExpiryNotifier creates a new ExpiryNotifier instance
+func NewExpiryNotifier(quals QualificationRepository, admins AdminRepository, mailer TemplateMailer, logger zerolog.Logger, window, interval time.Duration) *ExpiryNotifier {
+	if interval < time.Minute {
+		interval = time.Hour
+	}
+
+	return &ExpiryNotifier{
+		quals:    quals,
+		admins:   admins,
+		mailer:   mailer,
+		logger:   logger.With().Str("component", "qualification_expiry_notifier").Logger(),
+		window:   window,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins checking for expiring qualifications
+func (n *ExpiryNotifier) Start() {
+	n.logger.Info().
+		Dur("interval", n.interval).
+		Dur("window", n.window).
+		Msg("Starting qualification expiry worker")
+
+	go func() {
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		if err := n.notify(); err != nil {
+			n.logger.Error().Err(err).Msg("Initial expiry check failed")
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := n.notify(); err != nil {
+					n.logger.Error().Err(err).Msg("Periodic expiry check failed")
+				}
+			case <-n.done:
+				n.logger.Info().Msg("Qualification expiry worker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the worker
+func (n *ExpiryNotifier) Stop() {
+	n.logger.Info().Msg("Stopping qualification expiry worker")
+	close(n.done)
+}
+
+// notify sends each facility's admins one email listing the qualifications
+// expiring there. Grants are only marked warned once an admin was emailed.
+func (n *ExpiryNotifier) notify() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	expiring, err := n.quals.ListExpiring(ctx, n.window)
+	if err != nil {
+		return fmt.Errorf("listing expiring qualifications: %w", err)
+	}
+
+	byFacility := make(map[int][]entity.ExpiringQualification)
+	var order []int
+	for _, e := range expiring {
+		if _, ok := byFacility[e.FacilityID]; !ok {
+			order = append(order, e.FacilityID)
+		}
+		byFacility[e.FacilityID] = append(byFacility[e.FacilityID], e)
+	}
+
+	warned := 0
+	for _, facilityID := range order {
+		grants := byFacility[facilityID]
+		if !n.warnAdmins(ctx, facilityID, grants) {
+			continue
+		}
+		for _, g := range grants {
+			if err := n.quals.MarkWarned(ctx, g.UserID, g.QualificationID); err != nil {
+				n.logger.Error().
+					Err(err).
+					Int("user_id", g.UserID).
+					Int("qualification_id", g.QualificationID).
+					Msg("failed to mark qualification warned")
+				continue
+			}
+			warned++
+		}
+	}
+
+	n.logger.Info().
+		Int("expiring_count", len(expiring)).
+		Int("warned_count", warned).
+		Msg("Completed expiry check")
+
+	return nil
+}
+
+// warnAdmins emails the facility's admins and reports whether any were sent
+func (n *ExpiryNotifier) warnAdmins(ctx context.Context, facilityID int, grants []entity.ExpiringQualification) bool {
+	admins, err := n.admins.ListAdmins(ctx, facilityID)
+	if err != nil {
+		n.logger.Error().Err(err).Int("facility_id", facilityID).Msg("failed to list facility admins")
+		return false
+	}
+
+	type item struct {
+		Initials      string
+		UserName      string
+		Qualification string
+		ExpiresOn     string
+	}
+	items := make([]item, 0, len(grants))
+	for _, g := range grants {
+		items = append(items, item{
+			Initials:      g.Initials,
+			UserName:      g.FirstName + " " + g.LastName,
+			Qualification: g.Name,
+			ExpiresOn:     g.ExpiresOn.Format("January 2, 2006"),
+		})
+	}
+	facilityCode := grants[0].FacilityCode
+
+	sent := false
+	for _, admin := range admins {
+		data := map[string]interface{}{
+			"FirstName":    admin.FirstName,
+			"FacilityCode": facilityCode,
+			"Items":        items,
+			"FromName":     "MirandaShift Support",
+			"Subject":      fmt.Sprintf("Qualifications Expiring at %s", facilityCode),
+		}
+		if err := n.mailer.SendTemplate(ctx, "qualification_expiry", admin.Email, data); err != nil {
+			n.logger.Error().Err(err).Int("admin_id", admin.ID).Msg("failed to send qualification expiry warning")
+			continue
+		}
+		sent = true
+	}
+	if len(admins) == 0 {
+		n.logger.Warn().Int("facility_id", facilityID).Msg("no admins to warn about expiring qualifications")
+	}
+	return sent
+}
@@ -0,0 +1,121 @@
+// internal/worker/transfer.go
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DukeRupert/haven/internal/model/entity"
+	"github.com/DukeRupert/haven/internal/repository/transfer"
+	"github.com/rs/zerolog"
+)
+
+// TransferRepository lists scheduled transfers that are due and applies them
+type TransferRepository interface {
+	ListDue(ctx context.Context) ([]entity.Transfer, error)
+	Apply(ctx context.Context, transferID int) (*entity.Transfer, error)
+}
+
+// TransferApplier periodically moves users whose transfer's effective date
+// has arrived at the facility they are leaving
+type TransferApplier struct {
+	transfers TransferRepository
+	logger    zerolog.Logger
+	interval  time.Duration
+	done      chan struct{}
+}
+
+// NewTransferApplier creates a new TransferApplier instance
+func NewTransferApplier(transfers TransferRepository, logger zerolog.Logger, interval time.Duration) *TransferApplier {
+	if interval < time.Minute {
+		interval = 15 * time.Minute
+	}
+
+	return &TransferApplier{
+		transfers: transfers,
+		logger:    logger.With().Str("component", "transfer_applier").Logger(),
+		interval:  interval,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins applying due transfers
+func (a *TransferApplier) Start() {
+	a.logger.Info().
+		Dur("interval", a.interval).
+		Msg("Starting transfer worker")
+
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		if err := a.apply(); err != nil {
+			a.logger.Error().Err(err).Msg("Initial transfer run failed")
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := a.apply(); err != nil {
+					a.logger.Error().Err(err).Msg("Periodic transfer run failed")
+				}
+			case <-a.done:
+				a.logger.Info().Msg("Transfer worker stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop gracefully stops the worker
+func (a *TransferApplier) Stop() {
+	a.logger.Info().Msg("Stopping transfer worker")
+	close(a.done)
+}
+
+// apply moves every user whose transfer is due. A transfer that fails is
+// retried on the next run.
+func (a *TransferApplier) apply() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	due, err := a.transfers.ListDue(ctx)
+	if err != nil {
+		return fmt.Errorf("listing due transfers: %w", err)
+	}
+
+	applied := 0
+	for _, t := range due {
+		_, err := a.transfers.Apply(ctx, t.ID)
+		switch {
+		case errors.Is(err, transfer.ErrCancelled):
+			a.logger.Warn().
+				Int("transfer_id", t.ID).
+				Int("user_id", t.UserID).
+				Msg("transfer cancelled; user no longer based at the old facility")
+		case err != nil:
+			a.logger.Error().
+				Err(err).
+				Int("transfer_id", t.ID).
+				Int("user_id", t.UserID).
+				Msg("failed to apply transfer")
+		default:
+			applied++
+			a.logger.Info().
+				Int("transfer_id", t.ID).
+				Int("user_id", t.UserID).
+				Int("from_facility_id", t.FromFacilityID).
+				Int("to_facility_id", t.ToFacilityID).
+				Msg("user transferred")
+		}
+	}
+
+	a.logger.Info().
+		Int("due_count", len(due)).
+		Int("applied_count", applied).
+		Msg("Completed transfer run")
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package component
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,38 +12,32 @@ import (
 )
 
 // Helper functions (in a separate .go file)
-func getDaysInMonth(date time.Time) []time.Time {
-	firstDay := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
-	lastDay := firstDay.AddDate(0, 1, -1)
-
-	// Calculate padding days for previous month
-	startPadding := int(firstDay.Weekday())
-	if startPadding == 0 {
-		startPadding = 7
-	}
-	startPadding--
 
-	// Calculate padding days for next month
-	endPadding := 42 - (startPadding + lastDay.Day()) // 42 = 6 weeks * 7 days
-
-	var days []time.Time
+// gridStart returns the first day shown on the month's calendar grid
+func gridStart(date time.Time, weekStart time.Weekday) time.Time {
+	firstDay := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	padding := (int(firstDay.Weekday()) - int(weekStart) + 7) % 7
+	return firstDay.AddDate(0, 0, -padding)
+}
 
-	// Add previous month padding days
-	for i := startPadding - 1; i >= 0; i-- {
-		days = append(days, firstDay.AddDate(0, 0, -i-1))
-	}
+// getDaysInMonth returns the 42 days (6 weeks) of the month's calendar grid
+func getDaysInMonth(date time.Time, weekStart time.Weekday) []time.Time {
+	start := gridStart(date, weekStart)
 
-	// Add current month days
-	for i := 0; i < lastDay.Day(); i++ {
-		days = append(days, firstDay.AddDate(0, 0, i))
+	days := make([]time.Time, 0, 42)
+	for i := 0; i < 42; i++ {
+		days = append(days, start.AddDate(0, 0, i))
 	}
+	return days
+}
 
-	// Add next month padding days
-	for i := 0; i < endPadding; i++ {
-		days = append(days, lastDay.AddDate(0, 0, i+1))
+// weekdayLabels returns the calendar column headings starting at weekStart
+func weekdayLabels(weekStart time.Weekday) []string {
+	labels := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		labels = append(labels, ((weekStart + time.Weekday(i)) % 7).String()[:1])
 	}
-
-	return days
+	return labels
 }
 
 func canToggleDate(protectedDate *entity.ProtectedDate, userRole types.UserRole, currentUserID int) bool {
@@ -91,23 +87,21 @@ func findProtectedDates(date time.Time, dates []entity.PD) []entity.PD {
 func getDayClasses(props dto.CalendarDayProps) string {
 	classes := []string{}
 
-	// Add position-based classes
-	if isFirstWeek(props.Date) && props.Date.Weekday() == time.Monday {
+	// Round the corners of the grid
+	switch int(props.Date.Sub(gridStart(props.CurrentMonth, props.WeekStart)).Hours() / 24) {
+	case 0:
 		classes = append(classes, "rounded-tl-lg")
-	}
-	if isFirstWeek(props.Date) && props.Date.Weekday() == time.Sunday {
+	case 6:
 		classes = append(classes, "rounded-tr-lg")
-	}
-	if isLastWeek(props.Date) && props.Date.Weekday() == time.Monday {
+	case 35:
 		classes = append(classes, "rounded-bl-lg")
-	}
-	if isLastWeek(props.Date) && props.Date.Weekday() == time.Sunday {
+	case 41:
 		classes = append(classes, "rounded-br-lg")
 	}
 
 	// Add month-based classes
 	if props.Date.Month() == props.CurrentMonth.Month() {
-		if isToday(props.Date) {
+		if isToday(props.Date, props.Today) {
 			classes = append(classes, "bg-picton-blue-50 text-gray-400")
 		} else {
 			classes = append(classes, "bg-white text-gray-400")
@@ -119,54 +113,26 @@ func getDayClasses(props dto.CalendarDayProps) string {
 	return strings.Join(classes, " ")
 }
 
-func isToday(date time.Time) bool {
-	now := time.Now()
-	return date.Year() == now.Year() &&
-		date.Month() == now.Month() &&
-		date.Day() == now.Day()
+func isToday(date, today time.Time) bool {
+	return date.Year() == today.Year() &&
+		date.Month() == today.Month() &&
+		date.Day() == today.Day()
 }
 
-// isFirstWeek checks if the given date falls in the first week of the calendar grid
-func isFirstWeek(date time.Time) bool {
-	// Get the first day of the month
-	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Local)
-
-	// Find the Monday that starts the calendar grid
-	var firstGridDay time.Time
-	if firstOfMonth.Weekday() != time.Monday {
-		daysToSubtract := int(firstOfMonth.Weekday() - time.Monday)
-		if daysToSubtract < 0 {
-			daysToSubtract += 7
-		}
-		firstGridDay = firstOfMonth.AddDate(0, 0, -daysToSubtract)
-	} else {
-		firstGridDay = firstOfMonth
+// calendarMonthURL links to another month of the calendar, keeping the area
+// filter
+func calendarMonthURL(props dto.CalendarProps, month time.Time) string {
+	code := props.RouteCtx.FacilityCode
+	if code == "" {
+		code = props.AuthCtx.FacilityCode
 	}
-
-	// Check if the date is in the first week by comparing with firstGridDay
-	return date.After(firstGridDay.Add(-24*time.Hour)) &&
-		date.Before(firstGridDay.AddDate(0, 0, 7))
-}
-
-// isLastWeek checks if the given date falls in the last week of the calendar grid
-func isLastWeek(date time.Time) bool {
-	// Get the last day of the month
-	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Local)
-	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
-
-	// Find the Sunday that ends the calendar grid
-	var lastGridDay time.Time
-	if lastOfMonth.Weekday() != time.Sunday {
-		daysToAdd := int(time.Sunday - lastOfMonth.Weekday())
-		if daysToAdd <= 0 {
-			daysToAdd += 7
-		}
-		lastGridDay = lastOfMonth.AddDate(0, 0, daysToAdd)
-	} else {
-		lastGridDay = lastOfMonth
+	area := "all"
+	if props.AreaID != nil {
+		area = strconv.Itoa(*props.AreaID)
 	}
-
-	// Check if the date is in the last week by comparing with lastGridDay
-	return date.After(lastGridDay.AddDate(0, 0, -7).Add(-24*time.Hour)) &&
-		date.Before(lastGridDay.AddDate(0, 0, 1))
+	url := fmt.Sprintf("/app/%s/calendar?month=%s&area=%s", code, month.Format("2006-01"), area)
+	if props.QualificationID != nil {
+		url += fmt.Sprintf("&qualification=%d", *props.QualificationID)
+	}
+	return url
 }
@@ -94,7 +94,7 @@ func Change_Password_Button(facilityCode string, initials string) templ.Componen
 	})
 }
 
-func Delete_User_Button(facilityCode string, initials string) templ.Component {
+func Deactivate_User_Button(facilityCode string, initials string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {